@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditEntry records one mutating API call
+type AuditEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Method    string    `json:"method"`
+	Endpoint  string    `json:"endpoint"` // Route pattern, e.g. /servers/:id/start
+	Path      string    `json:"path"`
+	ServerID  string    `json:"server_id,omitempty"`
+	User      string    `json:"user,omitempty"` // X-Forwarded-Preferred-Username as sent
+	// UserVerified is true when the username came from a configured trusted proxy (proxy.trusted_proxies)
+	UserVerified bool   `json:"user_verified"`
+	ClientIP     string `json:"client_ip"`
+	Status       int    `json:"status"`
+	Result       string `json:"result"` // "success" or "failure"
+	DurationMs   int64  `json:"duration_ms"`
+}
+
+// AuditLogger appends audit entries as JSON lines to a file
+type AuditLogger struct {
+	path  string
+	mutex sync.Mutex
+}
+
+func NewAuditLogger(path string) *AuditLogger {
+	os.MkdirAll(filepath.Dir(path), 0755)
+	return &AuditLogger{path: path}
+}
+
+// Record appends an entry to the audit log
+func (al *AuditLogger) Record(entry AuditEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Failed to marshal audit entry: %v", err)
+		return
+	}
+
+	al.mutex.Lock()
+	defer al.mutex.Unlock()
+
+	file, err := os.OpenFile(al.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("Failed to open audit log %s: %v", al.path, err)
+		return
+	}
+	defer file.Close()
+
+	file.Write(append(data, '\n'))
+}
+
+// isAuditedRequest reports whether a request changes state: any non-read method outside the
+// /vscode proxy, whose traffic belongs to the editor rather than the API
+func isAuditedRequest(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return !strings.HasPrefix(c.Request.URL.Path, "/vscode/") && c.Request.URL.Path != "/vscode"
+}
+
+// AuditMiddleware records every mutating API call, with its outcome, to the audit log
+func AuditMiddleware(al *AuditLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAuditedRequest(c) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		result := "success"
+		if status >= http.StatusBadRequest {
+			result = "failure"
+		}
+
+		user := c.GetHeader("X-Forwarded-Preferred-Username")
+		al.Record(AuditEntry{
+			Timestamp:    start,
+			Method:       c.Request.Method,
+			Endpoint:     c.FullPath(),
+			Path:         c.Request.URL.Path,
+			ServerID:     c.Param("id"),
+			User:         user,
+			UserVerified: user != "" && isTrustedProxy(GetConfig().Proxy, c.Request.RemoteAddr),
+			ClientIP:     c.ClientIP(),
+			Status:       status,
+			Result:       result,
+			DurationMs:   time.Since(start).Milliseconds(),
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// baseExtensionsID is used as the pseudo server ID when logging base seeding events
+const baseExtensionsID = "base-extensions"
+
+// baseExtensionsManifest records which extensions have been seeded into the base directory
+const baseExtensionsManifest = "seeded.json"
+
+// baseExtensionsDir returns the shared data directory (used as XDG_DATA_HOME) for seeded extensions
+func (pm *ProcessManager) baseExtensionsDir() string {
+	return filepath.Join(pm.dataDir, baseExtensionsID)
+}
+
+// GetBaseExtensions returns the extensions currently seeded into the base directory
+func (pm *ProcessManager) GetBaseExtensions() []string {
+	pm.baseExtensionsMutex.Lock()
+	defer pm.baseExtensionsMutex.Unlock()
+
+	return pm.loadBaseExtensionsManifest()
+}
+
+// SeedBaseExtensions installs extensions once into the shared base directory so new servers
+// inherit them on creation instead of installing them individually.
+// Returns the extensions that were installed and the ones that failed.
+func (pm *ProcessManager) SeedBaseExtensions(ctx context.Context, extensions []string) ([]string, []string, error) {
+	if err := validateExtensionRefs(extensions); err != nil {
+		return nil, nil, err
+	}
+
+	pm.baseExtensionsMutex.Lock()
+	defer pm.baseExtensionsMutex.Unlock()
+
+	baseDir := pm.baseExtensionsDir()
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create base extensions directory: %v", err)
+	}
+
+	env := pm.extensionInstallEnv(baseDir)
+	seeded := pm.loadBaseExtensionsManifest()
+
+	installed := []string{}
+	failed := []string{}
+	for _, extension := range extensions {
+		if ctx.Err() != nil {
+			break
+		}
+		if resolved, ok := pm.installExtension(ctx, env, extension, baseExtensionsID, "base"); ok {
+			installed = append(installed, resolved)
+			seeded = mergeResolvedExtensions(seeded, []string{resolved})
+			if !containsExtension(seeded, resolved) {
+				seeded = append(seeded, resolved)
+			}
+		} else {
+			failed = append(failed, extension)
+		}
+	}
+
+	data, err := json.MarshalIndent(seeded, "", "  ")
+	if err != nil {
+		return installed, failed, fmt.Errorf("failed to marshal base extensions manifest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, baseExtensionsManifest), data, 0644); err != nil {
+		return installed, failed, fmt.Errorf("failed to write base extensions manifest: %v", err)
+	}
+
+	pm.log.Printf("Seeded %d/%d base extensions", len(installed), len(extensions))
+	if pm.logManager != nil {
+		pm.logManager.AddSystemLog("INFO", fmt.Sprintf("Seeded %d/%d base extensions", len(installed), len(extensions)))
+	}
+	if err := ctx.Err(); err != nil {
+		return installed, failed, fmt.Errorf("base extension seeding interrupted: %w", err)
+	}
+	return installed, failed, nil
+}
+
+// inheritBaseExtensions copies the seeded base extensions into a server's data directory.
+// Returns the inherited extensions, or nil if nothing has been seeded.
+func (pm *ProcessManager) inheritBaseExtensions(serverID string) []string {
+	pm.baseExtensionsMutex.Lock()
+	defer pm.baseExtensionsMutex.Unlock()
+
+	seeded := pm.loadBaseExtensionsManifest()
+	if len(seeded) == 0 {
+		return nil
+	}
+
+	// code-server keeps extensions under $XDG_DATA_HOME/code-server/extensions
+	srcDir := filepath.Join(pm.baseExtensionsDir(), "code-server", "extensions")
+	dstDir := filepath.Join(pm.dataDir, serverID, "code-server", "extensions")
+	if err := copyDir(srcDir, dstDir); err != nil {
+		pm.log.Printf("Failed to copy base extensions for server %s: %v", serverID, err)
+		return nil
+	}
+
+	// extensions.json references absolute install locations, so point them at the copy
+	if err := rewriteExtensionsIndex(srcDir, dstDir); err != nil {
+		pm.log.Printf("Failed to rewrite extensions index for server %s: %v", serverID, err)
+	}
+
+	pm.log.Printf("Server %s inherited %d base extensions", serverID, len(seeded))
+	return seeded
+}
+
+// loadBaseExtensionsManifest reads the seeded extension list; callers must hold baseExtensionsMutex
+func (pm *ProcessManager) loadBaseExtensionsManifest() []string {
+	data, err := os.ReadFile(filepath.Join(pm.baseExtensionsDir(), baseExtensionsManifest))
+	if err != nil {
+		return []string{}
+	}
+
+	var seeded []string
+	if err := json.Unmarshal(data, &seeded); err != nil {
+		pm.log.Printf("Error parsing base extensions manifest: %v", err)
+		return []string{}
+	}
+	return seeded
+}
+
+// rewriteExtensionsIndex replaces the source directory in extensions.json with the destination
+func rewriteExtensionsIndex(srcDir, dstDir string) error {
+	indexFile := filepath.Join(dstDir, "extensions.json")
+	data, err := os.ReadFile(indexFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	absSrc, err := filepath.Abs(srcDir)
+	if err != nil {
+		return err
+	}
+	absDst, err := filepath.Abs(dstDir)
+	if err != nil {
+		return err
+	}
+
+	rewritten := strings.ReplaceAll(string(data), absSrc, absDst)
+	return os.WriteFile(indexFile, []byte(rewritten), 0644)
+}
+
+// copyDir recursively copies the contents of src into dst, preserving file modes
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		targetPath := filepath.Join(dst, relPath)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return os.MkdirAll(targetPath, info.Mode().Perm())
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			linkTarget, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(linkTarget, targetPath)
+		}
+
+		srcFile, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer srcFile.Close()
+
+		dstFile, err := os.OpenFile(targetPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		defer dstFile.Close()
+
+		_, err = io.Copy(dstFile, srcFile)
+		return err
+	})
+}
+
+// containsExtension reports whether an extension with the same ID as ref is present in extensions
+func containsExtension(extensions []string, ref string) bool {
+	for _, extension := range extensions {
+		if extensionRefID(extension) == extensionRefID(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeExtensions returns the extensions whose IDs are not already present in exclude
+func excludeExtensions(extensions, exclude []string) []string {
+	result := make([]string, 0, len(extensions))
+	for _, extension := range extensions {
+		if !containsExtension(exclude, extension) {
+			result = append(result, extension)
+		}
+	}
+	return result
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"sync/atomic"
+	"time"
+)
+
+// bindCheckWindow is how soon after launching an exit counts as a failure to bind the port, when
+// the server reported its address in use, e.g. held by a previous process that hasn't released it yet
+const bindCheckWindow = 2 * time.Second
+
+// bindRetryDelay is the wait before relaunching a server that failed to bind its port
+const bindRetryDelay = time.Second
+
+// addressInUseMarkers are what Node (EADDRINUSE) and other runtimes print when the port is taken
+var addressInUseMarkers = [][]byte{[]byte("EADDRINUSE"), []byte("address already in use")}
+
+// bindWatch scans a server's stderr for a report that its port is in use. attempt is the launch
+// it watches, counting relaunches after failed binds from 0.
+type bindWatch struct {
+	attempt      int
+	launched     time.Time
+	drained      chan struct{} // closed once stderr has been read to the end
+	tail         []byte        // end of the previous write, for markers split across writes
+	addressInUse atomic.Bool
+}
+
+func (w *bindWatch) Write(p []byte) (int, error) {
+	if w.addressInUse.Load() {
+		return len(p), nil
+	}
+	data := append(w.tail, p...)
+	for _, marker := range addressInUseMarkers {
+		if bytes.Contains(data, marker) {
+			w.addressInUse.Store(true)
+			return len(p), nil
+		}
+	}
+	w.tail = append(w.tail[:0], data[max(len(data)-64, 0):]...)
+	return len(p), nil
+}
+
+// launchServer starts cmd for server, capturing its output into the server's logs. It returns
+// the running process, a channel receiving its exit error and a watch on its stderr, both to be
+// passed to monitorProcess, which relaunches the server if it exits because its port was held.
+func (pm *ProcessManager) launchServer(launcher ProcessLauncher, cmd *exec.Cmd, server *ServerInstance, bindAttempt int) (LaunchedProcess, <-chan error, *bindWatch, error) {
+	proc, err := launcher.Start(cmd, server.Port)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	watch := &bindWatch{attempt: bindAttempt, launched: time.Now(), drained: make(chan struct{})}
+	stdout, stderr := proc.Output()
+	outputCapture := NewEnhancedProcessOutputCapture(pm.logger, pm.logManager, server.ID, server.Name)
+	outputCapture.CaptureOutput(stdout, nil)
+	go func() {
+		defer close(watch.drained)
+		if stderr != nil {
+			outputCapture.captureStream(io.TeeReader(stderr, watch), "stderr")
+		}
+	}()
+
+	return proc, watchExit(proc), watch, nil
+}
+
+// failedToBind reports whether the watched process, which has exited, did so right after
+// launching because its port was in use. Its last output may still be in flight, so it waits
+// briefly for stderr to be read to the end.
+func (w *bindWatch) failedToBind() bool {
+	if time.Since(w.launched) >= bindCheckWindow {
+		return false
+	}
+	select {
+	case <-w.drained:
+	case <-time.After(bindCheckWindow):
+	}
+	return w.addressInUse.Load()
+}
+
+// retryBind launches a server again after bindRetryDelay, its previous launch having exited
+// because its port was still held. It gives up quietly if the server was deleted or started by
+// someone else in the meantime.
+func (pm *ProcessManager) retryBind(id string, attempt int) {
+	select {
+	case <-pm.done:
+		return
+	case <-time.After(bindRetryDelay):
+	}
+
+	pm.mutex.RLock()
+	server, exists := pm.servers[id]
+	wanted := exists && server.Status == StatusStopped
+	name := ""
+	if exists {
+		name = server.Name
+	}
+	pm.mutex.RUnlock()
+	if !wanted {
+		return
+	}
+
+	if err := pm.beginOperation(id, "start"); err != nil {
+		return // Someone else is operating on the server
+	}
+	defer pm.endOperation(id)
+	if err := pm.startServerAttempt(id, attempt); err != nil {
+		pm.log.Printf("Failed to relaunch server %s after its port was in use: %v", name, err)
+	}
+}
+
+// watchExit waits for proc in the background; the returned channel receives its exit error
+func watchExit(proc LaunchedProcess) <-chan error {
+	exited := make(chan error, 1)
+	go func() {
+		exited <- proc.Wait()
+	}()
+	return exited
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// cgroupRoot is the cgroup v2 directory under which per-server cgroups are created
+const cgroupRoot = "/sys/fs/cgroup/databricks-devbox"
+
+// cgroupCPUPeriod is the cpu.max period in microseconds
+const cgroupCPUPeriod = 100000
+
+// applyCgroupLimits moves a process into a per-server cgroup v2 group with CPU/memory limits
+func applyCgroupLimits(serverID string, pid int, limits *ResourceLimits) error {
+	if _, err := os.Stat("/sys/fs/cgroup/cgroup.controllers"); err != nil {
+		return fmt.Errorf("cgroup v2 is not available: %v", err)
+	}
+
+	if err := os.MkdirAll(cgroupRoot, 0755); err != nil {
+		return fmt.Errorf("failed to create cgroup %s: %v", cgroupRoot, err)
+	}
+	// Delegate the controllers to child groups; this may already be enabled
+	os.WriteFile(filepath.Join(filepath.Dir(cgroupRoot), "cgroup.subtree_control"), []byte("+cpu +memory"), 0644)
+	os.WriteFile(filepath.Join(cgroupRoot, "cgroup.subtree_control"), []byte("+cpu +memory"), 0644)
+
+	group := filepath.Join(cgroupRoot, serverID)
+	if err := os.MkdirAll(group, 0755); err != nil {
+		return fmt.Errorf("failed to create cgroup %s: %v", group, err)
+	}
+
+	cpuMax := "max " + strconv.Itoa(cgroupCPUPeriod)
+	if limits.CPUCores > 0 {
+		cpuMax = fmt.Sprintf("%d %d", int(limits.CPUCores*cgroupCPUPeriod), cgroupCPUPeriod)
+	}
+	if err := os.WriteFile(filepath.Join(group, "cpu.max"), []byte(cpuMax), 0644); err != nil {
+		return fmt.Errorf("failed to set cpu.max: %v", err)
+	}
+
+	memoryMax := "max"
+	if limits.MemoryMB > 0 {
+		memoryMax = strconv.FormatInt(int64(limits.MemoryMB)*1024*1024, 10)
+	}
+	if err := os.WriteFile(filepath.Join(group, "memory.max"), []byte(memoryMax), 0644); err != nil {
+		return fmt.Errorf("failed to set memory.max: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(group, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+		return fmt.Errorf("failed to move process into cgroup: %v", err)
+	}
+	return nil
+}
+
+// removeCgroup removes a server's cgroup once its processes have exited
+func removeCgroup(serverID string) {
+	os.Remove(filepath.Join(cgroupRoot, serverID))
+}
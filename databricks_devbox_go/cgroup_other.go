@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import "fmt"
+
+// applyCgroupLimits is only supported on Linux
+func applyCgroupLimits(serverID string, pid int, limits *ResourceLimits) error {
+	return fmt.Errorf("cgroup limits are only supported on linux")
+}
+
+// removeCgroup is a no-op on platforms without cgroups
+func removeCgroup(serverID string) {}
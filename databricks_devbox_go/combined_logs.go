@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// parseProcessLogLine converts a process log line into a LogEntry. Lines look like
+// "2006-01-02 15:04:05 - process_{id} - LEVEL - STDOUT: output" for output, or carry a
+// PROCESS EVENT message for lifecycle events. Returns false for lines in another format.
+func parseProcessLogLine(line, serverID, serverName string) (LogEntry, bool) {
+	parts := strings.SplitN(line, " - ", 4)
+	if len(parts) < 4 || !strings.HasPrefix(parts[1], "process_") {
+		return LogEntry{}, false
+	}
+	ts, err := time.ParseInLocation(processLogTimeLayout, parts[0], time.Local)
+	if err != nil {
+		return LogEntry{}, false
+	}
+
+	entry := LogEntry{
+		Timestamp:  ts.Format(time.RFC3339),
+		Level:      parts[2],
+		ServerID:   serverID,
+		ServerName: serverName,
+		Source:     "server",
+		Message:    parts[3],
+	}
+	if message, ok := strings.CutPrefix(parts[3], "STDOUT: "); ok {
+		entry.Source, entry.Message = "stdout", message
+	} else if message, ok := strings.CutPrefix(parts[3], "STDERR: "); ok {
+		entry.Source, entry.Message = "stderr", message
+	}
+	return entry, true
+}
+
+// logEntryTime parses a LogEntry timestamp, returning the zero time if it can't be parsed
+func logEntryTime(entry LogEntry) time.Time {
+	ts, err := time.Parse(time.RFC3339, entry.Timestamp)
+	if err != nil {
+		return time.Time{}
+	}
+	return ts
+}
+
+// logEntryKey identifies the same log line across the process log file and the in-memory buffer
+func logEntryKey(unix int64, entry LogEntry) string {
+	return fmt.Sprintf("%d|%s|%s", unix, entry.Source, entry.Message)
+}
+
+// GetCombinedLogs merges a server's recent process log lines with its in-memory log entries into
+// one chronological list of LogEntry. Output captured in both places is only returned once.
+func (pm *ProcessManager) GetCombinedLogs(id string, lines int) ([]LogEntry, error) {
+	server, err := pm.GetServer(id)
+	if err != nil {
+		return nil, err
+	}
+
+	fileLines, err := pm.logger.GetRecentLogs(id, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	combined := make([]LogEntry, 0, len(fileLines))
+	seen := map[string]int{}
+	for _, line := range fileLines {
+		entry, ok := parseProcessLogLine(line, id, server.Name)
+		if !ok {
+			continue
+		}
+		combined = append(combined, entry)
+		seen[logEntryKey(logEntryTime(entry).Unix(), entry)]++
+	}
+
+	if pm.logManager != nil {
+		for _, entry := range pm.logManager.GetLogs(LogFilter{ServerID: id}) {
+			// stdout/stderr lines are written to both, stamped separately so possibly a second apart;
+			// skip the in-memory copy of ones already read from file
+			unix := logEntryTime(entry).Unix()
+			duplicate := false
+			for _, candidate := range []int64{unix, unix - 1, unix + 1} {
+				if key := logEntryKey(candidate, entry); seen[key] > 0 {
+					seen[key]--
+					duplicate = true
+					break
+				}
+			}
+			if !duplicate {
+				combined = append(combined, entry)
+			}
+		}
+	}
+
+	sort.SliceStable(combined, func(i, j int) bool {
+		return logEntryTime(combined[i]).Before(logEntryTime(combined[j]))
+	})
+	if len(combined) > lines {
+		combined = combined[len(combined)-lines:]
+	}
+	return combined, nil
+}
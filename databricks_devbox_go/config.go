@@ -1,10 +1,15 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -15,6 +20,10 @@ type ExtensionGroup struct {
 	Description  string                 `yaml:"description" json:"description"`
 	Extensions   []string               `yaml:"extensions" json:"extensions"`
 	UserSettings map[string]interface{} `yaml:"user_settings,omitempty" json:"user_settings,omitempty"`
+	// Hidden groups aren't offered in the UI but can still be requested through the API
+	Hidden bool `yaml:"hidden,omitempty" json:"hidden"`
+	// DependsOn lists groups whose extensions and settings are included whenever this group is selected
+	DependsOn []string `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
 }
 
 // PortRange represents a range of ports
@@ -23,10 +32,301 @@ type PortRange struct {
 	End   int `yaml:"end" json:"end"`
 }
 
+// Contains reports whether port falls within the range (inclusive)
+func (r PortRange) Contains(port int) bool {
+	return port >= r.Start && port <= r.End
+}
+
+// validPort reports whether port is a usable TCP port number
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+// validatePortRange checks a server port range, returning a corrected one. A range that is
+// inverted or out of bounds falls back to defaults; one overlapping the main port is moved past it.
+func validatePortRange(r PortRange, mainPort int, defaults PortRange) PortRange {
+	if r.End == 0 {
+		r.End = r.Start + (defaults.End - defaults.Start)
+	}
+	if !validPort(r.Start) || !validPort(r.End) || r.Start >= r.End {
+		log.Printf("Warning: invalid code_server_port_range %d-%d (start must be below end, both within 1-65535), using %d-%d",
+			r.Start, r.End, defaults.Start, defaults.End)
+		r = defaults
+	}
+
+	if r.Contains(mainPort) {
+		corrected := PortRange{Start: mainPort + 1, End: r.End}
+		if corrected.Start >= corrected.End {
+			corrected = defaults
+		}
+		log.Printf("Warning: code_server_port_range %d-%d overlaps default_port %d, using %d-%d",
+			r.Start, r.End, mainPort, corrected.Start, corrected.End)
+		r = corrected
+	}
+	return r
+}
+
 // ServerConfig represents server configuration
 type ServerConfig struct {
 	DefaultPort         int       `yaml:"default_port" json:"default_port"`
 	CodeServerPortRange PortRange `yaml:"code_server_port_range" json:"code_server_port_range"`
+	// MemoryBudgetMB caps the total memory of running servers; new starts are refused beyond it (0 disables)
+	MemoryBudgetMB float64 `yaml:"memory_budget_mb" json:"memory_budget_mb"`
+	// EstimatedServerMemoryMB is assumed for a new server when no running server has been measured
+	EstimatedServerMemoryMB float64 `yaml:"estimated_server_memory_mb" json:"estimated_server_memory_mb"`
+	// PostInitCommand runs in each new server's workspace after it is initialized (empty disables)
+	PostInitCommand string `yaml:"post_init_command" json:"post_init_command"`
+	// AutostartPreviouslyRunning starts servers whose persisted status was running when the manager starts
+	AutostartPreviouslyRunning bool `yaml:"autostart_previously_running" json:"autostart_previously_running"`
+	// MaxExtensions caps how many extensions a server may be created or installed with
+	MaxExtensions int `yaml:"max_extensions" json:"max_extensions"`
+	// RestartMaxAttempts caps consecutive automatic restarts of a server with a restart policy
+	RestartMaxAttempts int `yaml:"restart_max_attempts" json:"restart_max_attempts"`
+	// RestartBackoffMaxSeconds caps the exponential delay between automatic restarts
+	RestartBackoffMaxSeconds int `yaml:"restart_backoff_max_seconds" json:"restart_backoff_max_seconds"`
+	// Backend is where servers without their own backend run: "process" or "docker"
+	Backend string `yaml:"backend" json:"backend"`
+	// ExtensionInstallConcurrency is how many extensions of a server are installed at once
+	ExtensionInstallConcurrency int `yaml:"extension_install_concurrency" json:"extension_install_concurrency"`
+	// ExtensionInstallRetries is how many more times a failed extension install is attempted (0 disables)
+	ExtensionInstallRetries *int `yaml:"extension_install_retries,omitempty" json:"extension_install_retries,omitempty"`
+	// BindRetries is how many times a server that exits early because its port is still held is relaunched (0 disables)
+	BindRetries *int `yaml:"bind_retries,omitempty" json:"bind_retries,omitempty"`
+	// RunAsUser is the OS user servers without their own run as (empty runs them as the manager's user)
+	RunAsUser string `yaml:"run_as_user" json:"run_as_user"`
+	// IdleTimeoutMinutes stops running servers that received no proxied traffic for this long (0 disables)
+	IdleTimeoutMinutes int `yaml:"idle_timeout_minutes" json:"idle_timeout_minutes"`
+	// CheckWritableDirs probes the data and logs directories with a test file at startup
+	CheckWritableDirs *bool `yaml:"check_writable_dirs,omitempty" json:"check_writable_dirs,omitempty"`
+}
+
+// RestartBackoffMax returns the longest delay between automatic restarts
+func (s ServerConfig) RestartBackoffMax() time.Duration {
+	return time.Duration(s.RestartBackoffMaxSeconds) * time.Second
+}
+
+// IdleTimeout returns how long a running server may go without proxied traffic, or 0 if idle servers are kept
+func (s ServerConfig) IdleTimeout() time.Duration {
+	if s.IdleTimeoutMinutes <= 0 {
+		return 0
+	}
+	return time.Duration(s.IdleTimeoutMinutes) * time.Minute
+}
+
+// InstallRetries returns how many times a failed extension install is retried
+func (s ServerConfig) InstallRetries() int {
+	if s.ExtensionInstallRetries == nil {
+		return 2
+	}
+	return max(*s.ExtensionInstallRetries, 0)
+}
+
+// BindRetryCount returns how many times a server failing to bind its port is relaunched
+func (s ServerConfig) BindRetryCount() int {
+	if s.BindRetries == nil {
+		return 3
+	}
+	return max(*s.BindRetries, 0)
+}
+
+// WritableDirCheck reports whether the data and logs directories are probed at startup
+func (s ServerConfig) WritableDirCheck() bool {
+	return s.CheckWritableDirs == nil || *s.CheckWritableDirs
+}
+
+// TimeoutsConfig represents limits (in seconds) for long-running external commands
+type TimeoutsConfig struct {
+	ExtensionInstallSeconds int `yaml:"extension_install_seconds" json:"extension_install_seconds"`
+	GitCloneSeconds         int `yaml:"git_clone_seconds" json:"git_clone_seconds"`
+	OperationSeconds        int `yaml:"operation_seconds" json:"operation_seconds"`
+	PostInitSeconds         int `yaml:"post_init_seconds" json:"post_init_seconds"`
+	HookSeconds             int `yaml:"hook_seconds" json:"hook_seconds"`
+	GitCommandSeconds       int `yaml:"git_command_seconds" json:"git_command_seconds"`
+	HTTPReadSeconds         int `yaml:"http_read_seconds" json:"http_read_seconds"`
+	HTTPWriteSeconds        int `yaml:"http_write_seconds" json:"http_write_seconds"`
+	HTTPIdleSeconds         int `yaml:"http_idle_seconds" json:"http_idle_seconds"`
+}
+
+// ExtensionInstall returns the per-extension install timeout
+func (t TimeoutsConfig) ExtensionInstall() time.Duration {
+	return time.Duration(t.ExtensionInstallSeconds) * time.Second
+}
+
+// PostInit returns the timeout for a server's post-init command
+func (t TimeoutsConfig) PostInit() time.Duration {
+	return time.Duration(t.PostInitSeconds) * time.Second
+}
+
+// GitCommand returns the timeout for git commands run in a workspace (status, commit, push)
+func (t TimeoutsConfig) GitCommand() time.Duration {
+	return time.Duration(t.GitCommandSeconds) * time.Second
+}
+
+// Hook returns the timeout for a single lifecycle hook
+func (t TimeoutsConfig) Hook() time.Duration {
+	return time.Duration(t.HookSeconds) * time.Second
+}
+
+// GitClone returns the timeout for cloning a workspace repository
+func (t TimeoutsConfig) GitClone() time.Duration {
+	return time.Duration(t.GitCloneSeconds) * time.Second
+}
+
+// Operation returns the overall deadline for long-running synchronous API requests
+func (t TimeoutsConfig) Operation() time.Duration {
+	return time.Duration(t.OperationSeconds) * time.Second
+}
+
+// HTTPRead returns how long the manager's HTTP server waits for a request, including its body
+func (t TimeoutsConfig) HTTPRead() time.Duration {
+	return time.Duration(t.HTTPReadSeconds) * time.Second
+}
+
+// HTTPWrite returns how long the manager's HTTP server allows for writing a response
+func (t TimeoutsConfig) HTTPWrite() time.Duration {
+	return time.Duration(t.HTTPWriteSeconds) * time.Second
+}
+
+// HTTPIdle returns how long an idle keep-alive connection is kept open
+func (t TimeoutsConfig) HTTPIdle() time.Duration {
+	return time.Duration(t.HTTPIdleSeconds) * time.Second
+}
+
+// RuntimeConfig describes how to launch an editor process (e.g. code-server, JupyterLab, RStudio).
+// Args and Env may use the placeholders {port}, {workspace}, {workspace_target}, {data_dir} and {config_dir};
+// {workspace_target} is the server's .code-workspace file when it has one, otherwise {workspace}.
+type RuntimeConfig struct {
+	Name       string   `yaml:"name" json:"name"`
+	Binary     string   `yaml:"binary" json:"binary"`
+	Args       []string `yaml:"args" json:"args"`
+	Env        []string `yaml:"env,omitempty" json:"env,omitempty"`
+	HealthPath string   `yaml:"health_path,omitempty" json:"health_path,omitempty"` // Empty uses code-server's /healthz check
+}
+
+// AlertsConfig represents resource usage thresholds that trigger warnings; 0 disables a threshold
+type AlertsConfig struct {
+	CPUPercent float64 `yaml:"cpu_percent" json:"cpu_percent"`
+	MemoryMB   float64 `yaml:"memory_mb" json:"memory_mb"`
+}
+
+// MonitoringConfig represents the intervals (in seconds) of the background refresh routines
+type MonitoringConfig struct {
+	// MetricsIntervalSeconds is how often CPU/memory/uptime are sampled for running servers
+	MetricsIntervalSeconds int `yaml:"metrics_interval_seconds" json:"metrics_interval_seconds"`
+	// StateRefreshIntervalSeconds is how often servers.json is checked for outside changes
+	StateRefreshIntervalSeconds int `yaml:"state_refresh_interval_seconds" json:"state_refresh_interval_seconds"`
+	// EndToEndHealthCheck also requires a 200 from the server's health endpoint fetched through the /vscode proxy
+	EndToEndHealthCheck bool `yaml:"end_to_end_health_check" json:"end_to_end_health_check"`
+}
+
+// MetricsInterval returns the metrics sampling interval
+func (m MonitoringConfig) MetricsInterval() time.Duration {
+	return time.Duration(m.MetricsIntervalSeconds) * time.Second
+}
+
+// StateRefreshInterval returns the interval for checking the servers file for changes
+func (m MonitoringConfig) StateRefreshInterval() time.Duration {
+	return time.Duration(m.StateRefreshIntervalSeconds) * time.Second
+}
+
+// RetentionConfig selects what is left on disk by default when a server is deleted;
+// DELETE /servers/:id can override each flag with a query parameter of the same name
+type RetentionConfig struct {
+	KeepLogs      bool `yaml:"keep_logs" json:"keep_logs"`
+	KeepData      bool `yaml:"keep_data" json:"keep_data"`
+	KeepWorkspace bool `yaml:"keep_workspace" json:"keep_workspace"`
+}
+
+// ProxyConfig represents which inbound request headers the /vscode proxy forwards to servers
+type ProxyConfig struct {
+	// AllowHeaders, when non-empty, is the only set of inbound headers forwarded (protocol headers always are)
+	AllowHeaders []string `yaml:"allow_headers" json:"allow_headers"`
+	// StripHeaders are removed from every proxied request, e.g. identity headers clients could spoof
+	StripHeaders []string `yaml:"strip_headers" json:"strip_headers"`
+	// TrustedProxies are the upstream IPs/CIDRs whose identity headers (X-Forwarded-Preferred-Username etc.) are believed
+	TrustedProxies []string `yaml:"trusted_proxies" json:"trusted_proxies"`
+	// MaxWebSocketMessageMB caps a single relayed WebSocket message; larger ones close the connection
+	MaxWebSocketMessageMB int `yaml:"max_websocket_message_mb" json:"max_websocket_message_mb"`
+}
+
+// MaxWebSocketMessageBytes returns the WebSocket message cap in bytes
+func (p ProxyConfig) MaxWebSocketMessageBytes() int64 {
+	return int64(p.MaxWebSocketMessageMB) * 1024 * 1024
+}
+
+// WebSocketSettings tunes a WebSocket upgrader; zero buffer sizes use the library defaults
+type WebSocketSettings struct {
+	ReadBufferSize  int `yaml:"read_buffer_size" json:"read_buffer_size"`
+	WriteBufferSize int `yaml:"write_buffer_size" json:"write_buffer_size"`
+	// EnableCompression negotiates permessage-deflate; clients that don't offer it get uncompressed frames
+	EnableCompression *bool `yaml:"enable_compression,omitempty" json:"enable_compression,omitempty"`
+}
+
+// Compression reports whether permessage-deflate should be negotiated
+func (s WebSocketSettings) Compression() bool {
+	return s.EnableCompression != nil && *s.EnableCompression
+}
+
+// WebSocketConfig represents the upgrader settings for proxied editor connections and the log stream
+type WebSocketConfig struct {
+	Proxy WebSocketSettings `yaml:"proxy" json:"proxy"`
+	Logs  WebSocketSettings `yaml:"logs" json:"logs"`
+}
+
+// MarketplaceConfig represents the extension registry servers install from and search,
+// passed to code-server as EXTENSIONS_GALLERY
+type MarketplaceConfig struct {
+	// ServiceURL is the registry's VS Code gallery API, e.g. https://open-vsx.org/vscode/gallery
+	ServiceURL string `yaml:"service_url" json:"service_url"`
+	// ItemURL is the base of extension detail pages, e.g. https://open-vsx.org/vscode/item
+	ItemURL string `yaml:"item_url" json:"item_url"`
+	// ResourceURLTemplate locates extension assets, using {publisher}, {name}, {version} and {path}
+	ResourceURLTemplate string `yaml:"resource_url_template,omitempty" json:"resource_url_template,omitempty"`
+}
+
+// GalleryEnv returns the EXTENSIONS_GALLERY assignment pointing code-server at the registry
+func (m MarketplaceConfig) GalleryEnv() string {
+	gallery := map[string]string{"serviceUrl": m.ServiceURL}
+	if m.ItemURL != "" {
+		gallery["itemUrl"] = m.ItemURL
+	}
+	if m.ResourceURLTemplate != "" {
+		gallery["resourceUrlTemplate"] = m.ResourceURLTemplate
+	}
+	data, _ := json.Marshal(gallery)
+	return "EXTENSIONS_GALLERY=" + string(data)
+}
+
+// DockerConfig configures the docker backend, which runs each server in its own container
+type DockerConfig struct {
+	// Binary is the docker-compatible CLI used to run containers (e.g. "podman")
+	Binary string `yaml:"binary" json:"binary"`
+	// Image must provide the server's runtime binary (code-server by default) on its PATH
+	Image string `yaml:"image" json:"image"`
+	// RunArgs are extra `docker run` flags, e.g. ["--memory=4g", "--network=devbox"]
+	RunArgs []string `yaml:"run_args" json:"run_args"`
+}
+
+// TLSConfig represents trust settings for outbound TLS from code-server and extension installs
+type TLSConfig struct {
+	// CABundle is a PEM file of extra CAs (e.g. a corporate TLS intercept proxy) passed as
+	// NODE_EXTRA_CA_CERTS; when set, NODE_TLS_REJECT_UNAUTHORIZED=0 is no longer used
+	CABundle string `yaml:"ca_bundle" json:"ca_bundle"`
+}
+
+// LoggingConfig represents process log rotation settings
+type LoggingConfig struct {
+	// Rotation rolls process logs over at period boundaries: "daily", "hourly" or "" for size-based only
+	Rotation string `yaml:"rotation" json:"rotation"`
+	// BroadcastBatchMs groups log entries produced within the window into one WebSocket message (0 sends each entry)
+	BroadcastBatchMs int `yaml:"broadcast_batch_ms" json:"broadcast_batch_ms"`
+	// MaxLogLines caps the lines a client may request from a server's process log
+	MaxLogLines int `yaml:"max_log_lines" json:"max_log_lines"`
+}
+
+// BroadcastBatch returns the window over which log broadcasts are batched
+func (l LoggingConfig) BroadcastBatch() time.Duration {
+	return time.Duration(l.BroadcastBatchMs) * time.Millisecond
 }
 
 // UISettings represents UI behavior settings
@@ -63,6 +363,7 @@ type TemplateItem struct {
 	ExtensionGroups []string   `yaml:"extensions_groups" json:"extensions_groups"`
 	ThumbnailURL    string     `yaml:"thumbnail_url" json:"thumbnail_url"`
 	GithubURL       string     `yaml:"github_url" json:"github_url"`
+	PostInitCommand string     `yaml:"post_init_command" json:"post_init_command"`
 	IconLinks       []IconLink `yaml:"icon_links" json:"icon_links"`
 }
 
@@ -81,12 +382,26 @@ type PackagedAssets struct {
 type DevboxConfig struct {
 	ExtensionGroups map[string]ExtensionGroup `yaml:"extension_groups" json:"extension_groups"`
 	Server          ServerConfig              `yaml:"server" json:"server"`
+	Timeouts        TimeoutsConfig            `yaml:"timeouts" json:"timeouts"`
+	Logging         LoggingConfig             `yaml:"logging" json:"logging"`
+	Alerts          AlertsConfig              `yaml:"alerts" json:"alerts"`
+	Hooks           LifecycleHooks            `yaml:"hooks" json:"hooks"`
+	Retention       RetentionConfig           `yaml:"retention" json:"retention"`
+	Monitoring      MonitoringConfig          `yaml:"monitoring" json:"monitoring"`
+	Editor          EditorFlags               `yaml:"editor" json:"editor"`
+	TLS             TLSConfig                 `yaml:"tls" json:"tls"`
+	Docker          DockerConfig              `yaml:"docker" json:"docker"`
+	Marketplace     MarketplaceConfig         `yaml:"marketplace" json:"marketplace"`
+	Proxy           ProxyConfig               `yaml:"proxy" json:"proxy"`
+	WebSocket       WebSocketConfig           `yaml:"websocket" json:"websocket"`
+	Runtimes        map[string]RuntimeConfig  `yaml:"runtimes,omitempty" json:"runtimes,omitempty"`
 	UI              UIConfig                  `yaml:"ui" json:"ui"`
 	PackagedAssets  *PackagedAssets           `yaml:"packaged_assets,omitempty" json:"packaged_assets,omitempty"`
 }
 
-// Global config instance
-var globalConfig *DevboxConfig
+// Global config instance, swapped whole on reload so the manager's background routines can
+// read it while it changes
+var globalConfig atomic.Pointer[DevboxConfig]
 
 // getDefaultConfig returns the default configuration with hardcoded values
 func getDefaultConfig() *DevboxConfig {
@@ -130,6 +445,56 @@ func getDefaultConfig() *DevboxConfig {
 				Start: 8010,
 				End:   8100,
 			},
+			EstimatedServerMemoryMB:     512,
+			MaxExtensions:               100,
+			ExtensionInstallConcurrency: 3,
+			RestartMaxAttempts:          5,
+			RestartBackoffMaxSeconds:    60,
+			CheckWritableDirs:           boolPtr(true),
+			ExtensionInstallRetries:     intPtr(2),
+			BindRetries:                 intPtr(3),
+			Backend:                     BackendProcess,
+		},
+		Docker: DockerConfig{
+			Binary: "docker",
+			Image:  "codercom/code-server:latest",
+		},
+		Timeouts: TimeoutsConfig{
+			ExtensionInstallSeconds: 300,
+			GitCloneSeconds:         600,
+			OperationSeconds:        1800,
+			PostInitSeconds:         900,
+			HookSeconds:             120,
+			GitCommandSeconds:       120,
+			HTTPReadSeconds:         60,
+			HTTPWriteSeconds:        120,
+			HTTPIdleSeconds:         120,
+		},
+		Editor: EditorFlags{
+			DisableTelemetry:     boolPtr(true),
+			DisableUpdateCheck:   boolPtr(true),
+			DisableFileDownloads: boolPtr(true),
+		},
+		Logging: LoggingConfig{
+			MaxLogLines: 5000,
+		},
+		Marketplace: MarketplaceConfig{
+			// code-server's default registry
+			ServiceURL:          "https://open-vsx.org/vscode/gallery",
+			ItemURL:             "https://open-vsx.org/vscode/item",
+			ResourceURLTemplate: "https://open-vsx.org/vscode/unpkg/{publisher}/{name}/{version}/{path}",
+		},
+		Proxy: ProxyConfig{
+			MaxWebSocketMessageMB: 64,
+		},
+		WebSocket: WebSocketConfig{
+			Proxy: WebSocketSettings{EnableCompression: boolPtr(false)},
+			// Log messages are repetitive JSON and compress well
+			Logs: WebSocketSettings{EnableCompression: boolPtr(true)},
+		},
+		Monitoring: MonitoringConfig{
+			MetricsIntervalSeconds:      5,
+			StateRefreshIntervalSeconds: 1,
 		},
 		UI: UIConfig{
 			DefaultExtensionGroups: []string{"python", "jupyter"},
@@ -186,7 +551,7 @@ func InitializeConfig() {
 		config = validateAndFillDefaults(config)
 	}
 
-	globalConfig = config
+	globalConfig.Store(config)
 }
 
 // validateAndFillDefaults validates the loaded config and fills in missing values with defaults
@@ -198,14 +563,176 @@ func validateAndFillDefaults(config *DevboxConfig) *DevboxConfig {
 		log.Println("Warning: No extension groups found in config, using defaults")
 		config.ExtensionGroups = defaults.ExtensionGroups
 	}
+	validateExtensionGroupDependencies(config.ExtensionGroups)
 
 	// Fill in server defaults if missing
 	if config.Server.DefaultPort == 0 {
 		config.Server.DefaultPort = defaults.Server.DefaultPort
+	} else if !validPort(config.Server.DefaultPort) {
+		log.Printf("Warning: invalid default_port %d, using %d", config.Server.DefaultPort, defaults.Server.DefaultPort)
+		config.Server.DefaultPort = defaults.Server.DefaultPort
 	}
 	if config.Server.CodeServerPortRange.Start == 0 {
 		config.Server.CodeServerPortRange = defaults.Server.CodeServerPortRange
 	}
+	config.Server.CodeServerPortRange = validatePortRange(config.Server.CodeServerPortRange, config.Server.DefaultPort, defaults.Server.CodeServerPortRange)
+	if config.Server.EstimatedServerMemoryMB <= 0 {
+		config.Server.EstimatedServerMemoryMB = defaults.Server.EstimatedServerMemoryMB
+	}
+
+	// Fill in timeout defaults if missing
+	if config.Timeouts.ExtensionInstallSeconds <= 0 {
+		config.Timeouts.ExtensionInstallSeconds = defaults.Timeouts.ExtensionInstallSeconds
+	}
+	if config.Timeouts.GitCloneSeconds <= 0 {
+		config.Timeouts.GitCloneSeconds = defaults.Timeouts.GitCloneSeconds
+	}
+	if config.Timeouts.OperationSeconds <= 0 {
+		config.Timeouts.OperationSeconds = defaults.Timeouts.OperationSeconds
+	}
+	if config.Timeouts.PostInitSeconds <= 0 {
+		config.Timeouts.PostInitSeconds = defaults.Timeouts.PostInitSeconds
+	}
+	if config.Timeouts.HookSeconds <= 0 {
+		config.Timeouts.HookSeconds = defaults.Timeouts.HookSeconds
+	}
+	if config.Timeouts.GitCommandSeconds <= 0 {
+		config.Timeouts.GitCommandSeconds = defaults.Timeouts.GitCommandSeconds
+	}
+	if config.Timeouts.HTTPReadSeconds <= 0 {
+		config.Timeouts.HTTPReadSeconds = defaults.Timeouts.HTTPReadSeconds
+	}
+	if config.Timeouts.HTTPWriteSeconds <= 0 {
+		config.Timeouts.HTTPWriteSeconds = defaults.Timeouts.HTTPWriteSeconds
+	}
+	if config.Timeouts.HTTPIdleSeconds <= 0 {
+		config.Timeouts.HTTPIdleSeconds = defaults.Timeouts.HTTPIdleSeconds
+	}
+
+	// Editor flags default to enabled
+	config.Editor = config.Editor.withDefaults(defaults.Editor)
+
+	if config.Proxy.MaxWebSocketMessageMB <= 0 {
+		config.Proxy.MaxWebSocketMessageMB = defaults.Proxy.MaxWebSocketMessageMB
+	}
+
+	if config.Logging.BroadcastBatchMs < 0 {
+		config.Logging.BroadcastBatchMs = 0
+	}
+	// A registry is configured as a whole, so only fall back to Open VSX when no service URL is given
+	if config.Marketplace.ServiceURL == "" {
+		config.Marketplace = defaults.Marketplace
+	}
+	if config.Logging.MaxLogLines <= 0 {
+		config.Logging.MaxLogLines = defaults.Logging.MaxLogLines
+	}
+
+	if config.Server.MaxExtensions <= 0 {
+		config.Server.MaxExtensions = defaults.Server.MaxExtensions
+	}
+	if config.Server.ExtensionInstallConcurrency <= 0 {
+		config.Server.ExtensionInstallConcurrency = defaults.Server.ExtensionInstallConcurrency
+	}
+	if config.Server.RestartMaxAttempts <= 0 {
+		config.Server.RestartMaxAttempts = defaults.Server.RestartMaxAttempts
+	}
+	if config.Server.RestartBackoffMaxSeconds <= 0 {
+		config.Server.RestartBackoffMaxSeconds = defaults.Server.RestartBackoffMaxSeconds
+	}
+	if config.Server.CheckWritableDirs == nil {
+		config.Server.CheckWritableDirs = defaults.Server.CheckWritableDirs
+	}
+	if config.Server.ExtensionInstallRetries == nil {
+		config.Server.ExtensionInstallRetries = defaults.Server.ExtensionInstallRetries
+	}
+	if config.Server.BindRetries == nil {
+		config.Server.BindRetries = defaults.Server.BindRetries
+	}
+	if config.Server.Backend == "" {
+		config.Server.Backend = defaults.Server.Backend
+	} else if err := ValidateBackend(config.Server.Backend); err != nil {
+		log.Printf("Warning: %v, using %s", err, defaults.Server.Backend)
+		config.Server.Backend = defaults.Server.Backend
+	}
+	if err := ValidateRunAsUser(config.Server.RunAsUser); err != nil {
+		log.Printf("Warning: server.run_as_user: %v, running servers as the manager's user", err)
+		config.Server.RunAsUser = ""
+	}
+	if config.Docker.Binary == "" {
+		config.Docker.Binary = defaults.Docker.Binary
+	}
+	if config.Docker.Image == "" {
+		config.Docker.Image = defaults.Docker.Image
+	}
+
+	// Fill in WebSocket compression if unset; negative buffer sizes fall back to the library defaults
+	if config.WebSocket.Proxy.EnableCompression == nil {
+		config.WebSocket.Proxy.EnableCompression = defaults.WebSocket.Proxy.EnableCompression
+	}
+	if config.WebSocket.Logs.EnableCompression == nil {
+		config.WebSocket.Logs.EnableCompression = defaults.WebSocket.Logs.EnableCompression
+	}
+	for _, settings := range []*WebSocketSettings{&config.WebSocket.Proxy, &config.WebSocket.Logs} {
+		if settings.ReadBufferSize < 0 {
+			settings.ReadBufferSize = 0
+		}
+		if settings.WriteBufferSize < 0 {
+			settings.WriteBufferSize = 0
+		}
+	}
+
+	// Fill in monitoring intervals if missing
+	if config.Monitoring.MetricsIntervalSeconds <= 0 {
+		config.Monitoring.MetricsIntervalSeconds = defaults.Monitoring.MetricsIntervalSeconds
+	}
+	if config.Monitoring.StateRefreshIntervalSeconds <= 0 {
+		config.Monitoring.StateRefreshIntervalSeconds = defaults.Monitoring.StateRefreshIntervalSeconds
+	}
+
+	// Validate log rotation
+	switch config.Logging.Rotation {
+	case "", "daily", "hourly":
+	default:
+		log.Printf("Warning: Unknown log rotation %q, using size-based rotation only", config.Logging.Rotation)
+		config.Logging.Rotation = ""
+	}
+
+	// Header names are matched in canonical form
+	for i, name := range config.Proxy.AllowHeaders {
+		config.Proxy.AllowHeaders[i] = http.CanonicalHeaderKey(name)
+	}
+	for i, name := range config.Proxy.StripHeaders {
+		config.Proxy.StripHeaders[i] = http.CanonicalHeaderKey(name)
+	}
+
+	// Bare IPs are accepted as single-host trusted proxies; unparseable entries are dropped
+	trustedProxies := make([]string, 0, len(config.Proxy.TrustedProxies))
+	for _, entry := range config.Proxy.TrustedProxies {
+		if _, err := parseTrustedProxy(entry); err != nil {
+			log.Printf("Warning: ignoring trusted proxy %q: %v", entry, err)
+			continue
+		}
+		trustedProxies = append(trustedProxies, entry)
+	}
+	config.Proxy.TrustedProxies = trustedProxies
+
+	// Resolve the CA bundle to an absolute path; a missing file is only warned about since it may be mounted later
+	if config.TLS.CABundle != "" {
+		if absPath, err := filepath.Abs(config.TLS.CABundle); err == nil {
+			config.TLS.CABundle = absPath
+		}
+		if _, err := os.Stat(config.TLS.CABundle); err != nil {
+			log.Printf("Warning: CA bundle %s is not readable: %v", config.TLS.CABundle, err)
+		}
+	}
+
+	// Drop runtimes that can't be launched
+	for key, runtime := range config.Runtimes {
+		if runtime.Binary == "" {
+			log.Printf("Warning: Runtime %s has no binary, ignoring it", key)
+			delete(config.Runtimes, key)
+		}
+	}
 
 	// Fill in UI defaults if missing
 	if len(config.UI.DefaultExtensionGroups) == 0 {
@@ -223,11 +750,12 @@ func validateAndFillDefaults(config *DevboxConfig) *DevboxConfig {
 
 // GetConfig returns the global configuration
 func GetConfig() *DevboxConfig {
-	if globalConfig == nil {
+	config := globalConfig.Load()
+	if config == nil {
 		log.Println("Warning: Config not initialized, using defaults")
 		return getDefaultConfig()
 	}
-	return globalConfig
+	return config
 }
 
 // ReloadConfig reloads the configuration from file
@@ -242,7 +770,17 @@ func ReloadConfig() error {
 		return fmt.Errorf("failed to reload config: %v", err)
 	}
 
-	globalConfig = validateAndFillDefaults(config)
+	globalConfig.Store(validateAndFillDefaults(config))
 	log.Printf("Configuration reloaded from %s", configPath)
 	return nil
 }
+
+// boolPtr returns a pointer to b, for optional config toggles
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// intPtr returns a pointer to n, for optional config values where 0 is meaningful
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestValidatePortRange(t *testing.T) {
+	defaults := PortRange{Start: 8010, End: 8100}
+	tests := []struct {
+		name     string
+		r        PortRange
+		mainPort int
+		want     PortRange
+	}{
+		{"valid", PortRange{Start: 9000, End: 9050}, 8000, PortRange{Start: 9000, End: 9050}},
+		{"missing end keeps the default size", PortRange{Start: 9000}, 8000, PortRange{Start: 9000, End: 9090}},
+		{"inverted", PortRange{Start: 9050, End: 9000}, 8000, defaults},
+		{"empty", PortRange{Start: 9000, End: 9000}, 8000, defaults},
+		{"start below 1", PortRange{Start: 0, End: 10}, 8000, defaults},
+		{"end above 65535", PortRange{Start: 60000, End: 70000}, 8000, defaults},
+		{"starts at the main port", PortRange{Start: 8000, End: 8100}, 8000, PortRange{Start: 8001, End: 8100}},
+		{"main port inside", PortRange{Start: 7990, End: 8100}, 8000, PortRange{Start: 8001, End: 8100}},
+		{"nothing left past the main port", PortRange{Start: 9000, End: 9050}, 9050, defaults},
+	}
+	for _, test := range tests {
+		if got := validatePortRange(test.r, test.mainPort, defaults); got != test.want {
+			t.Errorf("%s: validatePortRange(%d-%d, main %d) = %d-%d, want %d-%d", test.name,
+				test.r.Start, test.r.End, test.mainPort, got.Start, got.End, test.want.Start, test.want.End)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// cpuSampler turns cumulative process CPU times into the usage over the window between two
+// consecutive samples. gopsutil's CPUPercent averages over the whole process lifetime, so it
+// barely moves for a long-running server and is meaningless right after start.
+type cpuSampler struct {
+	mutex sync.Mutex
+	last  map[string]cpuTimesSample // server_id -> previous sample
+}
+
+// cpuTimesSample is a process's cumulative user+system CPU seconds at a point in time
+type cpuTimesSample struct {
+	pid   int
+	total float64
+	at    time.Time
+}
+
+func newCPUSampler() *cpuSampler {
+	return &cpuSampler{last: make(map[string]cpuTimesSample)}
+}
+
+// Sample records the process's cumulative CPU seconds and returns its usage since the previous
+// sample, as a percentage of one core. ok is false when there is no previous sample of the same
+// process to measure against (first tick after start, or the PID changed).
+func (s *cpuSampler) Sample(serverID string, pid int, cpuSeconds float64) (percent float64, ok bool) {
+	current := cpuTimesSample{pid: pid, total: cpuSeconds, at: time.Now()}
+
+	s.mutex.Lock()
+	previous, exists := s.last[serverID]
+	s.last[serverID] = current
+	s.mutex.Unlock()
+
+	if !exists || previous.pid != current.pid {
+		return 0, false
+	}
+	elapsed := current.at.Sub(previous.at).Seconds()
+	if elapsed <= 0 {
+		return 0, false
+	}
+
+	percent = (current.total - previous.total) / elapsed * 100
+	if percent < 0 {
+		percent = 0
+	}
+	return percent, true
+}
+
+// Forget drops the previous sample for a server that is no longer running
+func (s *cpuSampler) Forget(serverID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.last, serverID)
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Launch backends a server can run on
+const (
+	BackendProcess = "process" // The editor runs as a child process of the manager
+	BackendDocker  = "docker"  // The editor runs in a container with its workspace and data mounted
+)
+
+// ValidateBackend checks that backend names a launch backend; empty follows server.backend
+func ValidateBackend(backend string) error {
+	switch backend {
+	case "", BackendProcess, BackendDocker:
+		return nil
+	default:
+		return fmt.Errorf("unknown backend %q (expected %s or %s)", backend, BackendProcess, BackendDocker)
+	}
+}
+
+// backend returns the server's launch backend, falling back to server.backend
+func (s *ServerInstance) backend() string {
+	if s.Backend != "" {
+		return s.Backend
+	}
+	return GetConfig().Server.Backend
+}
+
+// launcherFor returns the launcher that starts servers on backend
+func (pm *ProcessManager) launcherFor(backend string) (ProcessLauncher, error) {
+	if err := ValidateBackend(backend); err != nil {
+		return nil, err
+	}
+	launcher, exists := pm.launchers[backend]
+	if !exists {
+		return nil, fmt.Errorf("backend %s is not available", backend)
+	}
+	return launcher, nil
+}
+
+// SetBackend changes where a server runs from its next start
+func (pm *ProcessManager) SetBackend(id, backend string) error {
+	if err := ValidateBackend(backend); err != nil {
+		return err
+	}
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	server, exists := pm.servers[id]
+	if !exists {
+		return fmt.Errorf("server not found: %s", id)
+	}
+
+	server.Backend = backend
+	pm.logger.LogProcessEvent(id, server.Name, "BACKEND_UPDATED", fmt.Sprintf("Backend set to %s (applies from the next start)", server.backend()))
+	pm.saveServers()
+	return nil
+}
+
+// dockerContainerName names the container of the server listening on port; ports are unique
+// per server, so a container left over from an earlier run on the port can be found and removed
+func dockerContainerName(port int) string {
+	return fmt.Sprintf("devbox-%d", port)
+}
+
+// dockerLauncher runs each server's command in its own container. The workspace, data
+// directory and CA bundle are mounted at their host paths so the command runs unchanged,
+// and the container runs as the server's user (the manager's by default) so files it writes
+// stay editable on the host.
+type dockerLauncher struct {
+	log Logger
+}
+
+func (l *dockerLauncher) Start(cmd *exec.Cmd, port int) (LaunchedProcess, error) {
+	config := GetConfig().Docker
+	name := dockerContainerName(port)
+
+	// Remove a container left behind by an earlier run on this port
+	if output, err := exec.Command(config.Binary, "rm", "--force", name).CombinedOutput(); err == nil && len(output) > 0 {
+		l.log.Printf("Removed leftover container %s", name)
+	}
+
+	// Run as the server's own user when one is set, otherwise as the manager's
+	uid, gid, ok := commandCredential(cmd)
+	if !ok {
+		uid, gid = os.Getuid(), os.Getgid()
+	}
+	args := []string{"run", "--rm", "--init", "--name", name,
+		"--publish", fmt.Sprintf("%d:%d", port, port),
+		"--user", fmt.Sprintf("%d:%d", uid, gid),
+		"--workdir", cmd.Dir,
+		"--entrypoint", cmd.Args[0],
+	}
+
+	env := containerEnv(cmd.Env)
+	dataDir := ""
+	for _, entry := range env {
+		if value, ok := strings.CutPrefix(entry, "XDG_DATA_HOME="); ok {
+			dataDir = value
+		}
+	}
+	for _, dir := range []string{cmd.Dir, dataDir} {
+		if dir != "" {
+			args = append(args, "--volume", dir+":"+dir)
+		}
+	}
+	if caBundle := GetConfig().TLS.CABundle; caBundle != "" {
+		args = append(args, "--volume", caBundle+":"+caBundle+":ro")
+	}
+	// The manager's user has no home directory in the image, so keep the editor's state in its data directory
+	if dataDir != "" {
+		env = append(env, "HOME="+dataDir)
+	}
+	for _, entry := range env {
+		args = append(args, "--env", entry)
+	}
+
+	args = append(args, config.RunArgs...)
+	args = append(args, config.Image)
+	args = append(args, cmd.Args[1:]...)
+
+	dockerCmd := exec.Command(config.Binary, args...)
+	dockerCmd.Dir = cmd.Dir
+	proc, err := startExecProcess(dockerCmd)
+	if err != nil {
+		return nil, err
+	}
+	return &dockerProcess{execProcess: proc, binary: config.Binary, name: name}, nil
+}
+
+// containerEnv returns the entries of env the manager set itself, leaving out those inherited
+// from its own environment, which belong to the host rather than the container
+func containerEnv(env []string) []string {
+	inherited := make(map[string]bool)
+	for _, entry := range os.Environ() {
+		inherited[entry] = true
+	}
+	var result []string
+	for _, entry := range env {
+		if !inherited[entry] {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// dockerProcess is a `docker run` client attached to its container. Signals other than kill are
+// forwarded to the container by the client; kill stops the container itself.
+type dockerProcess struct {
+	*execProcess
+	binary string
+	name   string
+	exited atomic.Bool
+}
+
+func (p *dockerProcess) Wait() error {
+	err := p.execProcess.Wait()
+	p.exited.Store(true)
+	return err
+}
+
+func (p *dockerProcess) Signal(sig os.Signal) error {
+	if sig != os.Kill {
+		return p.execProcess.Signal(sig)
+	}
+	// Killing the client would leave the container running
+	if output, err := exec.Command(p.binary, "kill", p.name).CombinedOutput(); err != nil {
+		p.execProcess.Signal(os.Kill)
+		return fmt.Errorf("failed to kill container %s: %v: %s", p.name, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Metrics reads the container's CPU and memory usage from docker stats
+func (p *dockerProcess) Metrics() (ProcessMetrics, error) {
+	if p.exited.Load() {
+		return ProcessMetrics{}, nil
+	}
+
+	output, err := exec.Command(p.binary, "stats", "--no-stream", "--format", "{{.CPUPerc}}|{{.MemUsage}}", p.name).Output()
+	if err != nil {
+		// The container may still be starting; the exit is reported by Wait either way
+		return ProcessMetrics{Running: true}, nil
+	}
+
+	metrics := ProcessMetrics{Running: true}
+	cpu, memory, _ := strings.Cut(strings.TrimSpace(string(output)), "|")
+	if percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(cpu), "%"), 64); err == nil {
+		metrics.CPUPercent = &percent
+	}
+	usage, _, _ := strings.Cut(memory, "/")
+	if bytes, ok := parseDockerSize(strings.TrimSpace(usage)); ok {
+		metrics.MemoryMB = bytes / 1024 / 1024
+	}
+	return metrics, nil
+}
+
+// dockerSizeUnits are the suffixes docker stats formats sizes with
+var dockerSizeUnits = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"kB", 1e3}, {"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"B", 1},
+}
+
+// parseDockerSize converts a size such as "512.3MiB" to bytes
+func parseDockerSize(size string) (float64, bool) {
+	for _, unit := range dockerSizeUnits {
+		if number, ok := strings.CutSuffix(size, unit.suffix); ok {
+			value, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, false
+			}
+			return value * unit.multiplier, true
+		}
+	}
+	return 0, false
+}
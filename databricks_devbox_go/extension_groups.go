@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownExtensionGroup is returned when requested extension groups aren't defined in config
+var ErrUnknownExtensionGroup = errors.New("unknown extension groups")
+
+// checkExtensionGroupsExist returns an ErrUnknownExtensionGroup listing every key that isn't a configured group
+func checkExtensionGroupsExist(groups map[string]ExtensionGroup, keys []string) error {
+	unknown := []string{}
+	for _, key := range keys {
+		if _, exists := groups[key]; !exists && !slices.Contains(unknown, key) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownExtensionGroup, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// expandExtensionGroups resolves group keys and everything they depend on (via depends_on) into
+// one list, each group after its dependencies and listed once. Unknown groups and dependency
+// cycles are errors.
+func expandExtensionGroups(groups map[string]ExtensionGroup, keys []string) ([]string, error) {
+	expanded := []string{}
+	done := map[string]bool{}
+	visiting := map[string]bool{}
+
+	var visit func(key string, path []string) error
+	visit = func(key string, path []string) error {
+		if done[key] {
+			return nil
+		}
+		path = append(path, key)
+		if visiting[key] {
+			return fmt.Errorf("extension group dependency cycle: %s", strings.Join(path, " -> "))
+		}
+		group, exists := groups[key]
+		if !exists {
+			if len(path) > 1 {
+				return fmt.Errorf("extension group %s depends on unknown group: %s", path[len(path)-2], key)
+			}
+			return fmt.Errorf("unknown extension group: %s", key)
+		}
+
+		visiting[key] = true
+		for _, dependency := range group.DependsOn {
+			if err := visit(dependency, path); err != nil {
+				return err
+			}
+		}
+		visiting[key] = false
+
+		done[key] = true
+		expanded = append(expanded, key)
+		return nil
+	}
+
+	for _, key := range keys {
+		if err := visit(key, nil); err != nil {
+			return nil, err
+		}
+	}
+	return expanded, nil
+}
+
+// extensionGroupExtensions returns the extensions of the given groups and their dependencies, without duplicates
+func extensionGroupExtensions(groups map[string]ExtensionGroup, keys []string) ([]string, error) {
+	expanded, err := expandExtensionGroups(groups, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	extensions := []string{}
+	for _, key := range expanded {
+		for _, extension := range groups[key].Extensions {
+			if !containsExtension(extensions, extension) {
+				extensions = append(extensions, extension)
+			}
+		}
+	}
+	return extensions, nil
+}
+
+// validateExtensionGroupDependencies drops the dependencies of groups that reference unknown
+// groups or form a cycle, so one bad entry doesn't break every group that uses it
+func validateExtensionGroupDependencies(groups map[string]ExtensionGroup) {
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, err := expandExtensionGroups(groups, []string{key}); err != nil {
+			log.Printf("Warning: ignoring depends_on of extension group %s: %v", key, err)
+			group := groups[key]
+			group.DependsOn = nil
+			groups[key] = group
+		}
+	}
+}
+
+// SettingsConflict is a user_settings key that several selected extension groups set to different values
+type SettingsConflict struct {
+	Key     string                 `json:"key"`
+	Values  map[string]interface{} `json:"values"`  // Group key -> the value it sets
+	Applied string                 `json:"applied"` // Group whose value ends up in settings.json
+}
+
+// findSettingsConflicts reports user_settings keys set to different values by the given groups,
+// which are applied in order so the last group setting a key wins
+func findSettingsConflicts(groups map[string]ExtensionGroup, keys []string) []SettingsConflict {
+	setBy := map[string][]string{}
+	for _, key := range keys {
+		for setting := range groups[key].UserSettings {
+			setBy[setting] = append(setBy[setting], key)
+		}
+	}
+
+	conflicts := []SettingsConflict{}
+	for setting, groupKeys := range setBy {
+		first := groups[groupKeys[0]].UserSettings[setting]
+		conflicting := false
+		for _, key := range groupKeys[1:] {
+			if !reflect.DeepEqual(first, groups[key].UserSettings[setting]) {
+				conflicting = true
+				break
+			}
+		}
+		if !conflicting {
+			continue
+		}
+
+		conflict := SettingsConflict{Key: setting, Values: map[string]interface{}{}, Applied: groupKeys[len(groupKeys)-1]}
+		for _, key := range groupKeys {
+			conflict.Values[key] = groups[key].UserSettings[setting]
+		}
+		conflicts = append(conflicts, conflict)
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Key < conflicts[j].Key })
+	return conflicts
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// marketplaceSearchTimeout bounds a single marketplace search request
+const marketplaceSearchTimeout = 15 * time.Second
+
+// maxExtensionSearchResults caps the page size a client may ask the marketplace for
+const maxExtensionSearchResults = 100
+
+// ExtensionSearchResult is a marketplace extension matching a search
+type ExtensionSearchResult struct {
+	ID          string `json:"id"` // publisher.name, as accepted by extension installs
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Publisher   string `json:"publisher"`
+	Version     string `json:"version,omitempty"`
+}
+
+// galleryQueryResponse is the subset of a VS Code gallery extensionquery response we use
+type galleryQueryResponse struct {
+	Results []struct {
+		Extensions []struct {
+			ExtensionName    string `json:"extensionName"`
+			DisplayName      string `json:"displayName"`
+			ShortDescription string `json:"shortDescription"`
+			Publisher        struct {
+				PublisherName string `json:"publisherName"`
+				DisplayName   string `json:"displayName"`
+			} `json:"publisher"`
+			Versions []struct {
+				Version string `json:"version"`
+			} `json:"versions"`
+		} `json:"extensions"`
+	} `json:"results"`
+}
+
+// SearchExtensions queries the configured marketplace's VS Code gallery API (which Open VSX and
+// private registries implement) for extensions matching query
+func SearchExtensions(ctx context.Context, query string, limit int) ([]ExtensionSearchResult, error) {
+	serviceURL := strings.TrimSuffix(GetConfig().Marketplace.ServiceURL, "/")
+
+	// filterType 8 targets VS Code extensions, 10 is free-text search; flags ask for versions and metadata
+	body, err := json.Marshal(map[string]interface{}{
+		"filters": []map[string]interface{}{{
+			"criteria": []map[string]interface{}{
+				{"filterType": 8, "value": "Microsoft.VisualStudio.Code"},
+				{"filterType": 10, "value": query},
+			},
+			"pageNumber": 1,
+			"pageSize":   limit,
+		}},
+		"flags": 914,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, marketplaceSearchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, serviceURL+"/extensionquery", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json;api-version=3.0-preview.1")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("marketplace search failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("marketplace search failed: %s returned %s", serviceURL, resp.Status)
+	}
+
+	var gallery galleryQueryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&gallery); err != nil {
+		return nil, fmt.Errorf("failed to parse marketplace response: %v", err)
+	}
+
+	results := []ExtensionSearchResult{}
+	for _, page := range gallery.Results {
+		for _, extension := range page.Extensions {
+			result := ExtensionSearchResult{
+				ID:          extension.Publisher.PublisherName + "." + extension.ExtensionName,
+				Name:        coalesce(extension.DisplayName, extension.ExtensionName),
+				Description: extension.ShortDescription,
+				Publisher:   coalesce(extension.Publisher.DisplayName, extension.Publisher.PublisherName),
+			}
+			if len(extension.Versions) > 0 {
+				result.Version = extension.Versions[0].Version
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
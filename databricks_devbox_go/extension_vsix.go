@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// vsixExtensionPrefix marks server.Extensions entries installed from an uploaded .vsix. They can't
+// be reinstalled from the marketplace, so installs (e.g. on reset) skip them until uploaded again.
+const vsixExtensionPrefix = "vsix:"
+
+// isVSIXEntry reports whether an extensions entry was installed from an uploaded .vsix
+func isVSIXEntry(ref string) bool {
+	return strings.HasPrefix(ref, vsixExtensionPrefix)
+}
+
+// marketplaceExtensions returns extensions without the entries installed from uploaded .vsix
+// files, e.g. for exports that are installed again elsewhere
+func marketplaceExtensions(extensions []string) []string {
+	result := make([]string, 0, len(extensions))
+	for _, ext := range extensions {
+		if !isVSIXEntry(ext) {
+			result = append(result, ext)
+		}
+	}
+	return result
+}
+
+// InstallVSIX installs an uploaded .vsix package into a server and records it in server.Extensions
+// as vsix:<id>@<version>. filename is the name it was uploaded under, used when code-server
+// doesn't report the extension's ID.
+func (pm *ProcessManager) InstallVSIX(ctx context.Context, serverID, path, filename string) (string, error) {
+	pm.mutex.RLock()
+	server, exists := pm.servers[serverID]
+	if !exists {
+		pm.mutex.RUnlock()
+		return "", fmt.Errorf("server not found: %s", serverID)
+	}
+	serverName := server.Name
+	count := len(server.Extensions) + 1
+	pm.mutex.RUnlock()
+	if err := checkExtensionCount(count); err != nil {
+		return "", err
+	}
+
+	pm.log.Printf("Installing uploaded extension %s for server %s", filename, serverID)
+
+	account, err := pm.serverCommandUser(serverID)
+	if err != nil {
+		return "", err
+	}
+	// The upload is only readable by the manager; code-server reads it as the server's user
+	if account != nil {
+		if err := os.Chown(path, account.UID, account.GID); err != nil {
+			return "", fmt.Errorf("failed to give %s to user %s: %v", filename, account.Name, err)
+		}
+	}
+
+	env := pm.extensionInstallEnv(filepath.Join(pm.dataDir, serverID))
+	output, _, err := pm.runExtensionInstall(ctx, account, env, path)
+	if err != nil {
+		pm.logger.LogProcessEvent(serverID, serverName, "EXTENSION_INSTALL_FAILED", fmt.Sprintf("Failed to install %s: %v", filename, err))
+		return "", fmt.Errorf("failed to install %s: %w", filename, err)
+	}
+
+	entry := vsixExtensionPrefix + filename
+	if match := installedExtensionPattern.FindStringSubmatch(string(output)); match != nil {
+		entry = vsixExtensionPrefix + match[1] + "@" + match[2]
+	}
+
+	pm.mutex.Lock()
+	if server, exists := pm.servers[serverID]; exists {
+		// Replace an earlier install of the same extension, whether uploaded or from the marketplace
+		extensions := make([]string, 0, len(server.Extensions)+1)
+		for _, ext := range server.Extensions {
+			if extensionRefID(ext) != extensionRefID(entry) {
+				extensions = append(extensions, ext)
+			}
+		}
+		server.Extensions = append(extensions, entry)
+		pm.saveServers()
+	}
+	pm.mutex.Unlock()
+
+	pm.logger.LogProcessEvent(serverID, serverName, "EXTENSION_INSTALLED", fmt.Sprintf("Installed %s from uploaded %s", entry, filename))
+	pm.log.Printf("Successfully installed uploaded extension %s for server %s", entry, serverID)
+	return entry, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// extensionsIndexFile is the file in a code-server extensions directory listing the installed
+// extensions; an extension whose directory isn't listed there is not loaded by the editor
+const extensionsIndexFile = "extensions.json"
+
+// extensionsIndexEntry is the part of an extensions.json entry needed to tell which extension it is.
+// Existing entries are kept as they are; only missing ones are written from this struct.
+type extensionsIndexEntry struct {
+	Identifier struct {
+		ID string `json:"id"`
+	} `json:"identifier"`
+	Version  string `json:"version"`
+	Location struct {
+		Mid    int    `json:"$mid"`
+		Path   string `json:"path"`
+		Scheme string `json:"scheme"`
+	} `json:"location"`
+	RelativeLocation string         `json:"relativeLocation"`
+	Metadata         map[string]any `json:"metadata,omitempty"`
+}
+
+// extensionManifest is the part of an extension's package.json identifying it
+type extensionManifest struct {
+	Publisher string `json:"publisher"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+}
+
+// extensionsDir returns the directory code-server installs a server's extensions into
+func (pm *ProcessManager) extensionsDir(serverID string) string {
+	return filepath.Join(pm.dataDir, serverID, "code-server", "extensions")
+}
+
+// reconcileExtensionsIndex adds the extensions installed in dir that its extensions.json doesn't
+// list. Each code-server install rewrites the whole index, so installs running at the same time
+// can drop each other's entries while their directories stay; this restores them once they have
+// all finished. Returns how many entries were added.
+func reconcileExtensionsIndex(dir string) (int, error) {
+	indexPath := filepath.Join(dir, extensionsIndexFile)
+	var entries []json.RawMessage
+	if data, err := os.ReadFile(indexPath); err == nil {
+		// A torn write leaves the index unreadable; it is then rebuilt from the directories alone
+		if err := json.Unmarshal(data, &entries); err != nil {
+			entries = nil
+		}
+	} else if !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	listed := make(map[string]bool)
+	for _, raw := range entries {
+		var entry extensionsIndexEntry
+		if err := json.Unmarshal(raw, &entry); err == nil {
+			listed[strings.ToLower(entry.Identifier.ID)] = true
+			listed[entry.RelativeLocation] = true
+		}
+	}
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	obsolete := readObsoleteExtensions(dir)
+
+	added := 0
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if !dirEntry.IsDir() || strings.HasPrefix(name, ".") || listed[name] || obsolete[name] {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name, "package.json"))
+		if err != nil {
+			continue // Not an extension, or one still being extracted
+		}
+		var manifest extensionManifest
+		if err := json.Unmarshal(data, &manifest); err != nil || manifest.Publisher == "" || manifest.Name == "" {
+			continue
+		}
+		id := strings.ToLower(manifest.Publisher + "." + manifest.Name)
+		if listed[id] {
+			continue // Another version of it is listed
+		}
+
+		absPath, err := filepath.Abs(filepath.Join(dir, name))
+		if err != nil {
+			return added, err
+		}
+		var entry extensionsIndexEntry
+		entry.Identifier.ID = id
+		entry.Version = manifest.Version
+		entry.Location.Mid = 1
+		entry.Location.Path = filepath.ToSlash(absPath)
+		entry.Location.Scheme = "file"
+		entry.RelativeLocation = name
+		if info, err := dirEntry.Info(); err == nil {
+			entry.Metadata = map[string]any{"installedTimestamp": info.ModTime().UnixMilli()}
+		}
+
+		raw, err := json.Marshal(entry)
+		if err != nil {
+			return added, err
+		}
+		entries = append(entries, raw)
+		listed[id] = true
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(indexPath, data, 0644); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", indexPath, err)
+	}
+	return added, nil
+}
+
+// readObsoleteExtensions returns the extension directories code-server has marked for removal
+// in the .obsolete file, which must not be listed again
+func readObsoleteExtensions(dir string) map[string]bool {
+	obsolete := make(map[string]bool)
+	data, err := os.ReadFile(filepath.Join(dir, ".obsolete"))
+	if err != nil {
+		return obsolete
+	}
+	json.Unmarshal(data, &obsolete)
+	return obsolete
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeCodeServerInstall stands in for `code-server --install-extension`: like code-server it writes
+// the extension's directory, then reads extensions.json, and writes it back with its own entry
+// added. The pause between the read and the write makes concurrent installs lose each other's entries.
+const fakeCodeServerInstall = `#!/bin/sh
+ext="$2"
+dir="$XDG_DATA_HOME/code-server/extensions"
+mkdir -p "$dir/$ext-1.0.0"
+printf '{"publisher":"%s","name":"%s","version":"1.0.0"}' "${ext%%.*}" "${ext#*.}" > "$dir/$ext-1.0.0/package.json"
+index=$(cat "$dir/extensions.json" 2>/dev/null || echo "[]")
+sleep 0.2
+entry="{\"identifier\":{\"id\":\"$ext\"},\"version\":\"1.0.0\",\"relativeLocation\":\"$ext-1.0.0\"}"
+if [ "$index" = "[]" ]; then index="[$entry]"; else index="${index%]},$entry]"; fi
+printf '%s' "$index" > "$dir/extensions.json"
+echo "Extension '$ext' v1.0.0 was successfully installed."
+`
+
+func TestConcurrentExtensionInstallsKeepEveryIndexEntry(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake code-server is a shell script")
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "code-server"), []byte(fakeCodeServerInstall), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	pm := newTestProcessManager(t)
+	if GetConfig().Server.ExtensionInstallConcurrency < 2 {
+		t.Fatalf("default extension_install_concurrency is %d, want installs to run in parallel", GetConfig().Server.ExtensionInstallConcurrency)
+	}
+	serverID := "server"
+	var extensions []string
+	for i := range 6 {
+		extensions = append(extensions, fmt.Sprintf("publisher.ext%d", i))
+	}
+
+	env := pm.extensionInstallEnv(filepath.Join(pm.dataDir, serverID))
+	for i, result := range pm.installExtensionBatch(context.Background(), env, extensions, serverID, serverID, nil, nil) {
+		if !result.ok {
+			t.Fatalf("installing %s failed", extensions[i])
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(pm.extensionsDir(serverID), extensionsIndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []extensionsIndexEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("extensions.json is not valid JSON: %v", err)
+	}
+	listed := make(map[string]bool)
+	for _, entry := range entries {
+		listed[entry.Identifier.ID] = true
+	}
+	for _, extension := range extensions {
+		if !listed[extension] {
+			t.Errorf("extensions.json lacks %s after %d concurrent installs: %s", extension, len(extensions), data)
+		}
+	}
+	if len(entries) != len(extensions) {
+		t.Errorf("extensions.json has %d entries, want %d", len(entries), len(extensions))
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gzipSkippedPrefixes are paths whose responses aren't compressed: the code-server proxy passes
+// its upstream's encoding through, WebSockets are hijacked, and static assets serve byte ranges
+var gzipSkippedPrefixes = []string{"/vscode", "/ws/", "/assets/"}
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(nil) },
+}
+
+// GzipMiddleware compresses REST responses for clients that accept gzip, so dashboards polling
+// large responses such as GET /servers use less bandwidth
+func GzipMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !acceptsGzip(c.Request) {
+			c.Next()
+			return
+		}
+
+		writer := &gzipResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Header("Vary", "Accept-Encoding")
+		defer writer.close()
+
+		c.Next()
+	}
+}
+
+// acceptsGzip reports whether r's response should be gzip-compressed
+func acceptsGzip(r *http.Request) bool {
+	if r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+		return false
+	}
+	for _, prefix := range gzipSkippedPrefixes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return false
+		}
+	}
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter compresses the body once the handler starts writing it. Responses without
+// a body (e.g. 204 or 304) and ones the handler already encoded are passed through unchanged.
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	gz          *gzip.Writer
+	passthrough bool
+}
+
+func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+	if w.gz == nil && !w.passthrough {
+		header := w.Header()
+		if header.Get("Content-Encoding") != "" {
+			w.passthrough = true
+		} else {
+			header.Set("Content-Encoding", "gzip")
+			header.Del("Content-Length")
+			w.gz = gzipWriterPool.Get().(*gzip.Writer)
+			w.gz.Reset(w.ResponseWriter)
+		}
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.gz.Write(data)
+}
+
+func (w *gzipResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends what has been compressed so far, so streamed responses (e.g. ?follow=true logs) arrive promptly
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *gzipResponseWriter) close() {
+	if w.gz == nil {
+		return
+	}
+	w.gz.Close()
+	w.gz.Reset(nil)
+	gzipWriterPool.Put(w.gz)
+	w.gz = nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// idleCheckInterval is how often running servers are checked against server.idle_timeout_minutes
+const idleCheckInterval = time.Minute
+
+// accessRecordInterval limits how often a busy server's last access time is rewritten
+const accessRecordInterval = time.Second
+
+// RecordAccess marks the server on port as used now. Unknown ports are ignored.
+func (pm *ProcessManager) RecordAccess(port int) {
+	now := time.Now()
+
+	pm.mutex.RLock()
+	server := pm.serverOnPort(port)
+	recent := server != nil && server.LastAccess != nil && now.Sub(*server.LastAccess) < accessRecordInterval
+	pm.mutex.RUnlock()
+	if server == nil || recent {
+		return
+	}
+
+	pm.mutex.Lock()
+	if server := pm.serverOnPort(port); server != nil {
+		server.LastAccess = &now
+	}
+	pm.mutex.Unlock()
+}
+
+// trackConnection counts an open proxied WebSocket against the server on port, so it isn't
+// stopped as idle while the connection lasts. The returned func ends the connection.
+func (pm *ProcessManager) trackConnection(port int) func() {
+	pm.mutex.Lock()
+	server := pm.serverOnPort(port)
+	if server == nil {
+		pm.mutex.Unlock()
+		return func() {}
+	}
+	id := server.ID
+	pm.activeConnections[id]++
+	pm.mutex.Unlock()
+
+	return func() {
+		pm.mutex.Lock()
+		if pm.activeConnections[id]--; pm.activeConnections[id] <= 0 {
+			delete(pm.activeConnections, id)
+		}
+		if server, exists := pm.servers[id]; exists {
+			now := time.Now()
+			server.LastAccess = &now
+		}
+		pm.mutex.Unlock()
+	}
+}
+
+// serverOnPort returns the server assigned port, or nil. Requires pm.mutex to be held.
+func (pm *ProcessManager) serverOnPort(port int) *ServerInstance {
+	id, exists := pm.portMap[port]
+	if !exists || id == "" {
+		return nil
+	}
+	return pm.servers[id]
+}
+
+// startIdleRoutine stops servers that go longer than server.idle_timeout_minutes without traffic
+func (pm *ProcessManager) startIdleRoutine() {
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-pm.done:
+			return
+		case <-ticker.C:
+			if timeout := GetConfig().Server.IdleTimeout(); timeout > 0 {
+				pm.stopIdleServers(timeout)
+			}
+		}
+	}
+}
+
+// stopIdleServers stops running servers with no open connections whose last access (or start,
+// if more recent) is older than timeout
+func (pm *ProcessManager) stopIdleServers(timeout time.Duration) {
+	type idleServer struct {
+		id, name string
+		idle     time.Duration
+	}
+
+	now := time.Now()
+	var idle []idleServer
+
+	pm.mutex.RLock()
+	for id, server := range pm.servers {
+		if server.Status != StatusRunning || pm.activeConnections[id] > 0 {
+			continue
+		}
+		last := server.LastAccess
+		if last == nil || (server.StartTime != nil && server.StartTime.After(*last)) {
+			last = server.StartTime
+		}
+		if last != nil && now.Sub(*last) >= timeout {
+			idle = append(idle, idleServer{id: id, name: server.Name, idle: now.Sub(*last)})
+		}
+	}
+	pm.mutex.RUnlock()
+
+	for _, server := range idle {
+		message := fmt.Sprintf("No traffic for %v (idle timeout %v), stopping", server.idle.Round(time.Second), timeout)
+		pm.log.Printf("Server %s: %s", server.name, message)
+		pm.logger.LogProcessEvent(server.id, server.name, "IDLE_STOPPED", message)
+		if pm.logManager != nil {
+			pm.logManager.AddServerLog(server.id, server.name, "INFO", "server", message)
+		}
+
+		if err := pm.StopServer(server.id); err != nil {
+			pm.log.Printf("Failed to stop idle server %s: %v", server.name, err)
+		}
+	}
+}
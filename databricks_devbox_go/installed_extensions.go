@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrCodeServerNotFound is returned when the code-server binary needed to inspect a server's
+// extensions isn't on the manager's PATH
+var ErrCodeServerNotFound = errors.New("code-server not found")
+
+// InstalledExtension is an extension present in a server's data directory
+type InstalledExtension struct {
+	ID      string `json:"id"`
+	Version string `json:"version,omitempty"`
+}
+
+// ExtensionDrift compares a server's recorded extensions with those actually installed
+type ExtensionDrift struct {
+	Installed []InstalledExtension `json:"installed"`
+	Recorded  []string             `json:"recorded"`  // server.Extensions from servers.json
+	Missing   []string             `json:"missing"`   // Recorded but not installed, e.g. a failed install
+	Untracked []string             `json:"untracked"` // Installed but not recorded, e.g. installed from the editor
+}
+
+// ListInstalledExtensions asks code-server which extensions are installed in the server's data directory
+func (pm *ProcessManager) ListInstalledExtensions(ctx context.Context, serverID string) ([]InstalledExtension, error) {
+	pm.mutex.RLock()
+	_, exists := pm.servers[serverID]
+	pm.mutex.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("server not found: %s", serverID)
+	}
+	account, err := pm.serverCommandUser(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	binary, err := exec.LookPath("code-server")
+	if err != nil {
+		return nil, fmt.Errorf("%w: listing extensions needs code-server on the manager's PATH: %v", ErrCodeServerNotFound, err)
+	}
+
+	timeout := GetConfig().Timeouts.ExtensionInstall()
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(cmdCtx, binary, "--list-extensions", "--show-versions")
+	cmd.Env = pm.extensionInstallEnv(filepath.Join(pm.dataDir, serverID))
+	cmd.WaitDelay = 5 * time.Second
+	if err := runAs(cmd, account); err != nil {
+		return nil, err
+	}
+
+	output, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if cmdCtx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("listing extensions timed out after %s", timeout)
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("failed to list extensions: %v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("failed to list extensions: %v", err)
+	}
+
+	return parseInstalledExtensions(string(output)), nil
+}
+
+// parseInstalledExtensions parses `code-server --list-extensions --show-versions` output, one
+// id@version per line; other lines (e.g. warnings) are ignored
+func parseInstalledExtensions(output string) []InstalledExtension {
+	installed := []InstalledExtension{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !extensionRefPattern.MatchString(line) {
+			continue
+		}
+		id, version := splitExtensionRef(line)
+		installed = append(installed, InstalledExtension{ID: id, Version: version})
+	}
+	return installed
+}
+
+// GetExtensionDrift lists the server's installed extensions alongside the ones servers.json records
+func (pm *ProcessManager) GetExtensionDrift(ctx context.Context, serverID string) (*ExtensionDrift, error) {
+	installed, err := pm.ListInstalledExtensions(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	pm.mutex.RLock()
+	server, exists := pm.servers[serverID]
+	if !exists {
+		pm.mutex.RUnlock()
+		return nil, fmt.Errorf("server not found: %s", serverID)
+	}
+	recorded := append([]string{}, server.Extensions...)
+	pm.mutex.RUnlock()
+
+	drift := &ExtensionDrift{Installed: installed, Recorded: recorded, Missing: []string{}, Untracked: []string{}}
+	installedIDs := make(map[string]bool, len(installed))
+	for _, ext := range installed {
+		installedIDs[strings.ToLower(ext.ID)] = true
+	}
+	recordedIDs := make(map[string]bool, len(recorded))
+	for _, ref := range recorded {
+		recordedIDs[extensionRefID(ref)] = true
+		if !installedIDs[extensionRefID(ref)] {
+			drift.Missing = append(drift.Missing, ref)
+		}
+	}
+	for _, ext := range installed {
+		if !recordedIDs[strings.ToLower(ext.ID)] {
+			drift.Untracked = append(drift.Untracked, ext.ID)
+		}
+	}
+	return drift, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// deniedLaunchFlags are editor flags servers may not set through ExtraArgs because they would
+// change how the manager reaches or secures the server, or where its state lives
+var deniedLaunchFlags = map[string]bool{
+	"--auth":                true,
+	"--password":            true,
+	"--hashed-password":     true,
+	"--bind-addr":           true,
+	"--host":                true,
+	"--port":                true,
+	"--socket":              true,
+	"--socket-mode":         true,
+	"--user-data-dir":       true,
+	"--extensions-dir":      true,
+	"--cert":                true,
+	"--cert-key":            true,
+	"--cert-host":           true,
+	"--link":                true,
+	"--proxy-domain":        true,
+	"--install-extension":   true,
+	"--uninstall-extension": true,
+	"--config":              true,
+	// Toggled through EditorFlags instead
+	"--disable-telemetry":      true,
+	"--disable-update-check":   true,
+	"--disable-file-downloads": true,
+}
+
+// ValidateExtraArgs checks per-server launch flags. Every flag must start with "-" and not be
+// denied; bare values are only allowed directly after a flag (e.g. "--locale", "de").
+func ValidateExtraArgs(args []string) error {
+	previousWasFlag := false
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			if !previousWasFlag {
+				return fmt.Errorf("unexpected argument %q: extra args must be flags", arg)
+			}
+			previousWasFlag = false
+			continue
+		}
+
+		name, _, _ := strings.Cut(arg, "=")
+		if deniedLaunchFlags[name] {
+			return fmt.Errorf("flag %s is managed by devbox and can't be overridden", name)
+		}
+		previousWasFlag = !strings.Contains(arg, "=")
+	}
+	return nil
+}
+
+// SetExtraArgs replaces the extra flags appended to a server's launch command; they take
+// effect on the next start
+func (pm *ProcessManager) SetExtraArgs(id string, args []string) error {
+	if err := ValidateExtraArgs(args); err != nil {
+		return err
+	}
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	server, exists := pm.servers[id]
+	if !exists {
+		return fmt.Errorf("server not found: %s", id)
+	}
+
+	if len(args) == 0 {
+		args = nil
+	}
+	server.ExtraArgs = args
+	pm.logger.LogProcessEvent(id, server.Name, "EXTRA_ARGS_UPDATED", fmt.Sprintf("Extra args set to %v", args))
+	pm.saveServers()
+	return nil
+}
+
+// EditorFlags toggles code-server's --disable-telemetry, --disable-update-check and
+// --disable-file-downloads flags. Nil fields fall back to the next level: server -> config -> enabled.
+type EditorFlags struct {
+	DisableTelemetry     *bool `yaml:"disable_telemetry" json:"disable_telemetry,omitempty"`
+	DisableUpdateCheck   *bool `yaml:"disable_update_check" json:"disable_update_check,omitempty"`
+	DisableFileDownloads *bool `yaml:"disable_file_downloads" json:"disable_file_downloads,omitempty"`
+}
+
+// withDefaults fills unset toggles from defaults
+func (f EditorFlags) withDefaults(defaults EditorFlags) EditorFlags {
+	if f.DisableTelemetry == nil {
+		f.DisableTelemetry = defaults.DisableTelemetry
+	}
+	if f.DisableUpdateCheck == nil {
+		f.DisableUpdateCheck = defaults.DisableUpdateCheck
+	}
+	if f.DisableFileDownloads == nil {
+		f.DisableFileDownloads = defaults.DisableFileDownloads
+	}
+	return f
+}
+
+// isEmpty reports whether no toggle is set
+func (f EditorFlags) isEmpty() bool {
+	return f.DisableTelemetry == nil && f.DisableUpdateCheck == nil && f.DisableFileDownloads == nil
+}
+
+// Args returns the code-server flags for the enabled toggles
+func (f EditorFlags) Args() []string {
+	args := []string{}
+	if f.DisableTelemetry == nil || *f.DisableTelemetry {
+		args = append(args, "--disable-telemetry")
+	}
+	if f.DisableUpdateCheck == nil || *f.DisableUpdateCheck {
+		args = append(args, "--disable-update-check")
+	}
+	if f.DisableFileDownloads == nil || *f.DisableFileDownloads {
+		args = append(args, "--disable-file-downloads")
+	}
+	return args
+}
+
+// resolveEditorFlags applies a server's overrides on top of the configured defaults
+func resolveEditorFlags(server *ServerInstance) EditorFlags {
+	defaults := GetConfig().Editor
+	if server.EditorFlags == nil {
+		return defaults
+	}
+	return server.EditorFlags.withDefaults(defaults)
+}
+
+// SetEditorFlags replaces a server's editor flag overrides; unset toggles follow the config
+func (pm *ProcessManager) SetEditorFlags(id string, flags *EditorFlags) error {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	server, exists := pm.servers[id]
+	if !exists {
+		return fmt.Errorf("server not found: %s", id)
+	}
+
+	if flags != nil && flags.isEmpty() {
+		flags = nil
+	}
+	server.EditorFlags = flags
+	pm.logger.LogProcessEvent(id, server.Name, "EDITOR_FLAGS_UPDATED", fmt.Sprintf("Editor flags set to %v", resolveEditorFlags(server).Args()))
+	pm.saveServers()
+	return nil
+}
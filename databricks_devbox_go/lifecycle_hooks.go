@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// LifecycleHooks are shell commands run around a server's lifecycle, in its workspace directory.
+// Hooks receive DEVBOX_SERVER_ID, DEVBOX_SERVER_NAME, DEVBOX_SERVER_PORT and DEVBOX_WORKSPACE.
+type LifecycleHooks struct {
+	PreStart string `yaml:"pre_start" json:"pre_start,omitempty"` // Runs before launch; failure aborts the start
+	PostStop string `yaml:"post_stop" json:"post_stop,omitempty"` // Runs after the process exits; failure is only reported
+}
+
+// resolveHooks returns the server's hooks, falling back to the configured defaults per hook
+func resolveHooks(server *ServerInstance) LifecycleHooks {
+	hooks := GetConfig().Hooks
+	if server.Hooks != nil {
+		if server.Hooks.PreStart != "" {
+			hooks.PreStart = server.Hooks.PreStart
+		}
+		if server.Hooks.PostStop != "" {
+			hooks.PostStop = server.Hooks.PostStop
+		}
+	}
+	return hooks
+}
+
+// hookEnv returns the environment a hook runs with
+func hookEnv(server *ServerInstance) []string {
+	return append(os.Environ(),
+		"DEVBOX_SERVER_ID="+server.ID,
+		"DEVBOX_SERVER_NAME="+server.Name,
+		"DEVBOX_SERVER_PORT="+strconv.Itoa(server.Port),
+		"DEVBOX_WORKSPACE="+server.WorkspacePath,
+	)
+}
+
+// runHook runs a lifecycle hook for a server, logging its output and outcome.
+// hook is the event name, e.g. "pre_start"; an empty command is a no-op.
+func (pm *ProcessManager) runHook(server *ServerInstance, hook, command string) error {
+	if command == "" {
+		return nil
+	}
+
+	pm.log.Printf("Running %s hook for server %s: %s", hook, server.ID, command)
+	pm.logger.LogProcessEvent(server.ID, server.Name, "HOOK_STARTED", fmt.Sprintf("%s: %s", hook, command))
+
+	timeout := GetConfig().Timeouts.Hook()
+	account, err := commandUser(server.runAsUser())
+	if err == nil {
+		_, err = pm.runShellCommand(context.Background(), server.ID, server.Name, server.WorkspacePath, command, hookEnv(server), account, timeout)
+	}
+	if err != nil {
+		pm.log.Printf("%s hook failed for server %s: %v", hook, server.ID, err)
+		pm.logger.LogProcessEvent(server.ID, server.Name, "HOOK_FAILED", fmt.Sprintf("%s: %v", hook, err))
+		if pm.logManager != nil {
+			pm.logManager.AddServerLog(server.ID, server.Name, "ERROR", "server", fmt.Sprintf("%s hook failed: %v", hook, err))
+		}
+		return fmt.Errorf("%s hook failed: %v", hook, err)
+	}
+
+	pm.logger.LogProcessEvent(server.ID, server.Name, "HOOK_COMPLETED", hook)
+	if pm.logManager != nil {
+		pm.logManager.AddServerLog(server.ID, server.Name, "INFO", "server", fmt.Sprintf("%s hook completed", hook))
+	}
+	return nil
+}
+
+// SetHooks replaces a server's lifecycle hooks; empty hooks fall back to the configured defaults
+func (pm *ProcessManager) SetHooks(id string, hooks *LifecycleHooks) error {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	server, exists := pm.servers[id]
+	if !exists {
+		return fmt.Errorf("server not found: %s", id)
+	}
+
+	if hooks != nil && *hooks == (LifecycleHooks{}) {
+		hooks = nil
+	}
+	server.Hooks = hooks
+	pm.logger.LogProcessEvent(id, server.Name, "HOOKS_UPDATED", "Lifecycle hooks updated")
+	pm.saveServers()
+	return nil
+}
@@ -7,6 +7,8 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,20 +22,93 @@ type LogEntry struct {
 	ServerName string `json:"serverName,omitempty"`
 	Source     string `json:"source"` // 'system' | 'server' | 'stdout' | 'stderr'
 	Message    string `json:"message"`
+	// Seq increases by one for every entry the LogManager records, so clients can resume after it
+	Seq uint64 `json:"seq,omitempty"`
+}
+
+// validLogSources are the values LogEntry.Source can take
+var validLogSources = map[string]bool{
+	"system": true,
+	"server": true,
+	"stdout": true,
+	"stderr": true,
+}
+
+// LogFilter selects log entries by server and/or source; empty fields match everything
+type LogFilter struct {
+	ServerID string
+	Sources  []string
+	// SinceSeq only matches entries recorded after this sequence number (0 matches all)
+	SinceSeq uint64
+}
+
+// NewLogFilter builds a filter from a server ID and a comma-separated list of sources
+// (e.g. "stdout,stderr"), rejecting unknown sources
+func NewLogFilter(serverID, sources string) (LogFilter, error) {
+	filter := LogFilter{ServerID: serverID}
+	for _, source := range strings.Split(sources, ",") {
+		source = strings.TrimSpace(strings.ToLower(source))
+		if source == "" {
+			continue
+		}
+		if !validLogSources[source] {
+			return filter, fmt.Errorf("invalid log source %q: must be one of system, server, stdout, stderr", source)
+		}
+		filter.Sources = append(filter.Sources, source)
+	}
+	return filter, nil
+}
+
+// parseSinceSeq parses a since_seq cursor, where empty means from the start of the buffer
+func parseSinceSeq(value string) (uint64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	seq, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid since_seq %q: must be a non-negative integer", value)
+	}
+	return seq, nil
+}
+
+// Matches reports whether a log entry passes the filter
+func (f LogFilter) Matches(entry LogEntry) bool {
+	if f.ServerID != "" && entry.ServerID != f.ServerID {
+		return false
+	}
+	if entry.Seq <= f.SinceSeq {
+		return false
+	}
+	if len(f.Sources) == 0 {
+		return true
+	}
+	for _, source := range f.Sources {
+		if entry.Source == source {
+			return true
+		}
+	}
+	return false
 }
 
 type LogManager struct {
 	mutex   sync.RWMutex
 	logs    []LogEntry
 	maxLogs int
-	clients map[*websocket.Conn]bool
+	lastSeq uint64                        // sequence number of the most recently recorded entry
+	clients map[*websocket.Conn]LogFilter // each client only receives entries matching its filter
+
+	// Entries waiting to be broadcast as one new_logs message when batching is enabled
+	batchWindow  time.Duration
+	pending      []LogEntry
+	pendingTimer *time.Timer
 }
 
 func NewLogManager() *LogManager {
 	return &LogManager{
-		logs:    make([]LogEntry, 0, 10000),
-		maxLogs: 10000,
-		clients: make(map[*websocket.Conn]bool),
+		logs:        make([]LogEntry, 0, 10000),
+		maxLogs:     10000,
+		clients:     make(map[*websocket.Conn]LogFilter),
+		batchWindow: GetConfig().Logging.BroadcastBatch(),
 	}
 }
 
@@ -45,6 +120,8 @@ func (lm *LogManager) AddLog(entry LogEntry) {
 	if entry.Timestamp == "" {
 		entry.Timestamp = time.Now().Format(time.RFC3339)
 	}
+	lm.lastSeq++
+	entry.Seq = lm.lastSeq
 
 	// Add to logs and maintain max size
 	lm.logs = append(lm.logs, entry)
@@ -52,8 +129,15 @@ func (lm *LogManager) AddLog(entry LogEntry) {
 		lm.logs = lm.logs[1:] // Remove oldest log
 	}
 
-	// Broadcast to all connected WebSocket clients
-	lm.broadcastLog(entry)
+	// Broadcast to all connected WebSocket clients, batched if configured
+	if lm.batchWindow <= 0 {
+		lm.broadcastLog(entry)
+		return
+	}
+	lm.pending = append(lm.pending, entry)
+	if lm.pendingTimer == nil {
+		lm.pendingTimer = time.AfterFunc(lm.batchWindow, lm.flushPendingLogs)
+	}
 }
 
 func (lm *LogManager) AddSystemLog(level, message string) {
@@ -74,31 +158,68 @@ func (lm *LogManager) AddServerLog(serverID, serverName, level, source, message
 	})
 }
 
-func (lm *LogManager) GetLogs(serverId string) []LogEntry {
+func (lm *LogManager) GetLogs(filter LogFilter) []LogEntry {
 	lm.mutex.RLock()
 	defer lm.mutex.RUnlock()
 
-	if serverId == "" {
+	if filter.ServerID == "" && len(filter.Sources) == 0 && filter.SinceSeq == 0 {
 		// Return all logs
 		result := make([]LogEntry, len(lm.logs))
 		copy(result, lm.logs)
 		return result
 	}
 
-	// Filter logs for specific server
-	var filtered []LogEntry
+	// Filter logs for specific server and/or sources
+	filtered := []LogEntry{}
 	for _, entry := range lm.logs {
-		if entry.ServerID == serverId {
+		if filter.Matches(entry) {
 			filtered = append(filtered, entry)
 		}
 	}
 	return filtered
 }
 
-func (lm *LogManager) AddWebSocketClient(conn *websocket.Conn) {
+func (lm *LogManager) AddWebSocketClient(conn *websocket.Conn, filter LogFilter) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	lm.clients[conn] = filter
+}
+
+// subscribe registers a client and sends it the buffered entries matching its filter as an
+// initial_logs message. Both happen under the lock so no entry is sent twice or skipped between
+// the backlog and the live broadcasts.
+func (lm *LogManager) subscribe(conn *websocket.Conn, filter LogFilter) error {
 	lm.mutex.Lock()
 	defer lm.mutex.Unlock()
-	lm.clients[conn] = true
+
+	// Batched entries are already in the buffer; send them to existing clients before snapshotting
+	lm.flushPendingLocked()
+
+	logs := []LogEntry{}
+	for _, entry := range lm.logs {
+		if filter.Matches(entry) {
+			logs = append(logs, entry)
+		}
+	}
+
+	// Entries after the cursor may have been dropped from the buffer in the meantime
+	truncated := filter.SinceSeq > 0 && len(lm.logs) > 0 && lm.logs[0].Seq > filter.SinceSeq+1
+
+	data, err := json.Marshal(map[string]interface{}{
+		"type":      "initial_logs",
+		"logs":      logs,
+		"last_seq":  lm.lastSeq,
+		"truncated": truncated,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal initial logs: %v", err)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		return fmt.Errorf("failed to send initial logs: %v", err)
+	}
+
+	lm.clients[conn] = filter
+	return nil
 }
 
 func (lm *LogManager) RemoveWebSocketClient(conn *websocket.Conn) {
@@ -120,9 +241,12 @@ func (lm *LogManager) broadcastLog(entry LogEntry) {
 		return
 	}
 
-	// Send to all connected clients
+	// Send to all connected clients whose filter matches
 	var disconnectedClients []*websocket.Conn
-	for client := range lm.clients {
+	for client, filter := range lm.clients {
+		if !filter.Matches(entry) {
+			continue
+		}
 		err := client.WriteMessage(websocket.TextMessage, data)
 		if err != nil {
 			disconnectedClients = append(disconnectedClients, client)
@@ -136,52 +260,168 @@ func (lm *LogManager) broadcastLog(entry LogEntry) {
 	}
 }
 
-var logUpgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow connections from any origin in development
-	},
+// flushPendingLogs sends the batched entries to each client as a single new_logs message
+func (lm *LogManager) flushPendingLogs() {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+
+	lm.flushPendingLocked()
 }
 
-func (lm *LogManager) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := logUpgrader.Upgrade(w, r, nil)
+// flushPendingLocked broadcasts any batched entries; callers must hold mutex
+func (lm *LogManager) flushPendingLocked() {
+	if lm.pendingTimer != nil {
+		lm.pendingTimer.Stop()
+	}
+	entries := lm.pending
+	lm.pending = nil
+	lm.pendingTimer = nil
+	if len(entries) == 0 {
+		return
+	}
+
+	var disconnectedClients []*websocket.Conn
+	for client, filter := range lm.clients {
+		matched := make([]LogEntry, 0, len(entries))
+		for _, entry := range entries {
+			if filter.Matches(entry) {
+				matched = append(matched, entry)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		data, err := json.Marshal(map[string]interface{}{
+			"type": "new_logs",
+			"logs": matched,
+		})
+		if err != nil {
+			log.Printf("Error marshaling log batch: %v", err)
+			continue
+		}
+		if err := client.WriteMessage(websocket.TextMessage, data); err != nil {
+			disconnectedClients = append(disconnectedClients, client)
+		}
+	}
+
+	// Remove disconnected clients
+	for _, client := range disconnectedClients {
+		delete(lm.clients, client)
+		client.Close()
+	}
+}
+
+// CloseServerClients tells clients watching a single server's logs that it was deleted and closes them,
+// so they don't sit on a stream that will never receive anything again
+func (lm *LogManager) CloseServerClients(serverID string) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+
+	// Deliver anything still batched (e.g. the deletion message) before closing
+	lm.flushPendingLocked()
+
+	data, err := json.Marshal(map[string]interface{}{
+		"type":     "server_deleted",
+		"serverId": serverID,
+	})
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
+		log.Printf("Error marshaling server deleted message: %v", err)
 		return
 	}
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "server deleted")
 
+	for client, filter := range lm.clients {
+		if filter.ServerID != serverID {
+			continue
+		}
+		client.WriteMessage(websocket.TextMessage, data)
+		client.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+		delete(lm.clients, client)
+		client.Close()
+	}
+}
+
+const (
+	// logPingInterval is how often log stream clients are pinged
+	logPingInterval = 30 * time.Second
+	// logPongWait is how long a client may go without answering a ping before it is considered dead
+	logPongWait = 2 * logPingInterval
+)
+
+// keepAlive pings conn periodically and extends its read deadline on each pong, so a client that vanished
+// without a close frame makes the next read fail. The returned function stops the pings.
+func keepAlive(conn *websocket.Conn) func() {
+	conn.SetReadDeadline(time.Now().Add(logPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(logPongWait))
+	})
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(logPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				// WriteControl is safe to call alongside the broadcaster's writes
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// logUpgrader returns the upgrader for log stream connections
+func logUpgrader() websocket.Upgrader {
+	return newUpgrader(GetConfig().WebSocket.Logs, nil)
+}
+
+func (lm *LogManager) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	// Extract server ID from path if provided
 	serverId := r.URL.Path[len("/ws/logs"):]
 	if serverId != "" && serverId[0] == '/' {
 		serverId = serverId[1:] // Remove leading slash
 	}
 
-	log.Printf("New WebSocket connection for logs (serverId: %s)", serverId)
-
-	// Add client to manager
-	lm.AddWebSocketClient(conn)
-
-	// Send initial logs
-	logs := lm.GetLogs(serverId)
-	initialMessage := map[string]interface{}{
-		"type": "initial_logs",
-		"logs": logs,
+	// Validate the source filter and cursor before upgrading so clients get a plain HTTP error
+	filter, err := NewLogFilter(serverId, r.URL.Query().Get("source"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.SinceSeq, err = parseSinceSeq(r.URL.Query().Get("since_seq")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	data, err := json.Marshal(initialMessage)
+	upgrader := logUpgrader()
+	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("Error marshaling initial logs: %v", err)
-		lm.RemoveWebSocketClient(conn)
+		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
 
-	err = conn.WriteMessage(websocket.TextMessage, data)
-	if err != nil {
-		log.Printf("Error sending initial logs: %v", err)
+	log.Printf("New WebSocket connection for logs (serverId: %s, sources: %v)", serverId, filter.Sources)
+
+	// Reap clients that stop answering pings
+	stopKeepAlive := keepAlive(conn)
+	defer stopKeepAlive()
+
+	// Send the backlog (after ?since_seq= when reconnecting) and start receiving new entries
+	if err := lm.subscribe(conn, filter); err != nil {
+		log.Printf("Error subscribing log client: %v", err)
 		lm.RemoveWebSocketClient(conn)
 		return
 	}
 
-	// Keep connection alive and handle disconnection
+	// Block until the client disconnects or misses a pong
 	for {
 		_, _, err := conn.ReadMessage()
 		if err != nil {
@@ -239,13 +479,17 @@ func (poc *EnhancedProcessOutputCapture) captureStream(stream io.Reader, streamT
 				level = "WARN"
 			}
 
-			poc.logManager.AddServerLog(poc.serverID, poc.serverName, level, streamType, line)
+			if poc.logManager != nil {
+				poc.logManager.AddServerLog(poc.serverID, poc.serverName, level, streamType, line)
+			}
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
 		errorMsg := fmt.Sprintf("Error reading %s stream: %v", streamType, err)
 		poc.logger.LogProcessOutput(poc.serverID, poc.serverName, errorMsg, true)
-		poc.logManager.AddServerLog(poc.serverID, poc.serverName, "ERROR", streamType, errorMsg)
+		if poc.logManager != nil {
+			poc.logManager.AddServerLog(poc.serverID, poc.serverName, "ERROR", streamType, errorMsg)
+		}
 	}
 }
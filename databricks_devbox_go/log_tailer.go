@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// logTailInterval is how often a FileTailer polls its file for new output
+const logTailInterval = 500 * time.Millisecond
+
+// FileTailer follows a log file on disk, emitting complete lines as they are appended.
+// It polls rather than relying on filesystem notifications and survives rotation
+// (the file being renamed and recreated) and truncation.
+type FileTailer struct {
+	path     string
+	interval time.Duration
+	file     *os.File
+	info     os.FileInfo
+	offset   int64
+	partial  string
+}
+
+func NewFileTailer(path string, interval time.Duration) *FileTailer {
+	return &FileTailer{
+		path:     path,
+		interval: interval,
+	}
+}
+
+// Start positions the tailer at the current end of the file so only new output is emitted.
+// A missing file is not an error; it is picked up once created.
+func (t *FileTailer) Start() {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return
+	}
+	t.file, t.info, t.offset = file, info, info.Size()
+}
+
+// Follow emits new lines until the context is cancelled or emit returns an error
+func (t *FileTailer) Follow(ctx context.Context, emit func(line string) error) error {
+	defer t.Close()
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.poll(emit); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close releases the file currently being followed
+func (t *FileTailer) Close() {
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+}
+
+// poll drains new output from the current file, then switches to a recreated file if rotated
+func (t *FileTailer) poll(emit func(line string) error) error {
+	if t.file != nil {
+		if err := t.drain(emit); err != nil {
+			return err
+		}
+	}
+
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return nil // Rotated away and not recreated yet
+	}
+
+	switch {
+	case t.file == nil || !os.SameFile(info, t.info):
+		// The file was (re)created, so follow the new one from the beginning
+		t.Close()
+		file, err := os.Open(t.path)
+		if err != nil {
+			return nil
+		}
+		t.file, t.info, t.offset, t.partial = file, info, 0, ""
+		return t.drain(emit)
+	case info.Size() < t.offset:
+		// The file was truncated in place
+		t.offset, t.partial = 0, ""
+		return t.drain(emit)
+	}
+	return nil
+}
+
+// drain reads everything appended since the last offset, emitting complete lines
+func (t *FileTailer) drain(emit func(line string) error) error {
+	if _, err := t.file.Seek(t.offset, io.SeekStart); err != nil {
+		return nil
+	}
+
+	reader := bufio.NewReader(t.file)
+	for {
+		chunk, err := reader.ReadString('\n')
+		t.offset += int64(len(chunk))
+
+		if err != nil {
+			// Keep an incomplete trailing line until the rest of it is written
+			t.partial += chunk
+			return nil
+		}
+
+		line := strings.TrimRight(t.partial+chunk, "\r\n")
+		t.partial = ""
+		if err := emit(line); err != nil {
+			return err
+		}
+	}
+}
+
+// HandleLogTailWebSocket streams a server's on-disk process log: the last N lines
+// (?lines=, default 100) followed by new lines as they are written
+func (pm *ProcessManager) HandleLogTailWebSocket(w http.ResponseWriter, r *http.Request, serverID string, lines int) {
+	if _, err := pm.GetServer(serverID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	upgrader := logUpgrader()
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		pm.log.Printf("WebSocket upgrade error: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	pm.log.Printf("New WebSocket connection for log tail (serverId: %s)", serverID)
+
+	// Stop following clients that stop answering pings
+	stopKeepAlive := keepAlive(conn)
+	defer stopKeepAlive()
+
+	// Position the tailer before reading the backlog so no lines are missed in between
+	tailer := NewFileTailer(pm.logger.getLogFilePath(serverID), logTailInterval)
+	tailer.Start()
+
+	backlog, err := pm.logger.GetRecentLogs(serverID, lines)
+	if err != nil {
+		pm.log.Printf("Error reading recent logs for tail: %v", err)
+		backlog = []string{}
+	}
+
+	if err := writeTailMessage(conn, map[string]interface{}{
+		"type":  "initial_lines",
+		"lines": backlog,
+	}); err != nil {
+		pm.log.Printf("Error sending initial log lines: %v", err)
+		tailer.Close()
+		return
+	}
+
+	// Stop following once the client disconnects
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	err = tailer.Follow(ctx, func(line string) error {
+		return writeTailMessage(conn, map[string]interface{}{
+			"type": "new_line",
+			"line": line,
+		})
+	})
+	if err != nil && err != context.Canceled {
+		pm.log.Printf("Log tail for server %s ended: %v", serverID, err)
+	}
+}
+
+func writeTailMessage(conn *websocket.Conn, message map[string]interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// HandleLogTailSSE streams a server's on-disk process log as Server-Sent Events for clients that
+// can't use WebSockets (e.g. curl -N): the last N lines followed by new lines as they are written,
+// one "data:" event per line
+func (pm *ProcessManager) HandleLogTailSSE(w http.ResponseWriter, r *http.Request, serverID string, lines int) {
+	if _, err := pm.GetServer(serverID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Position the tailer before reading the backlog so no lines are missed in between
+	tailer := NewFileTailer(pm.logger.getLogFilePath(serverID), logTailInterval)
+	tailer.Start()
+
+	backlog, err := pm.logger.GetRecentLogs(serverID, lines)
+	if err != nil {
+		pm.log.Printf("Error reading recent logs for tail: %v", err)
+		backlog = []string{}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	writeLine := func(line string) error {
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", line); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	for _, line := range backlog {
+		if err := writeLine(line); err != nil {
+			tailer.Close()
+			return
+		}
+	}
+	flusher.Flush()
+
+	pm.log.Printf("New SSE connection for log tail (serverId: %s)", serverID)
+
+	// The request context is cancelled once the client disconnects
+	err = tailer.Follow(r.Context(), writeLine)
+	if err != nil && err != context.Canceled {
+		pm.log.Printf("Log tail for server %s ended: %v", serverID, err)
+	}
+}
@@ -2,27 +2,46 @@ package main
 
 import (
 	"bufio"
+	"compress/gzip"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
 const maxLogSize = 1024 * 1024 // 1MB
 
+// processLogTimeLayout is the timestamp format at the start of every process log line
+const processLogTimeLayout = "2006-01-02 15:04:05"
+
+// processEventMarker prefixes lifecycle events written by LogProcessEvent
+const processEventMarker = "PROCESS EVENT: "
+
+// ProcessEvent is a lifecycle event recorded in a server's process log
+type ProcessEvent struct {
+	Event     string    `json:"event"`
+	Details   string    `json:"details,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
 type ProcessLogger struct {
-	logsDir string
-	mutex   sync.RWMutex
+	logsDir     string
+	mutex       sync.RWMutex
+	lastEvents  map[string]*ProcessEvent // server_id -> most recent lifecycle event
+	eventsMutex sync.RWMutex
 }
 
-func NewProcessLogger() *ProcessLogger {
-	logsDir := "logs"
+// NewProcessLogger returns a logger that writes each server's process log under logsDir
+func NewProcessLogger(logsDir string) *ProcessLogger {
 	os.MkdirAll(logsDir, 0755)
 	return &ProcessLogger{
-		logsDir: logsDir,
+		logsDir:    logsDir,
+		lastEvents: make(map[string]*ProcessEvent),
 	}
 }
 
@@ -36,6 +55,19 @@ func (pl *ProcessLogger) getLogFilePath(serverID string) string {
 	return filepath.Join(pl.getServerLogDir(serverID), "process.log")
 }
 
+// rotationPeriodFormat returns the time layout identifying a rotation period (used to name
+// time-rotated files), or "" when only size-based rotation is configured
+func rotationPeriodFormat() string {
+	switch GetConfig().Logging.Rotation {
+	case "daily":
+		return "2006-01-02"
+	case "hourly":
+		return "2006-01-02_15"
+	default:
+		return ""
+	}
+}
+
 func (pl *ProcessLogger) rotateLogIfNeeded(logFile string) error {
 	pl.mutex.Lock()
 	defer pl.mutex.Unlock()
@@ -45,47 +77,129 @@ func (pl *ProcessLogger) rotateLogIfNeeded(logFile string) error {
 		return nil // File doesn't exist yet
 	}
 
-	if info.Size() <= maxLogSize {
+	// Every write rotates first, so a file last written in an earlier period only holds that period
+	var backupFile string
+	if periodFormat := rotationPeriodFormat(); periodFormat != "" &&
+		info.ModTime().Format(periodFormat) != time.Now().Format(periodFormat) {
+		log.Printf("Rotating log file %s (period ended: %s)", logFile, info.ModTime().Format(periodFormat))
+		backupFile = filepath.Join(filepath.Dir(logFile), fmt.Sprintf("process_%s.log", info.ModTime().Format(periodFormat)))
+	} else if info.Size() > maxLogSize {
+		log.Printf("Rotating log file %s (size: %d bytes)", logFile, info.Size())
+		timestamp := time.Now().Format("20060102_150405")
+		backupFile = filepath.Join(filepath.Dir(logFile), fmt.Sprintf("process_%s.log", timestamp))
+	} else {
 		return nil // No rotation needed
 	}
 
-	log.Printf("Rotating log file %s (size: %d bytes)", logFile, info.Size())
-
-	// Create backup filename with timestamp
-	timestamp := time.Now().Format("20060102_150405")
-	backupFile := filepath.Join(filepath.Dir(logFile), fmt.Sprintf("process_%s.log", timestamp))
+	// Don't overwrite an earlier backup for the same period (e.g. after a size rotation), whether
+	// or not it has been compressed yet
+	base := strings.TrimSuffix(backupFile, ".log")
+	for n := 1; logBackupExists(backupFile); n++ {
+		backupFile = fmt.Sprintf("%s_%d.log", base, n)
+	}
 
 	// Move current log to backup
 	if err := os.Rename(logFile, backupFile); err != nil {
 		return err
 	}
-
 	log.Printf("Rotated log to: %s", backupFile)
 
-	// Clean up old logs (keep only 5 most recent)
-	pl.cleanupOldLogs(filepath.Dir(logFile))
+	// Compress the backup without holding up writers to the new log; on failure the
+	// uncompressed file is kept. Old logs are cleaned up after (keep only 5 most recent).
+	go func() {
+		if err := compressLogFile(backupFile); err != nil {
+			log.Printf("Failed to compress rotated log %s: %v", backupFile, err)
+		}
+		pl.cleanupOldLogs(filepath.Dir(logFile))
+	}()
 
 	return nil
 }
 
-func (pl *ProcessLogger) cleanupOldLogs(logDir string) {
-	matches, err := filepath.Glob(filepath.Join(logDir, "process_*.log"))
+// logBackupExists reports whether a rotated log exists at path, or compressed at path.gz
+func logBackupExists(path string) bool {
+	for _, candidate := range []string{path, path + ".gz"} {
+		if _, err := os.Stat(candidate); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// compressLogFile gzips a rotated log to path.gz, keeping its modification time so
+// retention ordering is unchanged, and removes the original
+func compressLogFile(path string) error {
+	src, err := os.Open(path)
 	if err != nil {
-		return
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	gz.Name = filepath.Base(path)
+	gz.ModTime = info.ModTime()
+	_, err = io.Copy(gz, src)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
 	}
 
+	os.Chtimes(path+".gz", info.ModTime(), info.ModTime())
+	return os.Remove(path)
+}
+
+// rotatedLogFiles returns a directory's rotated logs (compressed or not), newest first
+func rotatedLogFiles(logDir string) []string {
+	plain, _ := filepath.Glob(filepath.Join(logDir, "process_*.log"))
+	compressed, _ := filepath.Glob(filepath.Join(logDir, "process_*.log.gz"))
+	matches := append(plain, compressed...)
+
+	sort.Slice(matches, func(i, j int) bool {
+		return logModTime(matches[i]).After(logModTime(matches[j]))
+	})
+	return matches
+}
+
+func (pl *ProcessLogger) cleanupOldLogs(logDir string) {
+	matches := rotatedLogFiles(logDir)
+
 	// Keep only the 5 most recent files
 	if len(matches) <= 5 {
 		return
 	}
 
-	// Sort by modification time and remove oldest
+	// Files are sorted newest first, so remove the oldest
 	for i := 5; i < len(matches); i++ {
 		os.Remove(matches[i])
 		log.Printf("Removed old log file: %s", matches[i])
 	}
 }
 
+// logModTime returns a file's modification time, or the zero time if it can't be read
+func logModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
 func (pl *ProcessLogger) LogProcessOutput(serverID, serverName, output string, isError bool) {
 	logFile := pl.getLogFilePath(serverID)
 
@@ -100,7 +214,7 @@ func (pl *ProcessLogger) LogProcessOutput(serverID, serverName, output string, i
 	}
 	defer file.Close()
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	timestamp := time.Now().Format(processLogTimeLayout)
 	logLevel := "INFO"
 	prefix := "STDOUT"
 	if isError {
@@ -116,6 +230,9 @@ func (pl *ProcessLogger) LogProcessOutput(serverID, serverName, output string, i
 func (pl *ProcessLogger) LogProcessEvent(serverID, serverName, event, details string) {
 	logFile := pl.getLogFilePath(serverID)
 
+	// Check if rotation is needed
+	pl.rotateLogIfNeeded(logFile)
+
 	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
 		log.Printf("Failed to open log file %s: %v", logFile, err)
@@ -123,35 +240,127 @@ func (pl *ProcessLogger) LogProcessEvent(serverID, serverName, event, details st
 	}
 	defer file.Close()
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	message := fmt.Sprintf("PROCESS EVENT: %s", event)
+	now := time.Now()
+	timestamp := now.Format(processLogTimeLayout)
+	message := processEventMarker + event
 	if details != "" {
 		message += fmt.Sprintf(" - %s", details)
 	}
 
 	logEntry := fmt.Sprintf("%s - process_%s - INFO - %s\n", timestamp, serverID, message)
 	file.WriteString(logEntry)
+
+	pl.eventsMutex.Lock()
+	pl.lastEvents[serverID] = &ProcessEvent{Event: event, Details: details, Timestamp: now}
+	pl.eventsMutex.Unlock()
 }
 
-func (pl *ProcessLogger) GetRecentLogs(serverID string, lines int) ([]string, error) {
-	logFile := pl.getLogFilePath(serverID)
+// LastEvent returns the most recent lifecycle event for a server, or nil if none was recorded.
+// Events logged before this process started are recovered from the server's process log.
+func (pl *ProcessLogger) LastEvent(serverID string) *ProcessEvent {
+	pl.eventsMutex.RLock()
+	event, exists := pl.lastEvents[serverID]
+	pl.eventsMutex.RUnlock()
+	if exists {
+		return event
+	}
+
+	event = pl.readLastEvent(serverID)
+	if event != nil {
+		pl.eventsMutex.Lock()
+		if _, exists := pl.lastEvents[serverID]; !exists {
+			pl.lastEvents[serverID] = event
+		}
+		pl.eventsMutex.Unlock()
+	}
+	return event
+}
+
+// readLastEvent scans a server's process log for the last lifecycle event line
+func (pl *ProcessLogger) readLastEvent(serverID string) *ProcessEvent {
+	events := pl.RecentEvents(serverID, 1)
+	if len(events) == 0 {
+		return nil
+	}
+	return events[0]
+}
+
+// recentEventsWindow is how many of the last lines of a process log RecentEvents looks through.
+// Events between a lot of output may be missed, but a long log is never read as a whole.
+const recentEventsWindow = 5000
 
-	file, err := os.Open(logFile)
+// RecentEvents returns up to limit of the most recent lifecycle events in the last
+// recentEventsWindow lines of a server's current process log
+func (pl *ProcessLogger) RecentEvents(serverID string, limit int) []*ProcessEvent {
+	lines, err := tailLogLines(pl.getLogFilePath(serverID), max(recentEventsWindow, limit))
 	if err != nil {
-		return []string{}, nil // Return empty if file doesn't exist
+		return []*ProcessEvent{}
 	}
-	defer file.Close()
 
-	var allLines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		allLines = append(allLines, scanner.Text())
+	events := []*ProcessEvent{}
+	for i := len(lines) - 1; i >= 0 && len(events) < limit; i-- {
+		if event := parseProcessEvent(lines[i]); event != nil {
+			events = append(events, event)
+		}
+	}
+
+	// Return oldest first, like the log itself
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events
+}
+
+// parseProcessEvent parses a lifecycle event log line, returning nil for other lines.
+// Lines look like: "2006-01-02 15:04:05 - process_{id} - INFO - PROCESS EVENT: {event} - {details}"
+func parseProcessEvent(line string) *ProcessEvent {
+	idx := strings.Index(line, processEventMarker)
+	if idx < 0 {
+		return nil
 	}
 
-	if err := scanner.Err(); err != nil {
+	event := &ProcessEvent{}
+	if len(line) >= 19 {
+		if ts, err := time.ParseInLocation(processLogTimeLayout, line[:19], time.Local); err == nil {
+			event.Timestamp = ts
+		}
+	}
+	message := line[idx+len(processEventMarker):]
+	if name, details, found := strings.Cut(message, " - "); found {
+		event.Event = name
+		event.Details = details
+	} else {
+		event.Event = message
+	}
+	return event
+}
+
+// GetRecentLogs returns the last lines of a server's process log, continuing into rotated
+// (possibly gzipped) backups when the current file holds fewer lines than requested
+func (pl *ProcessLogger) GetRecentLogs(serverID string, lines int) ([]string, error) {
+	logFile := pl.getLogFilePath(serverID)
+
+	allLines, err := tailLogLines(logFile, lines)
+	if err != nil && !os.IsNotExist(err) {
 		return nil, err
 	}
 
+	for _, backup := range rotatedLogFiles(filepath.Dir(logFile)) {
+		if len(allLines) >= lines {
+			break
+		}
+		backupLines, err := tailLogLines(backup, lines-len(allLines))
+		if err != nil {
+			log.Printf("Failed to read rotated log %s: %v", backup, err)
+			continue
+		}
+		allLines = append(backupLines, allLines...)
+	}
+
+	if allLines == nil {
+		return []string{}, nil
+	}
+
 	// Return last 'lines' number of lines
 	if len(allLines) <= lines {
 		return allLines, nil
@@ -160,9 +369,95 @@ func (pl *ProcessLogger) GetRecentLogs(serverID string, lines int) ([]string, er
 	return allLines[len(allLines)-lines:], nil
 }
 
+// logTailChunkSize is how much of a log file tailLogLines reads per step backwards from the end
+const logTailChunkSize = 64 * 1024
+
+// tailLogLines returns at most the last n lines of a log file. Plain files are read backwards from
+// the end in chunks until enough lines are found; .gz files can't be seeked, so they are streamed
+// keeping only the last n lines.
+func tailLogLines(path string, n int) ([]string, error) {
+	if strings.HasSuffix(path, ".gz") {
+		return tailCompressedLogLines(path, n)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read chunks until there are more than n line breaks (the earliest line may be cut off) or the file start
+	offset := info.Size()
+	var data []byte
+	newlines := 0
+	for offset > 0 && newlines <= n {
+		size := int64(logTailChunkSize)
+		if offset < size {
+			size = offset
+		}
+		offset -= size
+
+		chunk := make([]byte, size)
+		if _, err := file.ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+		newlines += strings.Count(string(chunk), "\n")
+		data = append(chunk, data...)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if offset > 0 {
+		lines = lines[1:] // partial line from the middle of the file
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// tailCompressedLogLines streams a gzipped log, keeping only its last n lines
+func tailCompressedLogLines(path string, n int) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	return lines, scanner.Err()
+}
+
 func (pl *ProcessLogger) CleanupServerLogs(serverID string) {
 	serverLogDir := pl.getServerLogDir(serverID)
 	os.RemoveAll(serverLogDir)
+
+	pl.eventsMutex.Lock()
+	delete(pl.lastEvents, serverID)
+	pl.eventsMutex.Unlock()
 	log.Printf("Cleaned up log directory for server %s", serverID)
 }
 
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
@@ -22,11 +23,95 @@ func main() {
 
 	// Initialize services
 	logManager := NewLogManager()
-	processManager := NewProcessManager()
+	processManager, err := NewProcessManager(log.Default())
+	if err != nil {
+		log.Fatalf("Failed to initialize process manager: %v", err)
+	}
 	processManager.SetLogManager(logManager) // Connect log manager to process manager
 	defer processManager.Cleanup()
 
+	// Record who changed what in logs/audit.log
+	auditLogger := NewAuditLogger(filepath.Join("logs", "audit.log"))
+
 	// Create Gin router
+	r := newRouter(auditLogger)
+
+	// Compress REST responses; the proxy, WebSockets and assets are left as they are
+	r.Use(GzipMiddleware())
+
+	// Setup routes
+	setupRoutes(r, processManager, logManager)
+
+	// Get port from environment variable, default to 8005
+	port := os.Getenv("DEVBOX_SERVER_PORT")
+	if port == "" {
+		port = "8005"
+	}
+
+	// DEVBOX_PROXY_PORT moves the /vscode proxy to its own listener, so only it needs to be
+	// exposed publicly while the management API stays internal
+	proxyPort := os.Getenv("DEVBOX_PROXY_PORT")
+	servers := []*http.Server{newHTTPServer(port, r)}
+	if proxyPort == "" || proxyPort == port {
+		proxyPort = port
+		setupProxyRoutes(r, processManager)
+	} else {
+		proxyRouter := newRouter(auditLogger)
+		proxyRouter.GET("/health", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{
+				"status":  "healthy",
+				"service": "Databricks Devbox Proxy",
+			})
+		})
+		setupProxyRoutes(proxyRouter, processManager)
+		servers = append(servers, newHTTPServer(proxyPort, proxyRouter))
+
+		// Point stray proxy requests at the right port instead of the web UI
+		r.Any("/vscode/*path", func(c *gin.Context) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "code-server is proxied on port " + proxyPort})
+		})
+	}
+
+	// Health checks can verify servers through our own /vscode proxy
+	processManager.SetProxyBaseURL("http://localhost:" + proxyPort)
+
+	// Start servers in goroutines
+	for i, srv := range servers {
+		go func() {
+			if i == 0 {
+				log.Printf("Starting Databricks Devbox on %s", srv.Addr)
+			} else {
+				log.Printf("Starting Databricks Devbox proxy on %s", srv.Addr)
+			}
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Failed to start server: %v", err)
+			}
+		}()
+	}
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down server...")
+
+	// Graceful shutdown with 30 second timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Fatal("Server forced to shutdown:", err)
+		}
+	}
+
+	log.Println("Server exited")
+}
+
+// newRouter creates a gin engine with the request logging, recovery, CORS and audit middleware
+// shared by the API and proxy listeners
+func newRouter(auditLogger *AuditLogger) *gin.Engine {
 	r := gin.New()
 
 	// Add middleware with verbose logging
@@ -45,6 +130,7 @@ func main() {
 	}))
 	r.Use(gin.Recovery())
 	r.Use(CORSMiddleware())
+	r.Use(AuditMiddleware(auditLogger))
 
 	// Add route debugging middleware
 	r.Use(func(c *gin.Context) {
@@ -52,46 +138,68 @@ func main() {
 		c.Next()
 		fmt.Printf("DEBUG MIDDLEWARE: Status: %d\n", c.Writer.Status())
 	})
+	return r
+}
 
-	// Setup routes
-	setupRoutes(r, processManager, logManager)
-
-	// Get port from environment variable, default to 8005
-	port := os.Getenv("DEVBOX_SERVER_PORT")
-	if port == "" {
-		port = "8005"
+// newHTTPServer serves handler on port. Slow clients can't hold connections open indefinitely;
+// the proxy, WebSocket and long-running routes lift these deadlines per request.
+func newHTTPServer(port string, handler http.Handler) *http.Server {
+	timeouts := GetConfig().Timeouts
+	return &http.Server{
+		Addr:         ":" + port,
+		Handler:      handler,
+		ReadTimeout:  timeouts.HTTPRead(),
+		WriteTimeout: timeouts.HTTPWrite(),
+		IdleTimeout:  timeouts.HTTPIdle(),
 	}
+}
 
-	// Create HTTP server
-	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: r,
-	}
+// OperationTimeoutMiddleware bounds the request context of long-running handlers so that
+// blocking work (cloning, extension installs) is cancelled and reported instead of hanging
+func OperationTimeoutMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := GetConfig().Timeouts.Operation()
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
 
-	// Start server in goroutine
-	go func() {
-		log.Printf("Starting Databricks Devbox on :%s", port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
-		}
-	}()
+		// Leave time to read a large upload and to write the response once the operation ends
+		setConnectionDeadline(c, time.Now().Add(timeout+time.Minute))
 
-	// Wait for interrupt signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
 
-	log.Println("Shutting down server...")
+// NoDeadlineMiddleware lifts the server's read and write timeouts for requests that stream for
+// as long as the client stays connected, such as the editor proxy and log WebSockets
+func NoDeadlineMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setConnectionDeadline(c, time.Time{})
+		c.Next()
+	}
+}
 
-	// Graceful shutdown with 30 second timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// setConnectionDeadline replaces the read and write deadlines of the request's connection;
+// the zero time removes them. Writers without deadline support are left as they are.
+func setConnectionDeadline(c *gin.Context, deadline time.Time) {
+	controller := http.NewResponseController(c.Writer)
+	controller.SetReadDeadline(deadline)
+	controller.SetWriteDeadline(deadline)
+}
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+// MaintenanceMiddleware rejects mutating requests with 503 while maintenance mode is on
+func MaintenanceMiddleware(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if status := pm.Maintenance(); status.Enabled {
+			message := "Devbox is in maintenance mode; servers can't be created or changed right now"
+			if status.Message != "" {
+				message += ": " + status.Message
+			}
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": message, "maintenance": status})
+			return
+		}
+		c.Next()
 	}
-
-	log.Println("Server exited")
 }
 
 func CORSMiddleware() gin.HandlerFunc {
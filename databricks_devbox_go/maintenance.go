@@ -0,0 +1,45 @@
+package main
+
+import (
+	"time"
+)
+
+// MaintenanceStatus reports whether the manager is frozen for maintenance
+type MaintenanceStatus struct {
+	Enabled bool       `json:"enabled"`
+	Message string     `json:"message,omitempty"` // Shown to clients whose requests are rejected
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+// SetMaintenance turns maintenance mode on or off. While it is on, the API rejects mutating
+// requests; existing servers keep running and reads keep working.
+func (pm *ProcessManager) SetMaintenance(enabled bool, message string) MaintenanceStatus {
+	pm.maintenanceMutex.Lock()
+	defer pm.maintenanceMutex.Unlock()
+
+	if !enabled {
+		pm.maintenance = MaintenanceStatus{}
+	} else {
+		since := time.Now()
+		if pm.maintenance.Enabled {
+			since = *pm.maintenance.Since // Updating the message doesn't restart the window
+		}
+		pm.maintenance = MaintenanceStatus{Enabled: true, Message: message, Since: &since}
+	}
+
+	if pm.logManager != nil {
+		if enabled {
+			pm.logManager.AddSystemLog("WARN", "Maintenance mode enabled: "+message)
+		} else {
+			pm.logManager.AddSystemLog("INFO", "Maintenance mode disabled")
+		}
+	}
+	return pm.maintenance
+}
+
+// Maintenance returns the current maintenance status
+func (pm *ProcessManager) Maintenance() MaintenanceStatus {
+	pm.maintenanceMutex.RLock()
+	defer pm.maintenanceMutex.RUnlock()
+	return pm.maintenance
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// ValidateOpenPath checks that path is relative and stays inside the workspace; empty opens nothing extra
+func ValidateOpenPath(path string) error {
+	if path == "" {
+		return nil
+	}
+	if !filepath.IsLocal(path) {
+		return fmt.Errorf("open path must be a relative path inside the workspace: %s", path)
+	}
+	return nil
+}
+
+// SetOpenPath changes the file the editor opens when the server is opened from the UI
+func (pm *ProcessManager) SetOpenPath(id, path string) error {
+	if err := ValidateOpenPath(path); err != nil {
+		return err
+	}
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	server, exists := pm.servers[id]
+	if !exists {
+		return fmt.Errorf("server not found: %s", id)
+	}
+
+	server.OpenPath = path
+	message := "Opens no extra file"
+	if path != "" {
+		message = fmt.Sprintf("Opens %s", path)
+	}
+	pm.logger.LogProcessEvent(id, server.Name, "OPEN_PATH_UPDATED", message)
+	pm.saveServers()
+	return nil
+}
+
+// resolveOpenPath returns the real path of the server's open path, or why it can't be opened.
+// Symlinks are resolved so a link can't point the editor outside the workspace.
+func resolveOpenPath(server *ServerInstance) (resolved, problem string) {
+	path := filepath.Join(server.WorkspacePath, server.OpenPath)
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", "not found"
+	}
+	if root, err := filepath.EvalSymlinks(server.WorkspacePath); err != nil || !pathWithin(root, resolved) {
+		return "", "outside the workspace"
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", "not accessible"
+	}
+	if info.IsDir() {
+		return "", "a directory, not a file"
+	}
+	return resolved, ""
+}
+
+// checkOpenPath warns at start when the server's open path can't be opened, since the editor
+// would then silently show only the workspace
+func (pm *ProcessManager) checkOpenPath(server *ServerInstance) {
+	if server.OpenPath == "" {
+		return
+	}
+	if _, problem := resolveOpenPath(server); problem != "" {
+		message := fmt.Sprintf("Open path %s is %s, opening only the workspace", server.OpenPath, problem)
+		pm.log.Printf("Server %s: %s", server.Name, message)
+		if pm.logManager != nil {
+			pm.logManager.AddServerLog(server.ID, server.Name, "WARN", "server", message)
+		}
+	}
+}
+
+// editorURL returns the proxied URL the server's editor is opened at. The workspace stays what
+// code-server was launched with; an open path is opened in it through the workbench's openFile
+// payload, as code-server ignores extra file arguments on its command line.
+func editorURL(server *ServerInstance) string {
+	base := fmt.Sprintf("/vscode/%d/", server.Port)
+	if server.OpenPath == "" || !isCodeServerRuntime(server.Runtime) {
+		return base
+	}
+	resolved, problem := resolveOpenPath(server)
+	if problem != "" {
+		return base
+	}
+
+	fileURI := url.URL{Scheme: "vscode-remote", Path: filepath.ToSlash(resolved)}
+	payload, err := json.Marshal([][2]string{{"openFile", fileURI.String()}})
+	if err != nil {
+		return base
+	}
+	return base + "?" + url.Values{"payload": {string(payload)}}.Encode()
+}
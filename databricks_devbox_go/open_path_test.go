@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestValidateOpenPath(t *testing.T) {
+	for path, valid := range map[string]bool{
+		"":                    true,
+		"README.md":           true,
+		"src/main.py":         true,
+		"../secrets.txt":      false,
+		"src/../../etc/hosts": false,
+		"/etc/hosts":          false,
+	} {
+		if err := ValidateOpenPath(path); (err == nil) != valid {
+			t.Errorf("ValidateOpenPath(%q) = %v, want valid %v", path, err, valid)
+		}
+	}
+}
+
+func TestResolveOpenPathStaysInWorkspace(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks needs extra privileges on Windows")
+	}
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("not in the workspace"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	workspace := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workspace, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workspace, "src", "main.py"), []byte("print()"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(workspace, "escape.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("src/main.py", filepath.Join(workspace, "main.py")); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]string{
+		"src/main.py": "",
+		"main.py":     "",
+		"escape.txt":  "outside the workspace",
+		"missing.txt": "not found",
+		"src":         "a directory, not a file",
+	} {
+		server := &ServerInstance{Port: 8010, WorkspacePath: workspace, OpenPath: path}
+		resolved, problem := resolveOpenPath(server)
+		if problem != want {
+			t.Errorf("resolveOpenPath(%q) problem = %q, want %q", path, problem, want)
+			continue
+		}
+		hasPayload := strings.Contains(editorURL(server), "payload=")
+		if want == "" && (!strings.HasSuffix(resolved, filepath.Join("src", "main.py")) || !hasPayload) {
+			t.Errorf("%q resolved to %s with editor URL %s, want src/main.py opened", path, resolved, editorURL(server))
+		}
+		if want != "" && hasPayload {
+			t.Errorf("editor URL for %q is %s, want only the workspace opened", path, editorURL(server))
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import "sort"
+
+// PortAssignment is a port held by a server, or reserved by a create still in progress
+type PortAssignment struct {
+	Port       int    `json:"port"`
+	ServerID   string `json:"server_id,omitempty"`
+	ServerName string `json:"server_name,omitempty"`
+	Reserved   bool   `json:"reserved,omitempty"` // Held for a server that is still being created
+	InRange    bool   `json:"in_range"`           // False for servers left on a port outside the current range
+}
+
+// PortUsage describes how the configured port range is allocated
+type PortUsage struct {
+	Range     PortRange        `json:"range"`
+	Used      []PortAssignment `json:"used"`
+	Free      []int            `json:"free"`
+	UsedCount int              `json:"used_count"` // Ports in the range that are taken
+	FreeCount int              `json:"free_count"`
+}
+
+// PortUsage reports which ports in server.code_server_port_range are taken and which are free
+func (pm *ProcessManager) PortUsage() PortUsage {
+	portRange := GetConfig().Server.CodeServerPortRange
+
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	usage := PortUsage{Range: portRange, Used: []PortAssignment{}, Free: []int{}}
+	for port, id := range pm.portMap {
+		assignment := PortAssignment{Port: port, ServerID: id, Reserved: id == "", InRange: portRange.Contains(port)}
+		if server, exists := pm.servers[id]; exists {
+			assignment.ServerName = server.Name
+		}
+		usage.Used = append(usage.Used, assignment)
+		if assignment.InRange {
+			usage.UsedCount++
+		}
+	}
+	sort.Slice(usage.Used, func(i, j int) bool { return usage.Used[i].Port < usage.Used[j].Port })
+
+	for port := portRange.Start; port <= portRange.End; port++ {
+		if _, exists := pm.portMap[port]; !exists {
+			usage.Free = append(usage.Free, port)
+		}
+	}
+	usage.FreeCount = len(usage.Free)
+	return usage
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// LaunchedProcess is a server process started by a ProcessLauncher
+type LaunchedProcess interface {
+	Pid() int
+	// Output returns the process's stdout and stderr, read until it exits
+	Output() (stdout, stderr io.Reader)
+	// Wait blocks until the process exits, returning its exit error
+	Wait() error
+	Signal(sig os.Signal) error
+	// Metrics samples the process's resource usage for the metrics routine
+	Metrics() (ProcessMetrics, error)
+}
+
+// ProcessMetrics is a point-in-time sample of a server process
+type ProcessMetrics struct {
+	Running    bool
+	CPUSeconds *float64 // Cumulative user+system CPU time; nil if it couldn't be read
+	CPUPercent *float64 // Current usage as reported by the backend, used instead of CPUSeconds when set
+	MemoryMB   float64  // Resident memory; 0 if it couldn't be read
+}
+
+// osProcessMetrics samples a local process by PID, which also covers servers still running
+// from before a restart that no launcher started
+func osProcessMetrics(pid int) (ProcessMetrics, error) {
+	proc, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return ProcessMetrics{}, err
+	}
+
+	var metrics ProcessMetrics
+	if running, err := proc.IsRunning(); err != nil || !running {
+		return metrics, nil
+	}
+	metrics.Running = true
+	if times, err := proc.Times(); err == nil {
+		total := times.User + times.System
+		metrics.CPUSeconds = &total
+	}
+	if memInfo, err := proc.MemoryInfo(); err == nil {
+		metrics.MemoryMB = float64(memInfo.RSS) / 1024 / 1024
+	}
+	return metrics, nil
+}
+
+// processGone reports whether a sample shows the process has exited, as opposed to it being
+// unreadable (err set, e.g. owned by another user) while possibly still running
+func processGone(metrics ProcessMetrics, err error) bool {
+	if err != nil {
+		return errors.Is(err, process.ErrorProcessNotRunning)
+	}
+	return !metrics.Running
+}
+
+// processSignaler is the part of a process needed to stop it; *os.Process also satisfies it
+type processSignaler interface {
+	Signal(sig os.Signal) error
+}
+
+// ProcessLauncher starts server processes; the returned handle is used to signal, wait for and
+// sample them. The default runs them as local child processes with os/exec, and other backends
+// (containers, remote hosts) can implement it. NewFakeProcessLauncher starts nothing, so routes
+// and the proxy can be tested without an editor.
+type ProcessLauncher interface {
+	// Start launches cmd for a server that will listen on port
+	Start(cmd *exec.Cmd, port int) (LaunchedProcess, error)
+}
+
+// execLauncher runs server commands as child processes
+type execLauncher struct {
+	log      Logger
+	freePort func(port int) error // kills whatever still holds the port from an earlier run
+}
+
+func (l *execLauncher) Start(cmd *exec.Cmd, port int) (LaunchedProcess, error) {
+	// Kill any existing process on the port before starting
+	if err := l.freePort(port); err != nil {
+		l.log.Printf("Warning: Failed to kill existing process on port %d: %v", port, err)
+		// Continue anyway - the port might just be free
+	}
+
+	return startExecProcess(cmd)
+}
+
+// startExecProcess starts cmd with its output piped back for logging
+func startExecProcess(cmd *exec.Cmd) (*execProcess, error) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stdout pipe: %v", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stderr pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &execProcess{cmd: cmd, stdout: stdout, stderr: stderr}, nil
+}
+
+type execProcess struct {
+	cmd            *exec.Cmd
+	stdout, stderr io.Reader
+}
+
+func (p *execProcess) Pid() int                         { return p.cmd.Process.Pid }
+func (p *execProcess) Output() (io.Reader, io.Reader)   { return p.stdout, p.stderr }
+func (p *execProcess) Wait() error                      { return p.cmd.Wait() }
+func (p *execProcess) Signal(sig os.Signal) error       { return p.cmd.Process.Signal(sig) }
+func (p *execProcess) Metrics() (ProcessMetrics, error) { return osProcessMetrics(p.Pid()) }
+
+// fakePIDBase is above Linux's highest possible PID (2^22), so fake processes can never be
+// mistaken for, or signal, a real one
+const fakePIDBase = 1 << 22
+
+// FakeProcessLauncher records launched commands and starts processes that only exist in memory.
+// A fake process runs until it is signalled or Exit is called.
+type FakeProcessLauncher struct {
+	mutex     sync.Mutex
+	nextPID   int
+	processes map[int]*FakeProcess
+	commands  [][]string
+	failures  []fakeFailure // exits of the next launches, in order
+}
+
+// fakeFailure is a launch that exits at once, see ExitNextWith
+type fakeFailure struct {
+	stderr string
+	err    error
+}
+
+// ExitNextWith makes the next launched process write stderr and exit at once with err, like a
+// server that fails on startup. Calls queue up for the launches after it.
+func (l *FakeProcessLauncher) ExitNextWith(stderr string, err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.failures = append(l.failures, fakeFailure{stderr: stderr, err: err})
+}
+
+// NewFakeProcessLauncher returns a ProcessLauncher that never spawns anything, for tests
+func NewFakeProcessLauncher() *FakeProcessLauncher {
+	return &FakeProcessLauncher{nextPID: fakePIDBase, processes: make(map[int]*FakeProcess)}
+}
+
+func (l *FakeProcessLauncher) Start(cmd *exec.Cmd, port int) (LaunchedProcess, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.nextPID++
+	process := &FakeProcess{pid: l.nextPID, exited: make(chan struct{})}
+	l.processes[process.pid] = process
+	l.commands = append(l.commands, append([]string(nil), cmd.Args...))
+	if len(l.failures) > 0 {
+		failure := l.failures[0]
+		l.failures = l.failures[1:]
+		process.stderr = failure.stderr
+		process.Exit(failure.err)
+	}
+	return process, nil
+}
+
+// Commands returns the command lines launched so far, in order
+func (l *FakeProcessLauncher) Commands() [][]string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([][]string(nil), l.commands...)
+}
+
+// Process returns the fake process with pid, or nil
+func (l *FakeProcessLauncher) Process(pid int) *FakeProcess {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.processes[pid]
+}
+
+// FakeProcess is a LaunchedProcess that exits when signalled or told to
+type FakeProcess struct {
+	pid        int
+	once       sync.Once
+	exited     chan struct{}
+	err        error
+	stderr     string
+	ignoreTerm atomic.Bool
+}
+
+// IgnoreTerm keeps the process running on SIGTERM, like a server slow to shut down; it still
+// exits when killed or told to
+func (p *FakeProcess) IgnoreTerm() {
+	p.ignoreTerm.Store(true)
+}
+
+// Exit ends the process as if it exited on its own with err (nil for a clean exit)
+func (p *FakeProcess) Exit(err error) {
+	p.once.Do(func() {
+		p.err = err
+		close(p.exited)
+	})
+}
+
+func (p *FakeProcess) Pid() int { return p.pid }
+
+func (p *FakeProcess) Output() (io.Reader, io.Reader) {
+	return strings.NewReader(""), strings.NewReader(p.stderr)
+}
+
+func (p *FakeProcess) Wait() error {
+	<-p.exited
+	return p.err
+}
+
+// Metrics reports a running fake process as idle
+func (p *FakeProcess) Metrics() (ProcessMetrics, error) {
+	select {
+	case <-p.exited:
+		return ProcessMetrics{}, nil
+	default:
+		zero := 0.0
+		return ProcessMetrics{Running: true, CPUSeconds: &zero}, nil
+	}
+}
+
+func (p *FakeProcess) Signal(sig os.Signal) error {
+	select {
+	case <-p.exited:
+		return os.ErrProcessDone
+	default:
+	}
+	if sig == syscall.SIGTERM && p.ignoreTerm.Load() {
+		return nil
+	}
+	p.Exit(fmt.Errorf("signal: %v", sig))
+	return nil
+}
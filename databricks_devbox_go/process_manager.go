@@ -1,22 +1,33 @@
 package main
 
 import (
+	"archive/tar"
 	"archive/zip"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/shirou/gopsutil/v3/process"
 )
 
 type ServerStatus string
@@ -37,9 +48,10 @@ const (
 )
 
 type ExtensionProgress struct {
-	Name   string                 `json:"name"`
-	Status ExtensionInstallStatus `json:"status"`
-	Error  string                 `json:"error,omitempty"`
+	Name       string                 `json:"name"`
+	Status     ExtensionInstallStatus `json:"status"`
+	Error      string                 `json:"error,omitempty"`
+	DurationMs int64                  `json:"duration_ms,omitempty"` // Time the install took, set once finished
 }
 
 type ExtensionInstallationProgress struct {
@@ -53,58 +65,260 @@ type ExtensionInstallationProgress struct {
 }
 
 type ServerInstance struct {
-	ID            string       `json:"id"`
-	Name          string       `json:"name"`
-	Port          int          `json:"port"`
-	WorkspacePath string       `json:"workspace_path"`
-	Extensions    []string     `json:"extensions"`
-	Status        ServerStatus `json:"status"`
-	PID           *int         `json:"pid,omitempty"`
-	StartTime     *time.Time   `json:"start_time,omitempty"`
-	Command       []string     `json:"command,omitempty"`
-	Uptime        *float64     `json:"uptime,omitempty"`      // Uptime in seconds
-	CPUPercent    *float64     `json:"cpu_percent,omitempty"` // CPU usage percentage
-	MemoryMB      *float64     `json:"memory_mb,omitempty"`   // Memory usage in MB
-	LastUpdate    *time.Time   `json:"last_update,omitempty"` // Last metrics update time
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	Port           int               `json:"port"`
+	WorkspacePath  string            `json:"workspace_path"`
+	WorkspaceFile  string            `json:"workspace_file,omitempty"` // .code-workspace file opened instead of the directory, relative to it
+	OpenPath       string            `json:"open_path,omitempty"`      // File in the workspace the editor opens, via editor_url
+	OwnedWorkspace bool              `json:"owned_workspace"`          // False for adopted external directories, which are never deleted
+	Runtime        string            `json:"runtime,omitempty"`        // Runtime key from config; empty means code-server
+	Extensions     []string          `json:"extensions"`
+	BaseExtensions []string          `json:"base_extensions,omitempty"` // Extensions inherited from the seeded base directory
+	Status         ServerStatus      `json:"status"`
+	PID            *int              `json:"pid,omitempty"`
+	StartTime      *time.Time        `json:"start_time,omitempty"`
+	Command        []string          `json:"command,omitempty"`
+	ExtraArgs      []string          `json:"extra_args,omitempty"`      // Additional editor flags appended at launch (validated against a denylist)
+	EditorFlags    *EditorFlags      `json:"editor_flags,omitempty"`    // Overrides of the configured code-server telemetry/update/download flags
+	Env            map[string]string `json:"env,omitempty"`             // Variables set by the manager at launch (inherited env excluded, secrets redacted)
+	Uptime         *float64          `json:"uptime,omitempty"`          // Uptime in seconds
+	CPUPercent     *float64          `json:"cpu_percent,omitempty"`     // CPU usage percentage
+	MemoryMB       *float64          `json:"memory_mb,omitempty"`       // Memory usage in MB
+	LastUpdate     *time.Time        `json:"last_update,omitempty"`     // Last metrics update time
+	ResourceLimits *ResourceLimits   `json:"resource_limits,omitempty"` // Optional niceness/cgroup limits applied at start
+	PostInit       *PostInitResult   `json:"post_init,omitempty"`       // Outcome of the post-init command, if one ran
+	Hooks          *LifecycleHooks   `json:"hooks,omitempty"`           // Per-server lifecycle hooks overriding the configured defaults
+	RestartPolicy  RestartPolicy     `json:"restart_policy,omitempty"`  // Restart after the process exits on its own; empty means never
+	Backend        string            `json:"backend,omitempty"`         // process or docker; empty follows server.backend
+	RunAsUser      string            `json:"run_as_user,omitempty"`     // OS user the server runs as; empty follows server.run_as_user
+	RestartCount   int               `json:"restart_count,omitempty"`   // Automatic restarts since the server last ran stably or was started by hand
+	LastAccess     *time.Time        `json:"last_access,omitempty"`     // Last proxied request or WebSocket activity
 }
 
 type ProcessManager struct {
 	servers                map[string]*ServerInstance
 	mutex                  sync.RWMutex
 	portMap                map[int]string // port -> server_id mapping
-	nextPort               int
 	logger                 *ProcessLogger
 	logManager             *LogManager
 	dataDir                string
-	serversFile            string
+	store                  StateStore                                // persisted server state, data/servers.json unless injected
 	extensionProgress      map[string]*ExtensionInstallationProgress // server_id -> progress
 	extensionProgressMutex sync.RWMutex
+	baseExtensionsMutex    sync.Mutex        // guards seeding and copying of the shared base extensions
+	operations             map[string]string // server_id -> lifecycle operation in progress
+	operationsMutex        sync.Mutex
+	resourceAlerts         map[string]*resourceAlertState // server_id -> active resource alerts, guarded by mutex
+	cpuSampler             *cpuSampler                    // CPU usage over the window between metrics ticks
+	statusNotifier         *statusNotifier                // delivers status changes to registered listeners
+	log                    Logger                         // destination for operational messages (stdout unless injected)
+	proxyBaseURL           atomic.Value                   // string: the manager's own base URL, used for end-to-end health checks
+	maintenance            MaintenanceStatus              // set via POST /maintenance, guarded by maintenanceMutex
+	maintenanceMutex       sync.RWMutex
+	activeConnections      map[string]int             // server_id -> open proxied WebSocket connections, guarded by mutex
+	launchers              map[string]ProcessLauncher // backend -> launcher that starts its servers
+	processes              map[string]LaunchedProcess // server_id -> process launched by this manager, guarded by mutex
+	exits                  map[string]chan struct{}   // server_id -> closed once its launched process has exited, guarded by mutex
+	baseDir                string                     // directory holding data/, logs/ and workspace/; the working directory when empty
+	workspaceDir           string                     // parent of the workspaces the manager creates
+	done                   chan struct{}              // closed by Close to stop the background routines
+	closeOnce              sync.Once
+	routines               sync.WaitGroup // background routines Close waits for
+}
+
+// ErrServerBusy is returned when a lifecycle operation is already in progress for a server
+var ErrServerBusy = errors.New("server is busy")
+
+// ErrMemoryBudgetExceeded is returned when starting another server would exceed the memory budget
+var ErrMemoryBudgetExceeded = errors.New("memory budget exceeded")
+
+// ErrPortRangeExhausted is returned when every port in server.code_server_port_range is taken
+var ErrPortRangeExhausted = errors.New("no free port in the configured range")
+
+// ErrInvalidPort is returned when a requested server port is outside the configured range
+var ErrInvalidPort = errors.New("invalid port")
+
+// ErrPortInUse is returned when a requested server port is taken
+var ErrPortInUse = errors.New("port in use")
+
+// ErrTooManyExtensions is returned when a server would get more than server.max_extensions extensions
+var ErrTooManyExtensions = errors.New("too many extensions")
+
+// checkExtensionCount rejects extension lists longer than server.max_extensions before any
+// progress tracking or install work is done for them
+func checkExtensionCount(count int) error {
+	if limit := GetConfig().Server.MaxExtensions; count > limit {
+		return fmt.Errorf("%w: %d requested, at most %d are allowed per server", ErrTooManyExtensions, count, limit)
+	}
+	return nil
+}
+
+// ErrInvalidExtension is returned for extension references that aren't publisher.name[@version] or a .vsix path/URL
+var ErrInvalidExtension = errors.New("invalid extension")
+
+// ErrExtensionNotInstalled is returned when removing an extension that isn't in the server's list
+var ErrExtensionNotInstalled = errors.New("extension not installed")
+
+// ErrArchiveTooLarge is returned when a workspace archive expands beyond ui.workspace.max_upload_size_mb
+var ErrArchiveTooLarge = errors.New("archive too large")
+
+// ErrStateNotSaved is returned when servers.json couldn't be written, so a change won't survive a restart
+var ErrStateNotSaved = errors.New("server state could not be saved")
+
+// Logger receives the ProcessManager's operational messages. *log.Logger satisfies it, and
+// embedders can adapt their own structured logger to it.
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+// ProcessManagerOption customizes a ProcessManager created by NewProcessManager
+type ProcessManagerOption func(*ProcessManager)
+
+// WithStateStore persists servers in store instead of data/servers.json
+func WithStateStore(store StateStore) ProcessManagerOption {
+	return func(pm *ProcessManager) { pm.store = store }
+}
+
+// WithProcessLauncher starts servers on every backend through launcher instead of running their commands
+func WithProcessLauncher(launcher ProcessLauncher) ProcessManagerOption {
+	return func(pm *ProcessManager) {
+		for backend := range pm.launchers {
+			pm.launchers[backend] = launcher
+		}
+	}
+}
+
+// WithBaseDir keeps server data, logs and created workspaces under dir instead of the working directory
+func WithBaseDir(dir string) ProcessManagerOption {
+	return func(pm *ProcessManager) { pm.baseDir = dir }
 }
 
-func NewProcessManager() *ProcessManager {
-	dataDir := "data"
-	os.MkdirAll(dataDir, 0755)
+// NewProcessManager creates a ProcessManager that writes operational messages to logger,
+// or to the standard logger when logger is nil. It fails if the data or logs directory can't
+// be created or (when server.check_writable_dirs is on) written to, since no server state
+// or process logs could be persisted.
+//
+// Tests can pass WithStateStore(NewMemoryStateStore()), WithProcessLauncher(NewFakeProcessLauncher())
+// and WithBaseDir(t.TempDir()) to avoid servers.json, spawning editors and writing to the working
+// directory, and should Close the manager when done.
+func NewProcessManager(logger Logger, opts ...ProcessManagerOption) (*ProcessManager, error) {
+	if logger == nil {
+		logger = log.Default()
+	}
 
 	pm := &ProcessManager{
 		servers:           make(map[string]*ServerInstance),
 		portMap:           make(map[int]string),
-		nextPort:          8500, // Start from 8500 like Python version
-		logger:            NewProcessLogger(),
-		dataDir:           dataDir,
-		serversFile:       filepath.Join(dataDir, "servers.json"),
 		extensionProgress: make(map[string]*ExtensionInstallationProgress),
+		operations:        make(map[string]string),
+		resourceAlerts:    make(map[string]*resourceAlertState),
+		activeConnections: make(map[string]int),
+		processes:         make(map[string]LaunchedProcess),
+		exits:             make(map[string]chan struct{}),
+		cpuSampler:        newCPUSampler(),
+		statusNotifier:    newStatusNotifier(logger),
+		log:               logger,
+		done:              make(chan struct{}),
+	}
+	pm.launchers = map[string]ProcessLauncher{
+		BackendProcess: &execLauncher{log: logger, freePort: pm.killProcessOnPort},
+		BackendDocker:  &dockerLauncher{log: logger},
+	}
+	for _, opt := range opts {
+		opt(pm)
+	}
+
+	pm.dataDir = filepath.Join(pm.baseDir, "data")
+	pm.workspaceDir = filepath.Join(pm.baseDir, "workspace")
+	pm.logger = NewProcessLogger(filepath.Join(pm.baseDir, "logs"))
+	checkWritable := GetConfig().Server.WritableDirCheck()
+	for _, dir := range []string{pm.dataDir, pm.logger.logsDir} {
+		if err := ensureStateDir(dir, checkWritable); err != nil {
+			return nil, err
+		}
+	}
+	if pm.store == nil {
+		pm.store = newFileStateStore(filepath.Join(pm.dataDir, "servers.json"))
 	}
 
 	// Load existing servers from file
 	pm.loadServers()
 
+	// Restart servers that were running when the manager last stopped, before the health
+	// monitor marks their (no longer adopted) processes as stopped
+	if GetConfig().Server.AutostartPreviouslyRunning {
+		ids := pm.runningServerIDs()
+		pm.runRoutine(func() { pm.autostartServers(ids) })
+	}
+
 	// Start single health monitoring routine for all servers
-	go pm.startHealthMonitor()
+	pm.runRoutine(pm.startHealthMonitor)
+
+	// Start separate state refresh and metrics sampling routines
+	pm.runRoutine(pm.startStateRefreshRoutine)
+	pm.runRoutine(pm.startMetricsRoutine)
+	pm.runRoutine(pm.startIdleRoutine)
+
+	return pm, nil
+}
+
+// runRoutine runs a background routine in its own goroutine, which Close waits for
+func (pm *ProcessManager) runRoutine(routine func()) {
+	pm.routines.Add(1)
+	go func() {
+		defer pm.routines.Done()
+		routine()
+	}()
+}
+
+// Close stops the health, state refresh, metrics, idle and autostart routines and waits for
+// them to return. Servers are left running; Cleanup kills them.
+func (pm *ProcessManager) Close() {
+	pm.closeOnce.Do(func() { close(pm.done) })
+	pm.routines.Wait()
+}
+
+// closed reports whether Close has been called
+func (pm *ProcessManager) closed() bool {
+	select {
+	case <-pm.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// ensureStateDir creates dir if needed and, when checkWritable is set, writes and removes a
+// probe file so an unwritable directory fails startup rather than the first save
+func ensureStateDir(dir string, checkWritable bool) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	if !checkWritable {
+		return nil
+	}
 
-	// Start separate state refresh routine
-	go pm.startStateRefreshRoutine()
+	probe, err := os.CreateTemp(dir, ".write-check-*")
+	if err == nil {
+		_, err = probe.WriteString("ok")
+		if closeErr := probe.Close(); err == nil {
+			err = closeErr
+		}
+		if removeErr := os.Remove(probe.Name()); err == nil {
+			err = removeErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", dir, err)
+	}
+	return nil
+}
 
-	return pm
+// SetProxyBaseURL sets the manager's own base URL (e.g. http://localhost:8005), through which
+// end-to-end health checks fetch /vscode/{port}
+func (pm *ProcessManager) SetProxyBaseURL(baseURL string) {
+	pm.proxyBaseURL.Store(strings.TrimSuffix(baseURL, "/"))
 }
 
 func (pm *ProcessManager) SetLogManager(lm *LogManager) {
@@ -113,19 +327,75 @@ func (pm *ProcessManager) SetLogManager(lm *LogManager) {
 	lm.AddSystemLog("INFO", "Process Manager initialized")
 }
 
-func (pm *ProcessManager) getNextAvailablePort() int {
+// getNextAvailablePort reserves the next free port in server.code_server_port_range
+func (pm *ProcessManager) getNextAvailablePort() (int, error) {
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
-	for {
-		if _, exists := pm.portMap[pm.nextPort]; !exists {
-			pm.portMap[pm.nextPort] = "" // Reserve the port
-			port := pm.nextPort
-			pm.nextPort++
-			return port
+	port, err := pm.findFreePort()
+	if err != nil {
+		return 0, err
+	}
+	pm.portMap[port] = "" // Reserve the port
+	return port, nil
+}
+
+// peekNextAvailablePort returns the port getNextAvailablePort would hand out, without reserving it
+func (pm *ProcessManager) peekNextAvailablePort() (int, error) {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+	return pm.findFreePort()
+}
+
+// allocatePort reserves requested, or the next free port in the configured range when it is 0
+func (pm *ProcessManager) allocatePort(requested int) (int, error) {
+	if requested == 0 {
+		return pm.getNextAvailablePort()
+	}
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	if err := pm.checkRequestedPort(requested); err != nil {
+		return 0, err
+	}
+	pm.portMap[requested] = "" // Reserve the port
+	return requested, nil
+}
+
+// checkRequestedPort verifies that a specific port can be given to a new server: it must be in
+// the configured range, not assigned to another server and not already listened on (starting
+// the server would otherwise kill whatever holds it). Callers must hold pm.mutex.
+func (pm *ProcessManager) checkRequestedPort(port int) error {
+	portRange := GetConfig().Server.CodeServerPortRange
+	if !portRange.Contains(port) {
+		return fmt.Errorf("%w: port %d is outside the configured range %d-%d", ErrInvalidPort, port, portRange.Start, portRange.End)
+	}
+	if id, exists := pm.portMap[port]; exists {
+		if id == "" {
+			return fmt.Errorf("%w: port %d is being reserved by another create", ErrPortInUse, port)
+		}
+		return fmt.Errorf("%w: port %d is assigned to server %s", ErrPortInUse, port, id)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("%w: port %d is already in use by another process", ErrPortInUse, port)
+	}
+	listener.Close()
+	return nil
+}
+
+// findFreePort returns the lowest unused port in the configured range, so ports freed by
+// deleted servers are handed out again. Callers must hold pm.mutex.
+func (pm *ProcessManager) findFreePort() (int, error) {
+	portRange := GetConfig().Server.CodeServerPortRange
+	for port := portRange.Start; port <= portRange.End; port++ {
+		if _, exists := pm.portMap[port]; !exists {
+			return port, nil
 		}
-		pm.nextPort++
 	}
+	return 0, fmt.Errorf("%w: all ports in %d-%d are in use", ErrPortRangeExhausted, portRange.Start, portRange.End)
 }
 
 // killProcessOnPort kills any process listening on the specified port
@@ -162,9 +432,9 @@ func (pm *ProcessManager) killProcessOnPort(port int) error {
 		// Kill the process
 		killCmd := exec.Command("kill", "-9", pidLine)
 		if err := killCmd.Run(); err != nil {
-			log.Printf("Failed to kill process %s on port %d: %v", pidLine, port, err)
+			pm.log.Printf("Failed to kill process %s on port %d: %v", pidLine, port, err)
 		} else {
-			log.Printf("Killed existing process %s on port %d", pidLine, port)
+			pm.log.Printf("Killed existing process %s on port %d", pidLine, port)
 			if pm.logManager != nil {
 				pm.logManager.AddSystemLog("INFO", fmt.Sprintf("Killed existing process %s on port %d", pidLine, port))
 			}
@@ -174,14 +444,50 @@ func (pm *ProcessManager) killProcessOnPort(port int) error {
 	return nil
 }
 
-func (pm *ProcessManager) CreateServer(name, workspacePath string, extensions []string, zipFilePath, githubURL string) (*ServerInstance, error) {
-	// Generate unique ID and port (don't lock here since getNextAvailablePort locks internally)
+// CreateServer creates a server, optionally initializing its workspace from source (nil for an empty workspace).
+// A non-empty workspacePath adopts an existing directory as-is; it can't be combined with a source.
+// It may also name a .code-workspace file, which is opened in place of its directory.
+// postInitCommand runs in the workspace afterwards; when empty the configured server.post_init_command is used.
+// A non-zero port is used instead of allocating one, if it is free and within the configured range.
+func (pm *ProcessManager) CreateServer(ctx context.Context, name, workspacePath string, extensions []string, source *WorkspaceSource, postInitCommand string, port int) (*ServerInstance, error) {
+	if err := checkExtensionCount(len(extensions)); err != nil {
+		return nil, err
+	}
+	if err := validateExtensionRefs(extensions); err != nil {
+		return nil, err
+	}
+
+	// Validate an adopted workspace before reserving anything
+	adopted := workspacePath != ""
+	if adopted {
+		if err := validateAdoptedWorkspace(workspacePath); err != nil {
+			return nil, err
+		}
+		if source != nil {
+			return nil, fmt.Errorf("an adopted workspace can't also be initialized from a %s source", source.Type)
+		}
+	}
+
+	workspacePath, workspaceFile := splitWorkspaceFile(workspacePath)
+
+	// Generate unique ID and port (don't lock here since allocatePort locks internally)
 	id := uuid.New().String()
-	port := pm.getNextAvailablePort()
+	port, err := pm.allocatePort(port)
+	if err != nil {
+		return nil, err
+	}
+
+	// Until the server is stored, any failure gives the port back and removes what was created for it
+	stored := false
+	defer func() {
+		if !stored {
+			pm.abandonCreate(id, port, !adopted)
+		}
+	}()
 
 	// Create workspace directory if it doesn't exist (like Python version)
-	if workspacePath == "" || workspacePath == "." {
-		workspacePath = filepath.Join("workspace", id)
+	if workspacePath == "" {
+		workspacePath = filepath.Join(pm.workspaceDir, id)
 	}
 
 	// Convert to absolute path to avoid VS Code creating nested directories
@@ -195,19 +501,11 @@ func (pm *ProcessManager) CreateServer(name, workspacePath string, extensions []
 		return nil, fmt.Errorf("failed to create workspace directory: %v", err)
 	}
 
-	// Initialize workspace from zip file or GitHub repository
-	if zipFilePath != "" {
-		log.Printf("Initializing workspace from zip file: %s", zipFilePath)
-		if err := pm.extractZipFile(zipFilePath, workspacePath); err != nil {
-			return nil, fmt.Errorf("failed to initialize workspace from zip file: %v", err)
+	// Initialize workspace from the requested source (zip file, GitHub repository, ...)
+	if source != nil {
+		if err := pm.initializeWorkspace(ctx, *source, workspacePath); err != nil {
+			return nil, err
 		}
-		log.Printf("Workspace successfully initialized from zip file")
-	} else if githubURL != "" {
-		log.Printf("Initializing workspace from GitHub repository: %s", githubURL)
-		if err := pm.cloneGithubRepo(githubURL, workspacePath); err != nil {
-			return nil, fmt.Errorf("failed to clone GitHub repository: %v", err)
-		}
-		log.Printf("Workspace successfully initialized from GitHub repository")
 	}
 
 	// Create server data directory for extensions and Code-Server settings (like Python version)
@@ -215,24 +513,42 @@ func (pm *ProcessManager) CreateServer(name, workspacePath string, extensions []
 	if err := os.MkdirAll(serverDataDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create server data directory: %v", err)
 	}
-	log.Printf("Created server data directory: %s", serverDataDir)
+	pm.log.Printf("Created server data directory: %s", serverDataDir)
+
+	// Inherit any extensions seeded into the shared base directory
+	baseExtensions := pm.inheritBaseExtensions(id)
 
 	server := &ServerInstance{
-		ID:            id,
-		Name:          name,
-		Port:          port,
-		WorkspacePath: workspacePath,
-		Extensions:    extensions,
-		Status:        StatusStopped, // ONLY creates metadata, doesn't start process
-		StartTime:     nil,
-		PID:           nil,
+		ID:             id,
+		Name:           name,
+		Port:           port,
+		WorkspacePath:  workspacePath,
+		WorkspaceFile:  workspaceFile,
+		OwnedWorkspace: !adopted,
+		Extensions:     extensions,
+		BaseExtensions: baseExtensions,
+		Status:         StatusStopped, // ONLY creates metadata, doesn't start process
+		StartTime:      nil,
+		PID:            nil,
+	}
+
+	// Nothing has run as the server's user yet; from here on its commands (post-init,
+	// extension installs) run as that user in directories it owns
+	if err := pm.handOverServerDirs(server); err != nil {
+		return nil, err
 	}
 
 	// Lock only for the actual storage operations
 	pm.mutex.Lock()
 	pm.servers[id] = server
 	pm.portMap[port] = id
-	pm.saveServers() // Save to disk like Python version
+	if err := pm.saveServers(); err != nil { // Save to disk like Python version
+		// Don't keep a server that would vanish on restart
+		delete(pm.servers, id)
+		pm.mutex.Unlock()
+		return nil, err
+	}
+	stored = true
 	pm.mutex.Unlock()
 
 	// Log creation
@@ -241,66 +557,138 @@ func (pm *ProcessManager) CreateServer(name, workspacePath string, extensions []
 		pm.logManager.AddSystemLog("INFO", fmt.Sprintf("Server %s created on port %d", name, port))
 		pm.logManager.AddServerLog(id, name, "INFO", "server", fmt.Sprintf("Server created on port %d with workspace %s", port, workspacePath))
 	}
+
+	// Run the post-init setup command in the workspace (failures are recorded, the server is kept)
+	if postInitCommand == "" {
+		postInitCommand = GetConfig().Server.PostInitCommand
+	}
+	if postInitCommand != "" {
+		pm.runPostInitCommand(ctx, server, postInitCommand)
+	}
+
 	// Install extensions if provided (blocking operation like Python version)
 	if len(extensions) > 0 {
-		log.Printf("Installing extensions for server %s: %v", id, extensions)
+		pm.log.Printf("Installing extensions for server %s: %v", id, extensions)
 
 		// Set up environment for extension installation (like Python version)
-		env := os.Environ()
+		env := pm.extensionInstallEnv(serverDataDir)
 
-		// Get absolute path for XDG_DATA_HOME (same as server startup)
-		userDataDir := filepath.Join(pm.dataDir, id) // data/{server_id}
-		absDataDir, err := filepath.Abs(userDataDir)
-		if err != nil {
-			log.Printf("Failed to get absolute data dir path: %v", err)
-			absDataDir = userDataDir // Fallback to relative path
-		}
-
-		env = append(env,
-			// fmt.Sprintf("VSCODE_PROXY_URI=./vscode/%d", port),
-			fmt.Sprintf("XDG_DATA_HOME=%s", absDataDir), // data/{server_id}
-		)
-
-		// Install extensions synchronously (blocks API call until complete)
-		extensionSuccess := pm.installExtensions(env, extensions, id, name)
+		// Install synchronously (blocks API call until complete), skipping anything inherited from the base
+		resolved, extensionSuccess := pm.installExtensions(ctx, env, excludeExtensions(extensions, baseExtensions), id, name)
 
 		if extensionSuccess {
-			log.Printf("All extensions installed successfully for server %s", id)
+			pm.log.Printf("All extensions installed successfully for server %s", id)
 		} else {
-			log.Printf("Some extensions failed to install for server %s", id)
+			pm.log.Printf("Some extensions failed to install for server %s", id)
 			// Continue anyway, don't fail server creation
 		}
 
+		// Record the resolved versions of what was installed
+		pm.mutex.Lock()
+		server.Extensions = mergeResolvedExtensions(extensions, resolved)
+		pm.saveServers()
+		pm.mutex.Unlock()
+
+		// The server record is kept so the remaining extensions can be installed later
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("extension installation for server %s interrupted: %w", id, err)
+		}
+
 		// Apply user settings after extension installation
 		if err := pm.applyUserSettings(id, extensions); err != nil {
-			log.Printf("Failed to apply user settings for server %s: %v", id, err)
+			pm.log.Printf("Failed to apply user settings for server %s: %v", id, err)
 			// Continue anyway, don't fail server creation
 		}
 	}
 
-	log.Printf("Created server %s (%s) on port %d", name, id, port)
+	pm.log.Printf("Created server %s (%s) on port %d", name, id, port)
 	return server, nil
 }
 
-func (pm *ProcessManager) StartServer(id string) error {
+// abandonCreate undoes a create that failed before its server was stored: the port reserved for
+// it is released and its data directory removed, along with its workspace when ownsWorkspace
+// (the manager created it under workspace/ rather than adopting a directory)
+func (pm *ProcessManager) abandonCreate(id string, port int, ownsWorkspace bool) {
 	pm.mutex.Lock()
-	defer pm.mutex.Unlock()
+	if owner, exists := pm.portMap[port]; exists && (owner == "" || owner == id) {
+		delete(pm.portMap, port)
+	}
+	pm.mutex.Unlock()
+
+	dirs := []string{filepath.Join(pm.dataDir, id)}
+	if ownsWorkspace {
+		dirs = append(dirs, filepath.Join(pm.workspaceDir, id))
+	}
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			pm.log.Printf("Failed to remove %s after a failed create: %v", dir, err)
+		}
+	}
+}
+
+// beginOperation marks a lifecycle operation as in progress for a server so that
+// start/stop/restart requests for the same server can't overlap
+func (pm *ProcessManager) beginOperation(id, operation string) error {
+	pm.operationsMutex.Lock()
+	defer pm.operationsMutex.Unlock()
+
+	if current, busy := pm.operations[id]; busy {
+		return fmt.Errorf("%w: %s already in progress for server %s", ErrServerBusy, current, id)
+	}
+	pm.operations[id] = operation
+	return nil
+}
+
+// endOperation clears the in-progress lifecycle operation for a server
+func (pm *ProcessManager) endOperation(id string) {
+	pm.operationsMutex.Lock()
+	defer pm.operationsMutex.Unlock()
+	delete(pm.operations, id)
+}
+
+func (pm *ProcessManager) StartServer(id string) error {
+	if err := pm.beginOperation(id, "start"); err != nil {
+		return err
+	}
+	defer pm.endOperation(id)
+
+	pm.cancelAutoRestart(id)
+
+	return pm.startServer(id)
+}
 
+// startServer launches the server's runtime (code-server by default); callers must hold the server's operation.
+// The global lock is only held to read the server and to commit the new state; directory
+// creation and process launch happen without it so other API calls aren't blocked.
+func (pm *ProcessManager) startServer(id string) error {
+	return pm.startServerAttempt(id, 0)
+}
+
+// startServerAttempt is startServer for the given relaunch of a server whose earlier launches
+// exited because its port was still held (0 for a first launch)
+func (pm *ProcessManager) startServerAttempt(id string, bindAttempt int) error {
+	pm.mutex.RLock()
 	server, exists := pm.servers[id]
 	if !exists {
+		pm.mutex.RUnlock()
 		return fmt.Errorf("server not found: %s", id)
 	}
 
 	if server.Status == StatusRunning {
+		pm.mutex.RUnlock()
 		return fmt.Errorf("server is already running")
 	}
 
-	// Kill any existing process on the port before starting
-	if err := pm.killProcessOnPort(server.Port); err != nil {
-		log.Printf("Warning: Failed to kill existing process on port %d: %v", server.Port, err)
-		// Continue anyway - the port might just be free
+	if err := pm.checkMemoryBudget(); err != nil {
+		pm.mutex.RUnlock()
+		pm.logger.LogProcessEvent(id, server.Name, "START_REFUSED", err.Error())
+		return err
 	}
 
+	// Stage a copy of the server so the slow work below doesn't need the lock
+	staged := *server
+	pm.mutex.RUnlock()
+
 	// Create user data directory and config directory (like Python version)
 	userDataDir := filepath.Join(pm.dataDir, id)
 	configDir := filepath.Join(userDataDir, "code-server") // Like Python: data/{server_id}/code-server
@@ -308,159 +696,326 @@ func (pm *ProcessManager) StartServer(id string) error {
 		return fmt.Errorf("failed to create config directory: %v", err)
 	}
 
+	// The hook and the process run as the server's user, which must own its directories first
+	if err := pm.handOverServerDirs(&staged); err != nil {
+		pm.logger.LogProcessEvent(id, staged.Name, "START_FAILED", err.Error())
+		return err
+	}
+
+	// A failing pre-start hook aborts the start
+	if err := pm.runHook(&staged, "pre_start", resolveHooks(&staged).PreStart); err != nil {
+		pm.logger.LogProcessEvent(id, staged.Name, "START_FAILED", err.Error())
+		return err
+	}
+
 	// Get absolute path for config directory
 	absConfigDir, err := filepath.Abs(configDir)
 	if err != nil {
-		log.Printf("Failed to get absolute config dir path: %v", err)
+		pm.log.Printf("Failed to get absolute config dir path: %v", err)
 		absConfigDir = configDir // Fallback to relative path
 	}
 
-	// Build code-server command with all stability options
-	args := []string{
-		"--bind-addr", fmt.Sprintf("0.0.0.0:%d", server.Port),
-		"--user-data-dir", absConfigDir, // Use absolute config dir like Python version
-		"--auth", "none",
-		"--disable-telemetry",
-		"--disable-update-check",
-		"--disable-file-downloads",
-		"--log", "info",
-		server.WorkspacePath,
+	// Build the runtime's command (code-server with all stability options by default)
+	runtime, err := GetRuntime(staged.Runtime)
+	if err != nil {
+		pm.markServerStopped(id)
+		return err
+	}
+	args, runtimeEnv := runtime.BuildCommand(runtimeLaunch{
+		Port:      staged.Port,
+		Workspace: staged.WorkspacePath,
+		Target:    pm.workspaceTarget(&staged),
+		DataDir:   filepath.Dir(absConfigDir),
+		ConfigDir: absConfigDir,
+	})
+	if isCodeServerRuntime(staged.Runtime) {
+		pm.checkOpenPath(&staged)
+		args = append(args, resolveEditorFlags(&staged).Args()...)
 	}
+	args = append(args, staged.ExtraArgs...)
 
-	cmd := exec.Command("code-server", args...)
-	cmd.Dir = server.WorkspacePath
+	cmd := exec.Command(runtime.Binary, args...)
+	cmd.Dir = staged.WorkspacePath
 
 	// Set comprehensive environment variables (like Python version)
-	env := os.Environ()
-
-	// Get absolute path for XDG_DATA_HOME (parent of config dir)
-	absDataDir, err := filepath.Abs(userDataDir) // data/{server_id}
-	if err != nil {
-		log.Printf("Failed to get absolute data dir path: %v", err)
-		absDataDir = userDataDir // Fallback to relative path
-	}
-
-	env = append(env,
-		// fmt.Sprintf("VSCODE_PROXY_URI=./vscode/%d", server.Port),
-		fmt.Sprintf("XDG_DATA_HOME=%s", absDataDir),                                 // Match Python: absolute path to data/{server_id}
-		fmt.Sprintf("CODEX_HOME=%s", filepath.Join(server.WorkspacePath, ".codex")), // Absolute path to workspace/.codex directory
-		"NODE_OPTIONS=--max-old-space-size=2048",
-		"VSCODE_LOGS=info",
-		"CODE_SERVER_LOG=info",
-		"UV_THREADPOOL_SIZE=128",
-		"NODE_TLS_REJECT_UNAUTHORIZED=0",
-		"VSCODE_DISABLE_CRASH_REPORTER=true",
-		"ELECTRON_NO_ATTACH_CONSOLE=1",
-		"DISABLE_TELEMETRY=true",
-	)
-	cmd.Env = env
+	// XDG_DATA_HOME matches Python: absolute path to data/{server_id}
+	inherited := os.Environ()
+	launchEnv := append([]string{xdgDataHomeEnv(userDataDir)}, runtimeEnv...)
+	cmd.Env = withMarketplace(withCABundle(append(slices.Clip(inherited), launchEnv...)))
+	launchEnv = addedEnv(inherited, cmd.Env) // What the manager set, for server.Env
+	if err := pm.applyRunAsUser(&staged, cmd); err != nil {
+		pm.markServerStopped(id)
+		pm.logger.LogProcessEvent(id, staged.Name, "START_FAILED", err.Error())
+		return err
+	}
 
 	// Log process start
-	pm.logger.LogProcessEvent(id, server.Name, "STARTING", fmt.Sprintf("Starting on port %d", server.Port))
+	pm.logger.LogProcessEvent(id, staged.Name, "STARTING", fmt.Sprintf("Starting on port %d", staged.Port))
 	if pm.logManager != nil {
-		pm.logManager.AddServerLog(id, server.Name, "INFO", "server", fmt.Sprintf("Starting code-server on port %d", server.Port))
+		pm.logManager.AddServerLog(id, staged.Name, "INFO", "server", fmt.Sprintf("Starting %s on port %d", runtime.Name, staged.Port))
 	}
 
-	// Get stdout and stderr pipes for logging
-	stdout, err := cmd.StdoutPipe()
+	// Start the process, freeing the port from any leftover process first
+	launcher, err := pm.launcherFor(staged.backend())
 	if err != nil {
-		server.Status = StatusStopped
-		return fmt.Errorf("failed to get stdout pipe: %v", err)
+		pm.markServerStopped(id)
+		return err
 	}
-
-	stderr, err := cmd.StderrPipe()
+	proc, exited, bind, err := pm.launchServer(launcher, cmd, &staged, bindAttempt)
 	if err != nil {
-		server.Status = StatusStopped
-		return fmt.Errorf("failed to get stderr pipe: %v", err)
+		pm.logger.LogProcessEvent(id, staged.Name, "START_FAILED", err.Error())
+		pm.markServerStopped(id)
+		return fmt.Errorf("failed to start %s: %v", runtime.Name, err)
 	}
 
-	// Start the process
-	if err := cmd.Start(); err != nil {
-		server.Status = StatusStopped
-		pm.logger.LogProcessEvent(id, server.Name, "START_FAILED", err.Error())
-		return fmt.Errorf("failed to start code-server: %v", err)
+	// Commit the staged state atomically, re-reading the server since the map may have been
+	// refreshed from file while the lock was released
+	pm.mutex.Lock()
+	server, exists = pm.servers[id]
+	if !exists {
+		pm.mutex.Unlock()
+		proc.Signal(os.Kill)
+		return fmt.Errorf("server %s was removed while starting", id)
 	}
 
-	// Update server state
 	now := time.Now()
-	server.PID = &cmd.Process.Pid
+	pid := proc.Pid()
+	pm.processes[id] = proc
+	pm.exits[id] = make(chan struct{})
+	server.PID = &pid
 	server.StartTime = &now
-	server.Status = StatusRunning
-	server.Command = append([]string{"code-server"}, args...)
+	pm.setStatus(server, StatusRunning)
+	server.Command = append([]string{runtime.Binary}, args...)
+	server.Env = redactEnv(launchEnv)
+	limits := server.ResourceLimits
 
 	// IMPORTANT: Save to file BEFORE unlocking to prevent race with refreshStateFromFile
-	pm.saveServers()
+	saveErr := pm.saveServers()
+	pm.mutex.Unlock()
 
-	// Start output capture with LogManager integration for real-time WebSocket streaming
-	outputCapture := NewEnhancedProcessOutputCapture(pm.logger, pm.logManager, id, server.Name)
-	go outputCapture.CaptureOutput(stdout, stderr)
+	pm.applyResourceLimits(id, staged.Name, pid, limits)
 
-	pm.logger.LogProcessEvent(id, server.Name, "STARTED", fmt.Sprintf("Process started with PID %d on port %d", *server.PID, server.Port))
-	log.Printf("Started server %s (PID: %d) on port %d", server.Name, *server.PID, server.Port)
+	pm.logger.LogProcessEvent(id, staged.Name, "STARTED", fmt.Sprintf("Process started with PID %d on port %d", pid, staged.Port))
+	pm.log.Printf("Started server %s (PID: %d) on port %d", staged.Name, pid, staged.Port)
 	if pm.logManager != nil {
-		pm.logManager.AddServerLog(id, server.Name, "INFO", "server", fmt.Sprintf("code-server started with PID %d on port %d", *server.PID, server.Port))
+		pm.logManager.AddServerLog(id, staged.Name, "INFO", "server", fmt.Sprintf("%s started with PID %d on port %d", runtime.Name, pid, staged.Port))
 	}
 
 	// Monitor process in background (process lifecycle)
-	go pm.monitorProcess(id, cmd)
+	go pm.monitorProcess(id, proc, exited, bind)
 
+	// The process keeps running, but callers need to know it won't be recorded as running after a restart
+	if saveErr != nil {
+		return fmt.Errorf("server %s started but %w", id, saveErr)
+	}
 	return nil
 }
 
-func (pm *ProcessManager) StopServer(id string) error {
+// checkMemoryBudget refuses a new start when the memory used by running servers plus an
+// estimate for one more would exceed the configured budget. Callers must hold pm.mutex.
+func (pm *ProcessManager) checkMemoryBudget() error {
+	serverConfig := GetConfig().Server
+	if serverConfig.MemoryBudgetMB <= 0 {
+		return nil
+	}
+
+	usedMB := 0.0
+	measured := 0
+	for _, server := range pm.servers {
+		if server.Status == StatusRunning && server.MemoryMB != nil {
+			usedMB += *server.MemoryMB
+			measured++
+		}
+	}
+
+	// Estimate the new server from the average of running ones, falling back to the configured estimate
+	estimateMB := serverConfig.EstimatedServerMemoryMB
+	if measured > 0 {
+		estimateMB = usedMB / float64(measured)
+	}
+
+	if usedMB+estimateMB > serverConfig.MemoryBudgetMB {
+		return fmt.Errorf("%w: %.0fMB in use, starting another server (~%.0fMB) would exceed the %.0fMB budget",
+			ErrMemoryBudgetExceeded, usedMB, estimateMB, serverConfig.MemoryBudgetMB)
+	}
+	return nil
+}
+
+// markServerStopped records that a server is not running after a failed start
+func (pm *ProcessManager) markServerStopped(id string) {
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
+	if server, exists := pm.servers[id]; exists {
+		pm.setStatus(server, StatusStopped)
+	}
+}
+
+func (pm *ProcessManager) StopServer(id string) error {
+	if err := pm.beginOperation(id, "stop"); err != nil {
+		return err
+	}
+	defer pm.endOperation(id)
+
+	pm.cancelAutoRestart(id)
+
+	return pm.stopServer(id)
+}
+
+// stopGracePeriod is how long a stopping server gets to exit after SIGTERM before it is killed
+const stopGracePeriod = 10 * time.Second
+
+// stopKillWait bounds the wait for a killed server's process to go away
+const stopKillWait = 5 * time.Second
+
+// stopServer terminates a server's process; callers must hold the server's operation. It returns
+// once the process has exited or been killed, so the operation isn't released while the old
+// process still holds the port and data directory a following start would use.
+func (pm *ProcessManager) stopServer(id string) error {
+	pm.mutex.Lock()
+
 	server, exists := pm.servers[id]
 	if !exists {
+		pm.mutex.Unlock()
 		return fmt.Errorf("server not found: %s", id)
 	}
 
 	if server.Status != StatusRunning || server.PID == nil {
+		pm.mutex.Unlock()
 		return fmt.Errorf("server is not running")
 	}
 
 	// Try graceful shutdown first
-	if proc, err := os.FindProcess(*server.PID); err == nil {
-		if err := proc.Signal(syscall.SIGTERM); err == nil {
-			// Wait up to 10 seconds for graceful shutdown
-			go func() {
-				time.Sleep(10 * time.Second)
-				pm.mutex.Lock()
-				defer pm.mutex.Unlock()
-				// Force kill if process still exists
-				if server.PID != nil {
-					if p, err := os.FindProcess(*server.PID); err == nil {
-						p.Kill()
-					}
-				}
-				server.Status = StatusStopped
-				server.PID = nil
-				server.StartTime = nil
-				pm.saveServers()
-			}()
-		} else {
+	pid := *server.PID
+	exited := pm.exits[id]
+	if pm.launchedProcess(id, pid) == nil {
+		exited = nil // Still running from before a restart; its exit is polled for instead
+	}
+	proc, procErr := pm.serverProcess(id, pid)
+	if procErr == nil {
+		if err := proc.Signal(syscall.SIGTERM); err != nil {
 			// Force kill immediately if SIGTERM fails
-			proc.Kill()
+			proc.Signal(os.Kill)
 		}
 	}
 
 	// Immediately set to stopped
-	server.Status = StatusStopped
+	pm.setStatus(server, StatusStopped)
 	server.PID = nil
 	server.StartTime = nil
 
 	// Save updated state to file
 	pm.saveServers()
+	name := server.Name
+	pm.mutex.Unlock()
+
+	if procErr == nil && !waitForExit(exited, pid, stopGracePeriod) {
+		pm.log.Printf("Server %s did not exit within %s of SIGTERM, killing it", name, stopGracePeriod)
+		proc.Signal(os.Kill)
+		if !waitForExit(exited, pid, stopKillWait) {
+			pm.log.Printf("Server %s (PID: %d) has not exited %s after being killed", name, pid, stopKillWait)
+		}
+	}
 
-	log.Printf("Stopped server %s", server.Name)
+	pm.log.Printf("Stopped server %s", name)
 	if pm.logManager != nil {
-		pm.logManager.AddServerLog(id, server.Name, "INFO", "server", "Server stopped")
+		pm.logManager.AddServerLog(id, name, "INFO", "server", "Server stopped")
+	}
+	return nil
+}
+
+// waitForExit waits up to timeout for a server process to exit, reporting whether it did.
+// exited is closed by monitorProcess for processes this manager launched; without it (a process
+// still running from before a restart) the OS process is polled.
+func waitForExit(exited <-chan struct{}, pid int, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	if exited != nil {
+		select {
+		case <-exited:
+			return true
+		case <-timer.C:
+			return false
+		}
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if processGone(osProcessMetrics(pid)) {
+			return true
+		}
+		select {
+		case <-ticker.C:
+		case <-timer.C:
+			return false
+		}
+	}
+}
+
+// launchedProcess returns the handle of the process this manager launched as pid for a server,
+// or nil for a server still running from before a restart. Requires pm.mutex to be held.
+func (pm *ProcessManager) launchedProcess(id string, pid int) LaunchedProcess {
+	if proc, exists := pm.processes[id]; exists && proc.Pid() == pid {
+		return proc
 	}
 	return nil
 }
 
-func (pm *ProcessManager) DeleteServer(id string) error {
+// sampleProcess samples a server process through its launcher handle, falling back to the OS
+// for servers adopted from a previous run (proc nil)
+func sampleProcess(proc LaunchedProcess, pid int) (ProcessMetrics, error) {
+	if proc != nil {
+		return proc.Metrics()
+	}
+	return osProcessMetrics(pid)
+}
+
+// serverProcess returns the process running as pid for a server: the one this manager launched,
+// or the OS process for a server still running from before a restart. Requires pm.mutex to be held.
+func (pm *ProcessManager) serverProcess(id string, pid int) (processSignaler, error) {
+	if proc := pm.launchedProcess(id, pid); proc != nil {
+		return proc, nil
+	}
+	return os.FindProcess(pid)
+}
+
+// ownsWorkspace reports whether the manager created the server's workspace and may delete it.
+// Servers saved before OwnedWorkspace existed count as owned if their workspace is under workspace/.
+func (pm *ProcessManager) ownsWorkspace(server *ServerInstance) bool {
+	if server.OwnedWorkspace {
+		return true
+	}
+	root, err := filepath.Abs(pm.workspaceDir)
+	if err != nil {
+		return false
+	}
+	return server.WorkspacePath == filepath.Join(root, server.ID)
+}
+
+// DeleteOptions selects what DeleteServer leaves on disk after removing a server from management
+type DeleteOptions struct {
+	KeepLogs      bool `json:"keep_logs"`
+	KeepData      bool `json:"keep_data"`
+	KeepWorkspace bool `json:"keep_workspace"`
+}
+
+// DefaultDeleteOptions returns the retention configured under retention in devbox.yaml
+func DefaultDeleteOptions() DeleteOptions {
+	retention := GetConfig().Retention
+	return DeleteOptions{
+		KeepLogs:      retention.KeepLogs,
+		KeepData:      retention.KeepData,
+		KeepWorkspace: retention.KeepWorkspace,
+	}
+}
+
+func (pm *ProcessManager) DeleteServer(id string, opts DeleteOptions) error {
+	if err := pm.beginOperation(id, "delete"); err != nil {
+		return err
+	}
+	defer pm.endOperation(id)
+
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
@@ -474,37 +1029,45 @@ func (pm *ProcessManager) DeleteServer(id string) error {
 
 	// Stop server if running
 	if server.Status == StatusRunning && server.PID != nil {
-		if proc, err := os.FindProcess(*server.PID); err == nil {
-			proc.Kill()
+		if proc, err := pm.serverProcess(id, *server.PID); err == nil {
+			proc.Signal(os.Kill)
 		}
 	}
 
 	// Clean up data directory (includes config subdirectory)
 	dataDir := filepath.Join(pm.dataDir, id)
-	if _, err := os.Stat(dataDir); err == nil {
+	if opts.KeepData {
+		pm.log.Printf("Keeping data directory: %s", dataDir)
+	} else if _, err := os.Stat(dataDir); err == nil {
 		if err := os.RemoveAll(dataDir); err != nil {
-			log.Printf("Failed to remove data directory %s: %v", dataDir, err)
+			pm.log.Printf("Failed to remove data directory %s: %v", dataDir, err)
 		} else {
-			log.Printf("Removed data directory: %s", dataDir)
+			pm.log.Printf("Removed data directory: %s", dataDir)
 		}
 	}
 
-	// Clean up workspace directory
-	if _, err := os.Stat(server.WorkspacePath); err == nil {
+	// Clean up workspace directory, leaving adopted external directories alone
+	if !pm.ownsWorkspace(server) {
+		pm.log.Printf("Keeping adopted workspace directory: %s", server.WorkspacePath)
+	} else if opts.KeepWorkspace {
+		pm.log.Printf("Keeping workspace directory: %s", server.WorkspacePath)
+	} else if _, err := os.Stat(server.WorkspacePath); err == nil {
 		if err := os.RemoveAll(server.WorkspacePath); err != nil {
-			log.Printf("Failed to remove workspace directory %s: %v", server.WorkspacePath, err)
+			pm.log.Printf("Failed to remove workspace directory %s: %v", server.WorkspacePath, err)
 		} else {
-			log.Printf("Removed workspace directory: %s", server.WorkspacePath)
+			pm.log.Printf("Removed workspace directory: %s", server.WorkspacePath)
 		}
 	}
 
 	// Clean up logs directory
-	logsDir := filepath.Join("logs", id)
-	if _, err := os.Stat(logsDir); err == nil {
+	logsDir := filepath.Join(pm.logger.logsDir, id)
+	if opts.KeepLogs {
+		pm.log.Printf("Keeping logs directory: %s", logsDir)
+	} else if _, err := os.Stat(logsDir); err == nil {
 		if err := os.RemoveAll(logsDir); err != nil {
-			log.Printf("Failed to remove logs directory %s: %v", logsDir, err)
+			pm.log.Printf("Failed to remove logs directory %s: %v", logsDir, err)
 		} else {
-			log.Printf("Removed logs directory: %s", logsDir)
+			pm.log.Printf("Removed logs directory: %s", logsDir)
 		}
 	}
 
@@ -515,12 +1078,19 @@ func (pm *ProcessManager) DeleteServer(id string) error {
 	// Save updated state to file (critical!)
 	pm.saveServers()
 
-	// Final log entry
-	pm.logger.LogProcessEvent(id, server.Name, "DELETED", "Server deleted successfully")
+	// Final log entry, only when the logs survive (writing it would recreate a removed logs directory)
+	if opts.KeepLogs {
+		pm.logger.LogProcessEvent(id, server.Name, "DELETED", fmt.Sprintf("Server deleted successfully (keep_data=%t, keep_workspace=%t)", opts.KeepData, opts.KeepWorkspace))
+	}
 
-	log.Printf("Deleted server %s and cleaned up all directories", server.Name)
+	message := "Server deleted and all directories cleaned up"
+	if opts != (DeleteOptions{}) {
+		message = fmt.Sprintf("Server deleted (kept logs=%t, data=%t, workspace=%t)", opts.KeepLogs, opts.KeepData, opts.KeepWorkspace)
+	}
+	pm.log.Printf("Deleted server %s: %s", server.Name, message)
 	if pm.logManager != nil {
-		pm.logManager.AddServerLog(id, server.Name, "INFO", "server", "Server deleted and all directories cleaned up")
+		pm.logManager.AddServerLog(id, server.Name, "INFO", "server", message)
+		pm.logManager.CloseServerClients(id)
 	}
 	return nil
 }
@@ -566,18 +1136,48 @@ func (pm *ProcessManager) GetServerByPort(port int) (*ServerInstance, error) {
 	return server, nil
 }
 
-func (pm *ProcessManager) monitorProcess(id string, cmd *exec.Cmd) {
+// monitorProcess records the exit of a server's process, relaunching it if it couldn't bind its
+// port (see bindWatch) or its restart policy asks for it
+func (pm *ProcessManager) monitorProcess(id string, proc LaunchedProcess, exitErr <-chan error, bind *bindWatch) {
 	// Wait for process to finish
-	err := cmd.Wait()
+	err := <-exitErr
+	failedToBind := bind.failedToBind()
+	removeCgroup(id)
 
 	pm.mutex.Lock()
-	defer pm.mutex.Unlock()
-
+	// Tell a stop waiting on the process that it is gone, once its state has been recorded below
+	var exited chan struct{}
+	if pm.processes[id] == proc {
+		delete(pm.processes, id)
+		exited = pm.exits[id]
+		delete(pm.exits, id)
+	}
+	notifyExited := func() {
+		if exited != nil {
+			close(exited)
+		}
+	}
 	server, exists := pm.servers[id]
 	if !exists {
+		pm.mutex.Unlock()
+		notifyExited()
+		return
+	}
+
+	// A newer process has been started since this one was launched; its own monitor reports on it
+	if server.PID != nil && *server.PID != proc.Pid() {
+		pm.mutex.Unlock()
+		notifyExited()
 		return
 	}
 
+	// stopServer clears the PID, so a PID still set means the process exited on its own
+	unexpected := server.PID != nil
+	ranFor := time.Duration(0)
+	if server.StartTime != nil {
+		ranFor = time.Since(*server.StartTime)
+	}
+
 	// Get PID before clearing it, in case it was already cleared by stop
 	var pidStr string
 	if server.PID != nil {
@@ -587,37 +1187,84 @@ func (pm *ProcessManager) monitorProcess(id string, cmd *exec.Cmd) {
 	}
 
 	if err != nil {
-		log.Printf("Server %s (%s) exited with error: %v", server.Name, pidStr, err)
+		pm.log.Printf("Server %s (%s) exited with error: %v", server.Name, pidStr, err)
 		pm.logger.LogProcessEvent(id, server.Name, "PROCESS_EXITED_ERROR", err.Error())
 		if pm.logManager != nil {
 			pm.logManager.AddServerLog(id, server.Name, "ERROR", "server", fmt.Sprintf("Server process exited with error: %v", err))
 		}
-		server.Status = StatusStopped
+		pm.setStatus(server, StatusStopped)
 	} else {
-		log.Printf("Server %s (%s) exited normally", server.Name, pidStr)
+		pm.log.Printf("Server %s (%s) exited normally", server.Name, pidStr)
 		pm.logger.LogProcessEvent(id, server.Name, "PROCESS_EXITED", "Process exited normally")
 		if pm.logManager != nil {
 			pm.logManager.AddServerLog(id, server.Name, "INFO", "server", "Server process exited normally")
 		}
-		server.Status = StatusStopped
+		pm.setStatus(server, StatusStopped)
 	}
 
 	server.PID = nil
 	server.StartTime = nil
 
-	// Save updated state
-	pm.saveServers()
-}
+	// A server that exits right after launching because its port is still held is launched again,
+	// up to server.bind_retries times
+	bindRetry := 0
+	if unexpected && failedToBind {
+		if retries := GetConfig().Server.BindRetryCount(); bind.attempt < retries {
+			bindRetry = bind.attempt + 1
+			message := fmt.Sprintf("Port %d is still in use (attempt %d of %d exited: %v), retrying in %s",
+				server.Port, bindRetry, retries+1, err, bindRetryDelay)
+			pm.log.Printf("Server %s: %s", server.Name, message)
+			pm.logger.LogProcessEvent(id, server.Name, "BIND_RETRY", message)
+			if pm.logManager != nil {
+				pm.logManager.AddServerLog(id, server.Name, "WARN", "server", message)
+			}
+		} else {
+			message := fmt.Sprintf("%v: port %d is still held by another process after %d attempts", ErrPortInUse, server.Port, bind.attempt+1)
+			pm.logger.LogProcessEvent(id, server.Name, "START_FAILED", message)
+			if pm.logManager != nil {
+				pm.logManager.AddServerLog(id, server.Name, "ERROR", "server", message)
+			}
+			pm.setStatus(server, StatusFailed)
+		}
+	}
+
+	// Supervise servers that opted into a restart policy
+	restartAttempt := 0
+	if bindRetry == 0 && unexpected && server.restartPolicy().restartsAfter(err) {
+		if ranFor >= restartStableAfter {
+			server.RestartCount = 0
+		}
+		if attempt, ok := pm.nextRestartAttempt(server); ok {
+			restartAttempt = attempt
+		}
+	}
+
+	// Save updated state
+	pm.saveServers()
+	stopped := *server
+	pm.mutex.Unlock()
+	notifyExited()
+
+	// Run the post-stop hook once the process is gone; failures are only reported
+	pm.runHook(&stopped, "post_stop", resolveHooks(&stopped).PostStop)
+
+	if bindRetry > 0 {
+		go pm.retryBind(id, bindRetry)
+	}
+	if restartAttempt > 0 {
+		go pm.superviseRestart(id, restartAttempt)
+	}
+}
 
 func (pm *ProcessManager) Cleanup() {
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
-	log.Println("Cleaning up all running servers...")
-	for _, server := range pm.servers {
+	pm.log.Printf("Cleaning up all running servers...")
+	for id, server := range pm.servers {
 		if server.Status == StatusRunning && server.PID != nil {
-			if proc, err := os.FindProcess(*server.PID); err == nil {
-				proc.Kill()
+			if proc, err := pm.serverProcess(id, *server.PID); err == nil {
+				proc.Signal(os.Kill)
 			}
 		}
 	}
@@ -638,33 +1285,54 @@ func (pm *ProcessManager) GetServerHealth(id string) (map[string]interface{}, er
 
 	// If server is running, get detailed health info
 	if server.Status == StatusRunning && server.PID != nil && server.StartTime != nil {
-		port := server.Port
+		probe := newHealthProbe(server)
 		pid := *server.PID
 		startTime := *server.StartTime
+		cpuPercent := server.CPUPercent
+		proc := pm.launchedProcess(id, pid)
 		pm.mutex.RUnlock()
 
 		// Check HTTP health
-		isHealthy := pm.isServerHealthy(port)
+		isHealthy := pm.probeHealth(probe)
 		health["http_healthy"] = isHealthy
 
 		// Get process stats
-		if proc, err := process.NewProcess(int32(pid)); err == nil {
-			if cpuPercent, err := proc.CPUPercent(); err == nil {
-				health["cpu_percent"] = cpuPercent
-			}
-
-			if memInfo, err := proc.MemoryInfo(); err == nil {
-				health["memory_mb"] = float64(memInfo.RSS) / 1024 / 1024
+		metrics, err := sampleProcess(proc, pid)
+		switch {
+		case err == nil && metrics.Running:
+			// Report the sampled value; a one-off CPUPercent call would be the lifetime average
+			if cpuPercent != nil {
+				health["cpu_percent"] = *cpuPercent
+			} else {
+				health["cpu_percent"] = 0
 			}
+			health["memory_mb"] = metrics.MemoryMB
 
 			// Calculate uptime
 			health["uptime_seconds"] = time.Since(startTime).Seconds()
-		} else {
-			// Process doesn't exist anymore
+		case processGone(metrics, err):
+			// Process doesn't exist anymore: mark the server stopped like the metrics routine
+			// does, unless it was restarted in the meantime
+			pm.mutex.Lock()
+			if server, exists := pm.servers[id]; exists && server.Status == StatusRunning && server.PID != nil && *server.PID == pid {
+				pm.markProcessGone(server, "Process no longer exists - marking as stopped")
+				pm.saveServers()
+			}
+			pm.mutex.Unlock()
+			health["status"] = StatusStopped
+			health["http_healthy"] = false
 			health["cpu_percent"] = 0
 			health["memory_mb"] = 0
 			health["uptime_seconds"] = 0
 			health["process_exists"] = false
+		default:
+			// The process exists but can't be inspected (e.g. it runs as another user), so only
+			// the HTTP check says anything about it
+			health["cpu_percent"] = 0
+			health["memory_mb"] = 0
+			health["uptime_seconds"] = time.Since(startTime).Seconds()
+			health["metrics_available"] = false
+			health["metrics_error"] = err.Error()
 		}
 	} else {
 		pm.mutex.RUnlock()
@@ -697,108 +1365,598 @@ func (pm *ProcessManager) loadServers() {
 	// Log existing running servers (only on startup)
 	for _, server := range pm.servers {
 		if server.Status == StatusRunning && server.PID != nil {
-			log.Printf("Found existing running server %s (PID: %d)", server.Name, *server.PID)
+			pm.log.Printf("Found existing running server %s (PID: %d)", server.Name, *server.PID)
 		}
 	}
 }
 
-func (pm *ProcessManager) loadServersFromFile() {
-	// This reloads from file without starting monitoring (used for refreshing state)
-	data, err := os.ReadFile(pm.serversFile)
-	if err != nil {
-		log.Printf("Error reading servers file: %v", err)
+// runningServerIDs returns the IDs of servers whose recorded status is running, sorted by name
+func (pm *ProcessManager) runningServerIDs() []string {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	var running []*ServerInstance
+	for _, server := range pm.servers {
+		if server.Status == StatusRunning {
+			running = append(running, server)
+		}
+	}
+	sort.Slice(running, func(i, j int) bool { return running[i].Name < running[j].Name })
+
+	ids := make([]string, 0, len(running))
+	for _, server := range running {
+		ids = append(ids, server.ID)
+	}
+	return ids
+}
+
+// autostartServers starts the given servers one at a time (so the memory budget sees each
+// start), first clearing the running status persisted by the previous manager process
+func (pm *ProcessManager) autostartServers(ids []string) {
+	if len(ids) == 0 {
 		return
 	}
 
-	var servers map[string]*ServerInstance
-	if err := json.Unmarshal(data, &servers); err != nil {
-		log.Printf("Error parsing servers file: %v", err)
+	pm.log.Printf("Auto-starting %d servers that were running before restart", len(ids))
+	for _, id := range ids {
+		if pm.closed() {
+			return
+		}
+		pm.markServerStopped(id)
+		if err := pm.StartServer(id); err != nil {
+			pm.log.Printf("Failed to auto-start server %s: %v", id, err)
+			continue
+		}
+		pm.log.Printf("Auto-started server %s", id)
+	}
+}
+
+func (pm *ProcessManager) loadServersFromFile() {
+	// This reloads from file without starting monitoring (used for refreshing state)
+	servers, err := pm.store.Load()
+	if err != nil {
+		pm.log.Printf("Error reading servers file: %v", err)
 		return
 	}
 
 	// Clear existing state and rebuild from file
 	pm.servers = servers
-	pm.portMap = make(map[int]string)
+	// Rebuild port map, fixing servers a hand-edited file left sharing a port
+	if pm.rebuildPortMap() {
+		pm.saveServers()
+	}
+}
 
-	// Rebuild port map
-	for id, server := range servers {
-		pm.portMap[server.Port] = id
-		if server.Port >= pm.nextPort {
-			pm.nextPort = server.Port + 1
+// rebuildPortMap recomputes portMap from pm.servers, keeping reservations of creates in progress.
+// When several servers claim the same port, one keeps it (a running server if any, otherwise
+// the lowest ID) and the others are stopped and moved to a free port, or marked failed and left
+// out of portMap when the range is full, so the proxy can't route to the wrong server.
+// Reports whether any conflicts were fixed. Callers must hold pm.mutex.
+func (pm *ProcessManager) rebuildPortMap() bool {
+	byPort := make(map[int][]*ServerInstance)
+	for _, server := range pm.servers {
+		byPort[server.Port] = append(byPort[server.Port], server)
+	}
+
+	portMap := make(map[int]string)
+	for port, id := range pm.portMap {
+		if id == "" && len(byPort[port]) == 0 {
+			portMap[port] = "" // Reserved by a create that hasn't stored its server yet
+		}
+	}
+
+	var displaced []*ServerInstance
+	for port, claimants := range byPort {
+		sort.Slice(claimants, func(i, j int) bool {
+			iRunning, jRunning := claimants[i].Status == StatusRunning, claimants[j].Status == StatusRunning
+			if iRunning != jRunning {
+				return iRunning
+			}
+			return claimants[i].ID < claimants[j].ID
+		})
+		portMap[port] = claimants[0].ID
+		displaced = append(displaced, claimants[1:]...)
+	}
+	pm.portMap = portMap
+
+	sort.Slice(displaced, func(i, j int) bool { return displaced[i].ID < displaced[j].ID })
+	for _, server := range displaced {
+		oldPort := server.Port
+		owner := pm.portMap[oldPort]
+		server.PID = nil
+		server.StartTime = nil
+
+		port, err := pm.findFreePort()
+		if err != nil {
+			pm.setStatus(server, StatusFailed)
+			message := fmt.Sprintf("Port %d is also claimed by server %s and no free port is left; server quarantined until its port is fixed", oldPort, owner)
+			pm.reportPortConflict(server, message)
+			continue
 		}
+
+		pm.setStatus(server, StatusStopped)
+		server.Port = port
+		pm.portMap[port] = server.ID
+		pm.reportPortConflict(server, fmt.Sprintf("Port %d is also claimed by server %s; moved to port %d", oldPort, owner, port))
 	}
+	return len(displaced) > 0
 }
 
-func (pm *ProcessManager) saveServers() {
-	data, err := json.MarshalIndent(pm.servers, "", "  ")
-	if err != nil {
-		log.Printf("Error marshaling servers: %v", err)
-		return
+// reportPortConflict logs how a server sharing a port with another was resolved
+func (pm *ProcessManager) reportPortConflict(server *ServerInstance, message string) {
+	pm.log.Printf("Error: duplicate port in servers file for server %s (%s): %s", server.Name, server.ID, message)
+	pm.logger.LogProcessEvent(server.ID, server.Name, "PORT_CONFLICT", message)
+	if pm.logManager != nil {
+		pm.logManager.AddServerLog(server.ID, server.Name, "ERROR", "server", message)
 	}
+}
 
-	if err := os.WriteFile(pm.serversFile, data, 0644); err != nil {
-		log.Printf("Error saving servers file: %v", err)
-		return
+func (pm *ProcessManager) saveServers() error {
+	if err := pm.store.Save(pm.servers); err != nil {
+		pm.log.Printf("Error saving servers file: %v", err)
+		return fmt.Errorf("%w: %v", ErrStateNotSaved, err)
 	}
+	return nil
 }
 
 // Workspace initialization helper methods
-func (pm *ProcessManager) extractZipFile(zipPath, targetPath string) error {
+
+// extractArchive unpacks an uploaded workspace archive, choosing the format from its file extension
+func (pm *ProcessManager) extractArchive(archivePath, targetPath string) error {
+	return pm.extractArchiveWithLimit(archivePath, targetPath, GetConfig().UI.Workspace.MaxUploadSizeMB)
+}
+
+// extractArchiveWithLimit unpacks an archive, failing once more than limitMB (0 for no limit)
+// has been written
+func (pm *ProcessManager) extractArchiveWithLimit(archivePath, targetPath string, limitMB int) error {
+	if isTarGzArchive(archivePath) {
+		return pm.extractTarGz(archivePath, targetPath, limitMB)
+	}
+	return pm.extractZipFile(archivePath, targetPath, limitMB)
+}
+
+// extractionBudget caps the bytes written while extracting one archive at
+// ui.workspace.max_upload_size_mb, so a small archive can't expand to fill the disk
+type extractionBudget struct {
+	limitMB   int // 0 when unlimited
+	remaining int64
+}
+
+func newExtractionBudget(limitMB int) *extractionBudget {
+	if limitMB <= 0 {
+		return &extractionBudget{}
+	}
+	return &extractionBudget{limitMB: limitMB, remaining: int64(limitMB) * 1024 * 1024}
+}
+
+// copy writes src to dst, failing with ErrArchiveTooLarge once the budget is used up
+func (b *extractionBudget) copy(dst io.Writer, src io.Reader) error {
+	if b.limitMB == 0 {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+
+	written, err := io.CopyN(dst, src, b.remaining+1)
+	b.remaining -= written
+	if b.remaining < 0 {
+		return fmt.Errorf("%w: extracted files exceed the %dMB limit", ErrArchiveTooLarge, b.limitMB)
+	}
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// isTarGzArchive reports whether path names a gzip-compressed tarball
+func isTarGzArchive(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+}
+
+func (pm *ProcessManager) extractZipFile(zipPath, targetPath string, limitMB int) error {
 	reader, err := zip.OpenReader(zipPath)
 	if err != nil {
 		return err
 	}
 	defer reader.Close()
 
+	root, err := openArchiveRoot(targetPath, limitMB)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
 	for _, file := range reader.File {
 		// Sanitize file path to prevent directory traversal
-		if strings.Contains(file.Name, "..") {
+		name, ok := archiveEntryName(file.Name)
+		if !ok {
+			pm.log.Printf("Skipping archive entry outside the workspace: %s", file.Name)
 			continue
 		}
+		mode := file.Mode()
+
+		switch {
+		case mode.IsDir():
+			if err := root.mkdirAll(name, mode.Perm()|0700); err != nil {
+				return err
+			}
+		case mode&os.ModeSymlink != 0:
+			if err := pm.extractZipSymlink(file, root, name); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			if err := extractZipEntry(file, root, name); err != nil {
+				return err
+			}
+		default:
+			pm.log.Printf("Skipping unsupported archive entry: %s", file.Name)
+		}
+	}
+
+	pm.removeEscapingLinks(root)
+	return nil
+}
+
+// extractZipEntry writes one regular file from a zip archive, charging its size to the extraction budget
+func extractZipEntry(file *zip.File, root *archiveRoot, name string) error {
+	fileReader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer fileReader.Close()
+
+	return root.writeFile(name, file.Mode().Perm(), fileReader)
+}
+
+// extractZipSymlink recreates a symlink entry, whose content is the link target, unless it
+// points outside the workspace
+func (pm *ProcessManager) extractZipSymlink(file *zip.File, root *archiveRoot, name string) error {
+	fileReader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer fileReader.Close()
 
-		filePath := filepath.Join(targetPath, file.Name)
+	// A link target is a path, so anything longer than PATH_MAX is not one
+	target, err := io.ReadAll(io.LimitReader(fileReader, 4096))
+	if err != nil {
+		return err
+	}
+	linkname := string(target)
+	created, err := root.symlink(name, linkname)
+	if err == nil && !created {
+		pm.log.Printf("Skipping symlink pointing outside the workspace: %s -> %s", file.Name, linkname)
+	}
+	return err
+}
 
-		if file.FileInfo().IsDir() {
-			os.MkdirAll(filePath, file.FileInfo().Mode())
+// removeEscapingLinks removes extracted symlinks that resolve outside the workspace now that
+// every entry exists, e.g. "a -> s/.." created before "s -> ." made it point at the parent
+func (pm *ProcessManager) removeEscapingLinks(root *archiveRoot) {
+	for _, link := range root.links {
+		resolved, err := filepath.EvalSymlinks(link)
+		if err != nil || pathWithin(root.realPath, resolved) {
 			continue
 		}
-
-		// Create parent directories
-		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
-			return err
+		pm.log.Printf("Removing symlink pointing outside the workspace: %s", link)
+		if err := os.Remove(link); err != nil {
+			pm.log.Printf("Failed to remove symlink %s: %v", link, err)
 		}
+	}
+}
+
+func (pm *ProcessManager) extractTarGz(archivePath, targetPath string, limitMB int) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("not a gzip archive: %v", err)
+	}
+	defer gzipReader.Close()
 
-		// Extract file
-		fileReader, err := file.Open()
+	root, err := openArchiveRoot(targetPath, limitMB)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	reader := tar.NewReader(gzipReader)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			pm.removeEscapingLinks(root)
+			return nil
+		}
 		if err != nil {
 			return err
 		}
-		defer fileReader.Close()
 
-		targetFile, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.FileInfo().Mode())
-		if err != nil {
-			return err
+		// Sanitize file path to prevent directory traversal
+		name, ok := archiveEntryName(header.Name)
+		if !ok {
+			pm.log.Printf("Skipping archive entry outside the workspace: %s", header.Name)
+			continue
 		}
-		defer targetFile.Close()
+		mode := os.FileMode(header.Mode).Perm()
 
-		if _, err := io.Copy(targetFile, fileReader); err != nil {
-			return err
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := root.mkdirAll(name, mode|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := root.writeFile(name, mode, reader); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			created, err := root.symlink(name, header.Linkname)
+			if err != nil {
+				return err
+			}
+			if !created {
+				pm.log.Printf("Skipping symlink pointing outside the workspace: %s -> %s", header.Name, header.Linkname)
+			}
+		case tar.TypeLink:
+			// Hard link targets are named relative to the archive root
+			oldname, ok := archiveEntryName(header.Linkname)
+			if ok {
+				ok, err = root.link(name, oldname)
+				if err != nil {
+					return err
+				}
+			}
+			if !ok {
+				pm.log.Printf("Skipping hard link pointing outside the workspace: %s -> %s", header.Name, header.Linkname)
+			}
+		default:
+			// Devices, FIFOs and other special files have no place in a workspace
+			pm.log.Printf("Skipping unsupported archive entry: %s", header.Name)
 		}
 	}
+}
+
+// archiveRoot writes the entries of one archive beneath a workspace directory. Files and
+// directories are written through an os.Root, so symlinks created by earlier entries can't
+// redirect later writes outside it; symlinks and hard links are only created after their real
+// location and target have been checked to be inside as well.
+type archiveRoot struct {
+	root     *os.Root
+	realPath string   // The workspace directory with symlinks resolved
+	links    []string // Symlinks created so far, by real path
+	budget   *extractionBudget
+}
+
+func openArchiveRoot(targetPath string, limitMB int) (*archiveRoot, error) {
+	root, err := os.OpenRoot(targetPath)
+	if err != nil {
+		return nil, err
+	}
+	realPath, err := filepath.EvalSymlinks(targetPath)
+	if err != nil {
+		root.Close()
+		return nil, err
+	}
+	return &archiveRoot{root: root, realPath: realPath, budget: newExtractionBudget(limitMB)}, nil
+}
 
-	return nil
+func (a *archiveRoot) Close() error {
+	return a.root.Close()
 }
 
-func (pm *ProcessManager) cloneGithubRepo(repoURL, targetPath string) error {
-	cmd := exec.Command("git", "clone", repoURL, targetPath)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to clone repository: %v", err)
+// mkdirAll creates directory name and any missing parents (os.Root only has MkdirAll from Go 1.25)
+func (a *archiveRoot) mkdirAll(name string, perm os.FileMode) error {
+	current := ""
+	for _, part := range strings.Split(name, string(filepath.Separator)) {
+		if part == "" || part == "." {
+			continue
+		}
+		current = filepath.Join(current, part)
+		if err := a.root.Mkdir(current, perm); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
 	}
 	return nil
 }
 
+// writeFile creates regular file name from r, charging its size to the extraction budget
+func (a *archiveRoot) writeFile(name string, perm os.FileMode, r io.Reader) error {
+	if err := a.mkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	file, err := a.root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return a.budget.copy(file, r)
+}
+
+// realParent creates the parent directory of entry name and returns its real path, or false if
+// symlinks made by earlier entries lead it outside the workspace
+func (a *archiveRoot) realParent(name string) (string, bool, error) {
+	dir := filepath.Dir(name)
+	if err := a.mkdirAll(dir, 0755); err != nil {
+		return "", false, err
+	}
+	parent, err := filepath.EvalSymlinks(filepath.Join(a.realPath, dir))
+	if err != nil {
+		return "", false, err
+	}
+	return parent, pathWithin(a.realPath, parent), nil
+}
+
+// symlink creates symlink name pointing at linkname, returning false without creating it when
+// the link would point outside the workspace. Relative targets are checked from the link's real
+// directory, and as far as they already exist are resolved for real, since ".." after a symlink
+// climbs from wherever that symlink leads.
+func (a *archiveRoot) symlink(name, linkname string) (bool, error) {
+	parent, ok, err := a.realParent(name)
+	if err != nil || !ok {
+		return false, err
+	}
+	linkPath := filepath.Join(parent, filepath.Base(name))
+	if !symlinkWithin(a.realPath, linkPath, linkname) {
+		return false, nil
+	}
+	target := linkname
+	if !filepath.IsAbs(target) {
+		target = parent + string(filepath.Separator) + target
+	}
+	if resolved, err := filepath.EvalSymlinks(target); err == nil && !pathWithin(a.realPath, resolved) {
+		return false, nil
+	}
+
+	if err := os.Symlink(linkname, linkPath); err != nil {
+		return false, err
+	}
+	a.links = append(a.links, linkPath)
+	return true, nil
+}
+
+// link creates name as a hard link to the existing entry oldname, returning false without
+// creating it when oldname really lies outside the workspace
+func (a *archiveRoot) link(name, oldname string) (bool, error) {
+	parent, ok, err := a.realParent(name)
+	if err != nil || !ok {
+		return false, err
+	}
+	source, err := filepath.EvalSymlinks(filepath.Join(a.realPath, oldname))
+	if err != nil {
+		return false, err
+	}
+	if !pathWithin(a.realPath, source) {
+		return false, nil
+	}
+	return true, os.Link(source, filepath.Join(parent, filepath.Base(name)))
+}
+
+// archiveEntryName returns the cleaned path, relative to the workspace, that an archive entry
+// named name is extracted to, or false for names that are absolute or would land outside it.
+// Backslashes, which some archivers write as separators, are treated as slashes.
+func archiveEntryName(name string) (string, bool) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if name == "" || strings.HasPrefix(name, "/") {
+		return "", false
+	}
+	cleaned := filepath.Clean(filepath.FromSlash(name))
+	if !filepath.IsLocal(cleaned) {
+		return "", false
+	}
+	return cleaned, true
+}
+
+// symlinkWithin reports whether a symlink at linkPath pointing to linkname stays inside root.
+// Relative link targets resolve from the link's own directory.
+func symlinkWithin(root, linkPath, linkname string) bool {
+	if linkname == "" {
+		return false
+	}
+	target := linkname
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(linkPath), target)
+	}
+	return pathWithin(root, target)
+}
+
+// pathWithin reports whether path is root or lies below it, without following symlinks
+func pathWithin(root, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(root), filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// gitCloneOptions narrows what cloneGithubRepo fetches; the zero value clones the default branch with full history
+type gitCloneOptions struct {
+	Branch string // Branch or tag to check out
+	Depth  int    // Number of commits to fetch, 0 for the full history
+}
+
+// ErrInvalidCloneOptions is returned for a github workspace source whose branch or depth option is malformed
+var ErrInvalidCloneOptions = errors.New("invalid clone options")
+
+// parseGitCloneOptions reads the optional "branch" and "depth" options of a github workspace source
+func parseGitCloneOptions(options map[string]string) (gitCloneOptions, error) {
+	var opts gitCloneOptions
+	opts.Branch = strings.TrimSpace(options["branch"])
+	if strings.HasPrefix(opts.Branch, "-") {
+		return opts, fmt.Errorf("%w: invalid branch %q", ErrInvalidCloneOptions, opts.Branch)
+	}
+	if depth := strings.TrimSpace(options["depth"]); depth != "" {
+		n, err := strconv.Atoi(depth)
+		if err != nil || n <= 0 {
+			return opts, fmt.Errorf("%w: invalid depth %q: must be a positive integer", ErrInvalidCloneOptions, depth)
+		}
+		opts.Depth = n
+	}
+	return opts, nil
+}
+
+// ErrGitRefNotFound is returned when the branch or tag to clone doesn't exist in the repository
+var ErrGitRefNotFound = errors.New("branch not found")
+
+func (pm *ProcessManager) cloneGithubRepo(parent context.Context, repoURL, targetPath string, opts gitCloneOptions) error {
+	timeout := GetConfig().Timeouts.GitClone()
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	args := []string{"clone"}
+	if opts.Branch != "" {
+		args = append(args, "--branch", opts.Branch)
+	}
+	if opts.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+	}
+	args = append(args, repoURL, targetPath)
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	// Fail fast instead of blocking on a credential prompt for private or missing repos
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.WaitDelay = 5 * time.Second
+
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+
+	// git only clones into an empty directory, so whatever a failed or killed clone left behind is its own
+	clearDirectory(targetPath)
+
+	if err := parent.Err(); err != nil {
+		return fmt.Errorf("failed to clone repository: %w", err)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("failed to clone repository: timed out after %s", timeout)
+	}
+	message := strings.TrimSpace(string(output))
+	if opts.Branch != "" && strings.Contains(message, "not found in upstream") {
+		return fmt.Errorf("failed to clone repository: %w: %q does not exist in %s", ErrGitRefNotFound, opts.Branch, repoURL)
+	}
+	return fmt.Errorf("failed to clone repository: %v: %s", err, message)
+}
+
+// clearDirectory removes everything inside dir, leaving dir itself in place
+func clearDirectory(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		os.RemoveAll(filepath.Join(dir, entry.Name()))
+	}
+}
+
 // Restart server functionality
 func (pm *ProcessManager) RestartServer(id string) error {
+	if err := pm.beginOperation(id, "restart"); err != nil {
+		return err
+	}
+	defer pm.endOperation(id)
+
+	pm.cancelAutoRestart(id)
+
 	pm.mutex.Lock()
 	server, exists := pm.servers[id]
 	if !exists {
@@ -810,114 +1968,428 @@ func (pm *ProcessManager) RestartServer(id string) error {
 	if pm.logManager != nil {
 		pm.logManager.AddServerLog(id, server.Name, "INFO", "server", "Server restart requested")
 	}
+	running := server.Status == StatusRunning
 	pm.mutex.Unlock()
 
-	// Stop the server if running
-	if server.Status == StatusRunning {
-		if err := pm.StopServer(id); err != nil {
+	// Stop the server if running; stopServer returns once the old process is gone
+	if running {
+		if err := pm.stopServer(id); err != nil {
 			return fmt.Errorf("failed to stop server for restart: %v", err)
 		}
-
-		// Wait a moment before starting
-		time.Sleep(time.Second)
 	}
 
 	// Start the server
-	return pm.StartServer(id)
+	return pm.startServer(id)
 }
 
 // Extension installation methods (like Python version)
-func (pm *ProcessManager) installExtension(env []string, extensionID, serverID, serverName string) bool {
-	log.Printf("Installing extension: %s", extensionID)
 
-	cmd := exec.Command("code-server", "--install-extension", extensionID)
-	cmd.Env = env
+// extensionInstallEnv returns the environment for running code-server against the
+// given data directory, which is exported as an absolute XDG_DATA_HOME
+func (pm *ProcessManager) extensionInstallEnv(dataDir string) []string {
+	return withMarketplace(withCABundle(append(os.Environ(), xdgDataHomeEnv(dataDir))))
+}
 
-	stdout, err := cmd.Output()
+// withMarketplace points code-server at the configured extension registry, replacing any
+// EXTENSIONS_GALLERY inherited from the manager's environment
+func withMarketplace(env []string) []string {
+	result := make([]string, 0, len(env)+1)
+	for _, entry := range env {
+		if !strings.HasPrefix(entry, "EXTENSIONS_GALLERY=") {
+			result = append(result, entry)
+		}
+	}
+	return append(result, GetConfig().Marketplace.GalleryEnv())
+}
+
+// withCABundle points Node at the configured CA bundle with NODE_EXTRA_CA_CERTS and drops the
+// insecure NODE_TLS_REJECT_UNAUTHORIZED, which is only a fallback for when no bundle is configured.
+// env is returned unchanged without a bundle.
+func withCABundle(env []string) []string {
+	caBundle := GetConfig().TLS.CABundle
+	if caBundle == "" {
+		return env
+	}
+
+	result := make([]string, 0, len(env)+1)
+	for _, entry := range env {
+		if strings.HasPrefix(entry, "NODE_TLS_REJECT_UNAUTHORIZED=") || strings.HasPrefix(entry, "NODE_EXTRA_CA_CERTS=") {
+			continue
+		}
+		result = append(result, entry)
+	}
+	return append(result, "NODE_EXTRA_CA_CERTS="+caBundle)
+}
+
+// xdgDataHomeEnv returns the XDG_DATA_HOME assignment for a data directory as an absolute path
+func xdgDataHomeEnv(dataDir string) string {
+	absDataDir, err := filepath.Abs(dataDir)
+	if err != nil {
+		log.Printf("Failed to get absolute data dir path: %v", err)
+		absDataDir = dataDir // Fallback to relative path
+	}
+
+	return fmt.Sprintf("XDG_DATA_HOME=%s", absDataDir)
+}
+
+// sensitiveEnvKeys are substrings of environment variable names whose values are never recorded
+var sensitiveEnvKeys = []string{"TOKEN", "SECRET", "PASSWORD", "PASSWD", "CREDENTIAL", "AUTH", "PRIVATE", "KEY"}
+
+// addedEnv returns the entries of env that aren't in inherited
+func addedEnv(inherited, env []string) []string {
+	existing := make(map[string]bool, len(inherited))
+	for _, entry := range inherited {
+		existing[entry] = true
+	}
+	var added []string
+	for _, entry := range env {
+		if !existing[entry] {
+			added = append(added, entry)
+		}
+	}
+	return added
+}
+
+// redactEnv converts KEY=VALUE pairs into a map, replacing values of sensitive-looking keys
+func redactEnv(env []string) map[string]string {
+	result := make(map[string]string, len(env))
+	for _, entry := range env {
+		key, value, _ := strings.Cut(entry, "=")
+		upperKey := strings.ToUpper(key)
+		for _, sensitive := range sensitiveEnvKeys {
+			if strings.Contains(upperKey, sensitive) {
+				value = "[REDACTED]"
+				break
+			}
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// installExtension installs an extension, which may be pinned with the id@version syntax.
+// Returns the resolved id@version reported by code-server (or extensionID if it can't be
+// determined) and whether the installation succeeded.
+func (pm *ProcessManager) installExtension(parent context.Context, env []string, extensionID, serverID, serverName string) (string, bool) {
+	if isVSIXEntry(extensionID) {
+		message := fmt.Sprintf("%s was installed from an uploaded .vsix and must be uploaded again", strings.TrimPrefix(extensionID, vsixExtensionPrefix))
+		pm.log.Printf("Skipping extension: %s", message)
+		pm.logger.LogProcessEvent(serverID, serverName, "EXTENSION_INSTALL_FAILED", message)
+		return extensionID, false
+	}
+	// Entries from config or servers.json haven't been through the API checks
+	if err := validateExtensionRef(extensionID); err != nil {
+		pm.log.Printf("Skipping extension: %v", err)
+		pm.logger.LogProcessEvent(serverID, serverName, "EXTENSION_INSTALL_FAILED", err.Error())
+		return extensionID, false
+	}
+	pm.log.Printf("Installing extension: %s", extensionID)
+
+	// Retry transient failures such as network blips, giving up early if the caller is cancelled
+	// or code-server is missing altogether
+	account, err := pm.serverCommandUser(serverID)
+	if err != nil {
+		pm.logger.LogProcessEvent(serverID, serverName, "EXTENSION_INSTALL_FAILED", fmt.Sprintf("Failed to install %s: %v", extensionID, err))
+		return extensionID, false
+	}
+	retries := GetConfig().Server.InstallRetries()
+	stdout, duration, err := pm.runExtensionInstall(parent, account, env, extensionID)
+	for attempt := 1; err != nil && attempt <= retries && parent.Err() == nil && !errors.Is(err, exec.ErrNotFound); attempt++ {
+		pm.log.Printf("Failed to install extension %s: %v; retrying (%d/%d)", extensionID, err, attempt, retries)
+		pm.logger.LogProcessEvent(serverID, serverName, "EXTENSION_INSTALL_RETRY",
+			fmt.Sprintf("Retrying %s (attempt %d of %d) after: %v", extensionID, attempt+1, retries+1, err))
+
+		select {
+		case <-time.After(extensionRetryDelay * time.Duration(attempt)):
+		case <-parent.Done():
+		}
+		if parent.Err() != nil {
+			err = parent.Err()
+			break
+		}
+		stdout, duration, err = pm.runExtensionInstall(parent, account, env, extensionID)
+	}
 	if err != nil {
-		log.Printf("Failed to install extension %s: %v", extensionID, err)
+		pm.log.Printf("Failed to install extension %s after %s: %v", extensionID, duration.Round(time.Millisecond), err)
 		pm.logger.LogProcessEvent(serverID, serverName, "EXTENSION_INSTALL_FAILED",
-			fmt.Sprintf("Failed to install %s: %v", extensionID, err))
-		return false
+			fmt.Sprintf("Failed to install %s after %dms: %v", extensionID, duration.Milliseconds(), err))
+		return extensionID, false
 	}
 
-	log.Printf("Successfully installed extension: %s", extensionID)
+	resolved := resolveInstalledExtension(extensionID, string(stdout))
+	pm.log.Printf("Successfully installed extension: %s (took %s)", resolved, duration.Round(time.Millisecond))
 	if len(stdout) > 0 {
-		log.Printf("Extension install output: %s", string(stdout))
+		pm.log.Printf("Extension install output: %s", string(stdout))
 	}
 	pm.logger.LogProcessEvent(serverID, serverName, "EXTENSION_INSTALLED",
-		fmt.Sprintf("Successfully installed %s", extensionID))
-	return true
+		fmt.Sprintf("Successfully installed %s in %dms", resolved, duration.Milliseconds()))
+	return resolved, true
+}
+
+// extensionRetryDelay is the wait before the first retry of a failed extension install; later
+// retries wait proportionally longer
+const extensionRetryDelay = 2 * time.Second
+
+// runExtensionInstall makes one attempt at installing extensionID as account (nil for the
+// manager's user), returning code-server's output
+func (pm *ProcessManager) runExtensionInstall(parent context.Context, account *serverUser, env []string, extensionID string) ([]byte, time.Duration, error) {
+	// Bound the install so a hung marketplace request doesn't stall the whole install loop
+	timeout := GetConfig().Timeouts.ExtensionInstall()
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	// code-server accepts id@version natively, so the reference is passed through as-is
+	cmd := exec.CommandContext(ctx, "code-server", "--install-extension", extensionID)
+	cmd.Env = env
+	if err := runAs(cmd, account); err != nil {
+		return nil, 0, err
+	}
+	// Don't wait indefinitely on child processes still holding the output pipe after a kill
+	cmd.WaitDelay = 5 * time.Second
+
+	startTime := time.Now()
+	stdout, err := cmd.Output()
+	duration := time.Since(startTime)
+	if parent.Err() != nil {
+		err = parent.Err()
+	} else if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	return stdout, duration, err
 }
 
-func (pm *ProcessManager) installExtensions(env []string, extensions []string, serverID, serverName string) bool {
+// installExtensions installs extensions in parallel, returning the resolved references
+// of those that succeeded and whether all of them did
+func (pm *ProcessManager) installExtensions(ctx context.Context, env []string, extensions []string, serverID, serverName string) ([]string, bool) {
 	if len(extensions) == 0 {
-		return true
+		return []string{}, true
 	}
 
-	log.Printf("Installing %d extensions: %v", len(extensions), extensions)
-	successCount := 0
+	pm.log.Printf("Installing %d extensions: %v", len(extensions), extensions)
+	results := pm.installExtensionBatch(ctx, env, extensions, serverID, serverName, nil, nil)
+	resolved := make([]string, 0, len(extensions))
+	for i, result := range results {
+		if result.ok {
+			resolved = append(resolved, result.resolved)
+		} else {
+			pm.log.Printf("Failed to install extension: %s", extensions[i])
+		}
+	}
+
+	pm.log.Printf("Successfully installed %d/%d extensions", len(resolved), len(extensions))
+	return resolved, len(resolved) == len(extensions)
+}
+
+// extensionInstallResult is the outcome of installing one extension of a batch
+type extensionInstallResult struct {
+	resolved string // The id@version code-server installed
+	ok       bool
+}
+
+// installExtensionBatch installs extensions with up to server.extension_install_concurrency
+// installs running at once. onStart and onDone (either may be nil) are called from the worker
+// goroutines, so they must be safe for concurrent use. Parallel installs race on the server's
+// extensions.json, so once they have finished the entries they dropped are restored from the
+// installed directories. Results are in the order of extensions; extensions not attempted
+// because ctx ended count as failed.
+func (pm *ProcessManager) installExtensionBatch(ctx context.Context, env []string, extensions []string, serverID, serverName string,
+	onStart func(extension string), onDone func(extension string, result extensionInstallResult, duration time.Duration)) []extensionInstallResult {
+	results := make([]extensionInstallResult, len(extensions))
+	workers := min(GetConfig().Server.ExtensionInstallConcurrency, len(extensions))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				extension := extensions[i]
+				if onStart != nil {
+					onStart(extension)
+				}
+				startTime := time.Now()
+				resolved, ok := pm.installExtension(ctx, env, extension, serverID, serverName)
+				results[i] = extensionInstallResult{resolved: resolved, ok: ok}
+				if onDone != nil {
+					onDone(extension, results[i], time.Since(startTime))
+				}
+			}
+		}()
+	}
+
+queue:
+	for i := range extensions {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			pm.log.Printf("Stopping extension installation for server %s: %v", serverID, ctx.Err())
+			break queue
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if workers > 1 {
+		if added, err := reconcileExtensionsIndex(pm.extensionsDir(serverID)); err != nil {
+			pm.log.Printf("Failed to reconcile extensions.json for server %s: %v", serverID, err)
+		} else if added > 0 {
+			pm.log.Printf("Restored %d extensions.json entries dropped by parallel installs for server %s", added, serverID)
+			if err := pm.handOverServerData(serverID, "code-server/extensions/"+extensionsIndexFile); err != nil {
+				pm.log.Printf("Failed to give extensions.json to the user of server %s: %v", serverID, err)
+			}
+		}
+	}
+	return results
+}
+
+// installedExtensionPattern matches code-server's report of the version it installed
+var installedExtensionPattern = regexp.MustCompile(`Extension '([^']+)' v(\S+) (?:was successfully installed|is already installed)`)
+
+// extensionRefPattern matches marketplace references: publisher.name with an optional @version
+var extensionRefPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*\.[A-Za-z0-9][A-Za-z0-9_.-]*(@[A-Za-z0-9][A-Za-z0-9.+-]*)?$`)
+
+// validateExtensionRef accepts a publisher.name[@version] reference, a local .vsix file or an
+// http(s) URL, so obviously malformed entries fail before code-server is run for them
+func validateExtensionRef(ref string) error {
+	switch {
+	case extensionRefPattern.MatchString(ref):
+		return nil
+	case strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "http://"):
+		if _, err := url.ParseRequestURI(ref); err == nil && !strings.ContainsAny(ref, " \t\n") {
+			return nil
+		}
+	case strings.HasSuffix(strings.ToLower(ref), ".vsix") && !strings.HasPrefix(ref, "-") && !strings.ContainsAny(ref, "\n\x00"):
+		return nil
+	}
+	return fmt.Errorf("%w %q: expected publisher.name[@version], a .vsix path or an http(s) URL", ErrInvalidExtension, ref)
+}
 
+// validateExtensionRefs checks every reference in extensions, reporting the first invalid one
+func validateExtensionRefs(extensions []string) error {
 	for _, extension := range extensions {
-		if pm.installExtension(env, extension, serverID, serverName) {
-			successCount++
-		} else {
-			log.Printf("Failed to install extension: %s", extension)
+		if err := validateExtensionRef(extension); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitExtensionRef splits an "id@version" reference into its ID and (optional) version
+func splitExtensionRef(ref string) (string, string) {
+	if idx := strings.LastIndex(ref, "@"); idx > 0 {
+		return ref[:idx], ref[idx+1:]
+	}
+	return ref, ""
+}
+
+// extensionRefID returns the normalized (lowercase) extension ID of a possibly pinned reference
+func extensionRefID(ref string) string {
+	id, _ := splitExtensionRef(strings.TrimPrefix(ref, vsixExtensionPrefix))
+	return strings.ToLower(id)
+}
+
+// resolveInstalledExtension determines the id@version that code-server actually installed
+func resolveInstalledExtension(requested, output string) string {
+	requestedID := extensionRefID(requested)
+	for _, match := range installedExtensionPattern.FindAllStringSubmatch(output, -1) {
+		if strings.ToLower(match[1]) == requestedID {
+			return match[1] + "@" + match[2]
 		}
 	}
+	return requested
+}
+
+// mergeResolvedExtensions replaces entries in requested with their resolved id@version,
+// matching on extension ID and keeping entries that weren't resolved unchanged
+func mergeResolvedExtensions(requested, resolved []string) []string {
+	resolvedByID := make(map[string]string, len(resolved))
+	for _, ref := range resolved {
+		resolvedByID[extensionRefID(ref)] = ref
+	}
 
-	log.Printf("Successfully installed %d/%d extensions", successCount, len(extensions))
-	return successCount == len(extensions)
+	merged := make([]string, 0, len(requested))
+	for _, ref := range requested {
+		if resolvedRef, ok := resolvedByID[extensionRefID(ref)]; ok {
+			merged = append(merged, resolvedRef)
+		} else {
+			merged = append(merged, ref)
+		}
+	}
+	return merged
 }
 
-// State refresh routine - refreshes server state from file every second
+// State refresh routine - reloads server state when the servers file changes
 func (pm *ProcessManager) startStateRefreshRoutine() {
-	ticker := time.NewTicker(1 * time.Second) // Refresh every second
+	interval := GetConfig().Monitoring.StateRefreshInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	log.Println("State refresh routine started - refreshing server state every second")
+	pm.log.Printf("State refresh routine started - checking server state every %v", interval)
 
-	for range ticker.C {
-		pm.refreshStateFromFile()
+	for {
+		select {
+		case <-pm.done:
+			return
+		case <-ticker.C:
+			pm.refreshStateFromFile()
+		}
 	}
 }
 
-func (pm *ProcessManager) refreshStateFromFile() {
+// Metrics routine - samples process metrics for running servers
+func (pm *ProcessManager) startMetricsRoutine() {
+	interval := GetConfig().Monitoring.MetricsInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	pm.log.Printf("Metrics routine started - sampling server metrics every %v", interval)
+
+	for {
+		select {
+		case <-pm.done:
+			return
+		case <-ticker.C:
+			pm.refreshMetrics()
+		}
+	}
+}
+
+// refreshMetrics samples all running servers and stores the results
+func (pm *ProcessManager) refreshMetrics() {
+	// Sample process metrics before taking the lock
+	samples := pm.sampleServerProcesses()
+
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
-	// First, update metrics for all running servers
-	pm.updateServerMetrics()
+	pm.updateServerMetrics(samples)
 
-	// Reload servers from file
-	if _, err := os.Stat(pm.serversFile); os.IsNotExist(err) {
-		return // File doesn't exist
-	}
+	// Save updated state with metrics
+	pm.saveServers()
+}
 
-	data, err := os.ReadFile(pm.serversFile)
-	if err != nil {
-		// Don't log every second if file read fails, just skip this refresh
-		return
+// refreshStateFromFile reloads the servers file if it was changed by anything other than saveServers
+func (pm *ProcessManager) refreshStateFromFile() {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	if !pm.store.Changed() {
+		return // Missing or unchanged since we last wrote or read it
 	}
 
-	var servers map[string]*ServerInstance
-	if err := json.Unmarshal(data, &servers); err != nil {
-		// Don't log every second if parse fails, just skip this refresh
+	servers, err := pm.store.Load()
+	if err != nil {
+		// Don't log on every tick if the file can't be read or parsed, just skip this refresh
 		return
 	}
 
 	// Update in-memory state with fresh data from file, but preserve current running state
 	oldServers := pm.servers
 	pm.servers = servers
-	pm.portMap = make(map[int]string)
 
-	// Rebuild port map and preserve current state for running servers
+	// Preserve current state for running servers
 	for id, server := range servers {
-		pm.portMap[server.Port] = id
-		if server.Port >= pm.nextPort {
-			pm.nextPort = server.Port + 1
-		}
-
 		// If server was running in memory but file shows stopped, prefer memory state
 		// This handles the case where StartServer just updated the state
 		if oldServer, exists := oldServers[id]; exists {
@@ -935,10 +2407,21 @@ func (pm *ProcessManager) refreshStateFromFile() {
 				server.MemoryMB = oldServer.MemoryMB
 				server.LastUpdate = oldServer.LastUpdate
 			}
+			// Access times are only written on the next save, so keep the newer one
+			if oldServer.LastAccess != nil && (server.LastAccess == nil || oldServer.LastAccess.After(*server.LastAccess)) {
+				server.LastAccess = oldServer.LastAccess
+			}
+			// Status changed by whatever wrote the file
+			if server.Status != oldServer.Status {
+				pm.statusNotifier.notify(id, oldServer.Status, server.Status)
+			}
 		}
 	}
 
-	// Save updated state with metrics
+	// Rebuild port map (after the merge, so running servers keep their port in a conflict)
+	pm.rebuildPortMap()
+
+	// Save the merged state
 	pm.saveServers()
 }
 
@@ -947,67 +2430,220 @@ func (pm *ProcessManager) startHealthMonitor() {
 	ticker := time.NewTicker(30 * time.Second) // Check every 30 seconds
 	defer ticker.Stop()
 
-	log.Println("Health monitor started - checking all servers every 30 seconds")
+	pm.log.Printf("Health monitor started - checking all servers every 30 seconds")
 
-	for range ticker.C {
-		pm.performHealthCheck()
+	for {
+		select {
+		case <-pm.done:
+			return
+		case <-ticker.C:
+			pm.performHealthCheck()
+		}
 	}
 }
 
 func (pm *ProcessManager) performHealthCheck() {
+	// Snapshot running servers so the HTTP probes run without holding the lock
+	pm.mutex.RLock()
+	pids := make(map[string]int)
+	probes := make(map[string]healthProbe)
+	stoppedCount := 0
+	for serverID, server := range pm.servers {
+		if server.Status == StatusRunning && server.PID != nil {
+			pids[serverID] = *server.PID
+			probes[serverID] = newHealthProbe(server)
+		} else {
+			stoppedCount++
+		}
+	}
+	pm.mutex.RUnlock()
+
+	// Check if servers are healthy via HTTP health endpoint
+	healthy := pm.checkServersHealth(probes)
+
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 
 	runningCount := 0
-	stoppedCount := 0
-	serversToUpdate := make([]*ServerInstance, 0)
+	updatedCount := 0
 
-	for serverID, server := range pm.servers {
-		if server.Status == StatusRunning && server.PID != nil {
+	for serverID, pid := range pids {
+		server, exists := pm.servers[serverID]
+		if !exists || server.PID == nil || *server.PID != pid {
+			continue // Server changed while it was being probed
+		}
+
+		if !healthy[serverID] {
+			// Server is not responding to health checks, mark as stopped
+			pm.log.Printf("Health check: Server %s on port %d failed health check", server.Name, server.Port)
+			pm.logger.LogProcessEvent(serverID, server.Name, "HEALTH_CHECK_FAILED",
+				fmt.Sprintf("Server on port %d failed to respond to /healthz", server.Port))
+
+			if pm.logManager != nil {
+				pm.logManager.AddServerLog(serverID, server.Name, "WARN", "server",
+					fmt.Sprintf("Health check failed - server marked as stopped (port %d)", server.Port))
+			}
+
+			pm.setStatus(server, StatusStopped)
+			server.PID = nil
+			server.StartTime = nil
+			updatedCount++
+			stoppedCount++
+		} else {
 			runningCount++
+			// Server is healthy, log periodic health check (every 5 minutes)
+			if time.Now().Unix()%300 == 0 {
+				pm.logger.LogProcessEvent(serverID, server.Name, "HEALTH_CHECK_OK",
+					fmt.Sprintf("Server on port %d is healthy", server.Port))
+			}
+		}
+	}
 
-			// Check if server is healthy via HTTP health endpoint
-			isHealthy := pm.isServerHealthy(server.Port)
+	// Save updates if any servers changed status
+	if updatedCount > 0 {
+		pm.saveServers()
+		pm.log.Printf("Health check: Updated status for %d servers that died", updatedCount)
+	}
+
+	// Log summary every 2 minutes (every 4th check)
+	if time.Now().Unix()%120 == 0 {
+		pm.log.Printf("Health check summary: %d running, %d stopped servers", runningCount, stoppedCount)
+	}
+}
 
-			if !isHealthy {
-				// Server is not responding to health checks, mark as stopped
-				log.Printf("Health check: Server %s on port %d failed health check", server.Name, server.Port)
-				pm.logger.LogProcessEvent(serverID, server.Name, "HEALTH_CHECK_FAILED",
-					fmt.Sprintf("Server on port %d failed to respond to /healthz", server.Port))
+// checkServersHealth probes the given servers concurrently and returns health keyed by server ID
+func (pm *ProcessManager) checkServersHealth(probes map[string]healthProbe) map[string]bool {
+	var wg sync.WaitGroup
+	var resultsMutex sync.Mutex
+	results := make(map[string]bool, len(probes))
 
-				if pm.logManager != nil {
-					pm.logManager.AddServerLog(serverID, server.Name, "WARN", "server",
-						fmt.Sprintf("Health check failed - server marked as stopped (port %d)", server.Port))
-				}
+	for serverID, probe := range probes {
+		wg.Add(1)
+		go func(serverID string, probe healthProbe) {
+			defer wg.Done()
+			isHealthy := pm.probeHealth(probe)
 
-				server.Status = StatusStopped
-				server.PID = nil
-				server.StartTime = nil
-				serversToUpdate = append(serversToUpdate, server)
-				runningCount-- // Adjust count since this server just stopped
-				stoppedCount++
-			} else {
-				// Server is healthy, log periodic health check (every 5 minutes)
-				if time.Now().Unix()%300 == 0 {
-					pm.logger.LogProcessEvent(serverID, server.Name, "HEALTH_CHECK_OK",
-						fmt.Sprintf("Server on port %d is healthy", server.Port))
-				}
-			}
+			resultsMutex.Lock()
+			results[serverID] = isHealthy
+			resultsMutex.Unlock()
+		}(serverID, probe)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// StatusRefreshResult describes the outcome of re-checking a server's status
+type StatusRefreshResult struct {
+	ID            string       `json:"id"`
+	Name          string       `json:"name"`
+	Port          int          `json:"port"`
+	OldStatus     ServerStatus `json:"old_status"`
+	NewStatus     ServerStatus `json:"new_status"`
+	PIDStatus     string       `json:"pid_status"`
+	HealthzStatus string       `json:"healthz_status"`
+	Updated       bool         `json:"updated"`
+}
+
+// RefreshServerStatus re-derives a server's status from its PID and health endpoint
+func (pm *ProcessManager) RefreshServerStatus(id string) (*StatusRefreshResult, error) {
+	results := pm.refreshServersStatus([]string{id})
+	if len(results) == 0 {
+		return nil, fmt.Errorf("server not found: %s", id)
+	}
+	return results[0], nil
+}
+
+// RefreshAllServersStatus re-derives the status of every server, probing them concurrently
+func (pm *ProcessManager) RefreshAllServersStatus() []*StatusRefreshResult {
+	pm.mutex.RLock()
+	ids := make([]string, 0, len(pm.servers))
+	for id := range pm.servers {
+		ids = append(ids, id)
+	}
+	pm.mutex.RUnlock()
+
+	return pm.refreshServersStatus(ids)
+}
+
+// refreshServersStatus probes the given servers without holding the lock, then applies
+// any status changes in a single locked step. Unknown IDs are skipped.
+func (pm *ProcessManager) refreshServersStatus(ids []string) []*StatusRefreshResult {
+	pm.mutex.RLock()
+	results := make([]*StatusRefreshResult, 0, len(ids))
+	pids := make(map[string]*int, len(ids))
+	probes := make(map[string]healthProbe)
+	for _, id := range ids {
+		server, exists := pm.servers[id]
+		if !exists {
+			continue
+		}
+		results = append(results, &StatusRefreshResult{
+			ID:        id,
+			Name:      server.Name,
+			Port:      server.Port,
+			OldStatus: server.Status,
+		})
+		pids[id] = server.PID
+		if server.PID != nil {
+			probes[id] = newHealthProbe(server)
+		}
+	}
+	pm.mutex.RUnlock()
+
+	// Check HTTP health endpoints
+	healthy := pm.checkServersHealth(probes)
+
+	for _, result := range results {
+		pid := pids[result.ID]
+		// Check PID status
+		if pid == nil {
+			result.PIDStatus = "No PID"
+			result.HealthzStatus = "No process to check"
+			result.NewStatus = StatusStopped
+			continue
+		}
+
+		result.PIDStatus = fmt.Sprintf("PID %d exists", *pid)
+		if healthy[result.ID] {
+			result.HealthzStatus = fmt.Sprintf("Health endpoint responding on port %d", result.Port)
+			result.NewStatus = StatusRunning
 		} else {
-			stoppedCount++
+			result.HealthzStatus = fmt.Sprintf("Health endpoint not responding on port %d", result.Port)
+			result.NewStatus = StatusStopped
+		}
+	}
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	updated := 0
+	for _, result := range results {
+		server, exists := pm.servers[result.ID]
+		if !exists || server.Status == result.NewStatus {
+			continue
 		}
+
+		// Skip servers whose process changed while they were being probed
+		pid := pids[result.ID]
+		if (pid == nil) != (server.PID == nil) || (pid != nil && *pid != *server.PID) {
+			continue
+		}
+
+		pm.setStatus(server, result.NewStatus)
+		result.Updated = true
+		updated++
+		pm.logger.LogProcessEvent(result.ID, server.Name, "STATUS_REFRESHED",
+			fmt.Sprintf("Status updated from %s to %s (PID: %s, Health: %s)",
+				result.OldStatus, result.NewStatus, result.PIDStatus, result.HealthzStatus))
 	}
 
-	// Save updates if any servers changed status
-	if len(serversToUpdate) > 0 {
+	// Save changes if any updates
+	if updated > 0 {
 		pm.saveServers()
-		log.Printf("Health check: Updated status for %d servers that died", len(serversToUpdate))
 	}
 
-	// Log summary every 2 minutes (every 4th check)
-	if time.Now().Unix()%120 == 0 {
-		log.Printf("Health check summary: %d running, %d stopped servers", runningCount, stoppedCount)
-	}
+	return results
 }
 
 func (pm *ProcessManager) isServerHealthy(port int) bool {
@@ -1058,11 +2694,60 @@ func (pm *ProcessManager) isServerHealthy(port int) bool {
 	return healthResponse.Status == "alive"
 }
 
-// updateServerMetrics collects and updates CPU, memory, and uptime metrics for all running servers
-func (pm *ProcessManager) updateServerMetrics() {
+// processSample holds the metrics collected for a server's process
+type processSample struct {
+	pid        int
+	accessible bool // the process could be inspected
+	running    bool
+	cpuSampled bool // false on the first sample of a process, which has no window to measure over
+	cpuPercent float64
+	memoryMB   float64
+}
+
+// sampleServerProcesses collects process metrics for all running servers without holding
+// the global lock, so slow process inspection doesn't block other operations
+func (pm *ProcessManager) sampleServerProcesses() map[string]processSample {
+	pm.mutex.RLock()
+	pids := make(map[string]int)
+	launched := make(map[string]LaunchedProcess)
+	for id, server := range pm.servers {
+		if server.Status == StatusRunning && server.PID != nil && server.StartTime != nil {
+			pids[id] = *server.PID
+			launched[id] = pm.launchedProcess(id, *server.PID)
+		}
+	}
+	pm.mutex.RUnlock()
+
+	samples := make(map[string]processSample, len(pids))
+	for id, pid := range pids {
+		sample := processSample{pid: pid}
+
+		if metrics, err := sampleProcess(launched[id], pid); err == nil {
+			sample.accessible = true
+			if metrics.Running {
+				sample.running = true
+
+				// CPU usage since the previous tick, unless the backend measured it already
+				if metrics.CPUPercent != nil {
+					sample.cpuPercent, sample.cpuSampled = *metrics.CPUPercent, true
+				} else if metrics.CPUSeconds != nil {
+					sample.cpuPercent, sample.cpuSampled = pm.cpuSampler.Sample(id, pid, *metrics.CPUSeconds)
+				}
+				sample.memoryMB = metrics.MemoryMB
+			}
+		}
+		samples[id] = sample
+	}
+
+	return samples
+}
+
+// updateServerMetrics applies sampled CPU, memory, and uptime metrics to all running servers.
+// Callers must hold pm.mutex.
+func (pm *ProcessManager) updateServerMetrics(samples map[string]processSample) {
 	now := time.Now()
 
-	for _, server := range pm.servers {
+	for id, server := range pm.servers {
 		// Only update metrics for running servers with valid PID and start time
 		if server.Status != StatusRunning || server.PID == nil || server.StartTime == nil {
 			// Clear metrics for non-running servers
@@ -1070,75 +2755,85 @@ func (pm *ProcessManager) updateServerMetrics() {
 			server.CPUPercent = nil
 			server.MemoryMB = nil
 			server.LastUpdate = nil
+			pm.clearResourceAlerts(id)
+			pm.cpuSampler.Forget(id)
 			continue
 		}
 
-		// Check if process still exists and collect metrics
-		if proc, err := process.NewProcess(int32(*server.PID)); err == nil {
-			// Check if process still exists by trying to get its status
-			if exists, err := proc.IsRunning(); err == nil && exists {
-				// Calculate uptime
-				uptime := now.Sub(*server.StartTime).Seconds()
-				server.Uptime = &uptime
-
-				// Get CPU percentage
-				if cpuPercent, err := proc.CPUPercent(); err == nil {
-					server.CPUPercent = &cpuPercent
-				} else {
-					// Set to 0 if we can't get CPU data but process exists
-					zero := 0.0
-					server.CPUPercent = &zero
-				}
+		sample, sampled := samples[id]
+		if !sampled || sample.pid != *server.PID {
+			continue // Started after sampling, metrics will be collected on the next tick
+		}
 
-				// Get memory info
-				if memInfo, err := proc.MemoryInfo(); err == nil {
-					memoryMB := float64(memInfo.RSS) / 1024 / 1024
-					server.MemoryMB = &memoryMB
-				} else {
-					// Set to 0 if we can't get memory data but process exists
-					zero := 0.0
-					server.MemoryMB = &zero
-				}
+		if sample.running {
+			// Calculate uptime
+			uptime := now.Sub(*server.StartTime).Seconds()
+			server.Uptime = &uptime
 
-				// Update last update time
-				server.LastUpdate = &now
-			} else {
-				// Process doesn't exist anymore, mark as stopped and clear metrics
-				if pm.logManager != nil {
-					pm.logManager.AddServerLog("", server.Name, "WARN", "server", "Process no longer exists - marking as stopped")
-				}
-				server.Status = StatusStopped
-				server.PID = nil
-				server.StartTime = nil
-				server.Uptime = nil
-				server.CPUPercent = nil
-				server.MemoryMB = nil
-				server.LastUpdate = &now
-			}
-		} else {
-			// Can't access process, mark as stopped and clear metrics
-			if pm.logManager != nil {
-				pm.logManager.AddServerLog("", server.Name, "WARN", "server", "Cannot access process - marking as stopped")
+			// Keep the previous CPU value until a full sampling window has elapsed
+			if sample.cpuSampled {
+				cpuPercent := sample.cpuPercent
+				server.CPUPercent = &cpuPercent
 			}
-			server.Status = StatusStopped
-			server.PID = nil
-			server.StartTime = nil
-			server.Uptime = nil
-			server.CPUPercent = nil
-			server.MemoryMB = nil
+			memoryMB := sample.memoryMB
+			server.MemoryMB = &memoryMB
+
+			// Update last update time
 			server.LastUpdate = &now
+
+			var cpuPercent float64
+			if server.CPUPercent != nil {
+				cpuPercent = *server.CPUPercent
+			}
+			pm.checkResourceAlerts(server, cpuPercent, memoryMB)
+			continue
+		}
+
+		// Process doesn't exist anymore (or can't be accessed), mark as stopped and clear metrics
+		message := "Process no longer exists - marking as stopped"
+		if !sample.accessible {
+			message = "Cannot access process - marking as stopped"
 		}
+		pm.markProcessGone(server, message)
+	}
+}
+
+// markProcessGone marks a server whose process has disappeared as stopped and clears its
+// metrics. Requires pm.mutex to be held.
+func (pm *ProcessManager) markProcessGone(server *ServerInstance, message string) {
+	if pm.logManager != nil {
+		pm.logManager.AddServerLog(server.ID, server.Name, "WARN", "server", message)
 	}
+	now := time.Now()
+	pm.setStatus(server, StatusStopped)
+	server.PID = nil
+	server.StartTime = nil
+	server.Uptime = nil
+	server.CPUPercent = nil
+	server.MemoryMB = nil
+	server.LastUpdate = &now
+	pm.clearResourceAlerts(server.ID)
+	pm.cpuSampler.Forget(server.ID)
 }
 
 // Multi-step server creation methods
 func (pm *ProcessManager) CreateServerMetadata(name string) (*ServerInstance, error) {
 	// Generate unique ID and port
 	id := uuid.New().String()
-	port := pm.getNextAvailablePort()
+	port, err := pm.getNextAvailablePort()
+	if err != nil {
+		return nil, err
+	}
+
+	stored := false
+	defer func() {
+		if !stored {
+			pm.abandonCreate(id, port, true)
+		}
+	}()
 
 	// Create workspace directory
-	workspacePath := filepath.Join("workspace", id)
+	workspacePath := filepath.Join(pm.workspaceDir, id)
 	absWorkspacePath, err := filepath.Abs(workspacePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get absolute workspace path: %v", err)
@@ -1156,57 +2851,92 @@ func (pm *ProcessManager) CreateServerMetadata(name string) (*ServerInstance, er
 	}
 
 	server := &ServerInstance{
-		ID:            id,
-		Name:          name,
-		Port:          port,
-		WorkspacePath: workspacePath,
-		Extensions:    []string{},
-		Status:        StatusStopped,
-		StartTime:     nil,
-		PID:           nil,
+		ID:             id,
+		Name:           name,
+		Port:           port,
+		WorkspacePath:  workspacePath,
+		OwnedWorkspace: true,
+		Extensions:     []string{},
+		BaseExtensions: pm.inheritBaseExtensions(id),
+		Status:         StatusStopped,
+		StartTime:      nil,
+		PID:            nil,
+	}
+
+	// Extensions are installed as the server's user in later steps
+	if err := pm.handOverServerDirs(server); err != nil {
+		return nil, err
 	}
 
 	// Lock and store server
 	pm.mutex.Lock()
 	pm.servers[id] = server
 	pm.portMap[port] = id
-	pm.saveServers()
+	if err := pm.saveServers(); err != nil {
+		delete(pm.servers, id)
+		pm.mutex.Unlock()
+		return nil, err
+	}
+	stored = true
 	pm.mutex.Unlock()
 
 	pm.logger.LogProcessEvent(id, name, "METADATA_CREATED", fmt.Sprintf("Server metadata created on port %d", port))
-	log.Printf("Created server metadata %s (%s) on port %d", name, id, port)
+	pm.log.Printf("Created server metadata %s (%s) on port %d", name, id, port)
 	return server, nil
 }
 
-func (pm *ProcessManager) InstallExtensionsForServer(serverID string, extensions []string) error {
-	return pm.InstallExtensionsWithProgress(serverID, extensions, []string{}, nil)
+// InstallExtensionsForServer installs extensions plus those of the named extension groups (and the groups
+// they depend on), then applies each group's user_settings
+func (pm *ProcessManager) InstallExtensionsForServer(ctx context.Context, serverID string, extensions []string, groups []string) error {
+	config := GetConfig()
+	if err := checkExtensionGroupsExist(config.ExtensionGroups, groups); err != nil {
+		return err
+	}
+	groupExtensions, err := extensionGroupExtensions(config.ExtensionGroups, groups)
+	if err != nil {
+		return err
+	}
+
+	all := []string{}
+	for _, extension := range append(append([]string{}, extensions...), groupExtensions...) {
+		if !containsExtension(all, extension) {
+			all = append(all, extension)
+		}
+	}
+	return pm.InstallExtensionsWithProgress(ctx, serverID, all, groups, nil)
 }
 
 // InstallSingleExtension installs a single extension for a server
-func (pm *ProcessManager) InstallSingleExtension(serverID string, extension string) error {
+func (pm *ProcessManager) InstallSingleExtension(ctx context.Context, serverID string, extension string) error {
+	if err := validateExtensionRef(extension); err != nil {
+		return err
+	}
+
 	pm.mutex.RLock()
 	server, exists := pm.servers[serverID]
 	if !exists {
 		pm.mutex.RUnlock()
 		return fmt.Errorf("server not found: %s", serverID)
 	}
+	count := len(server.Extensions)
+	if !containsExtension(server.Extensions, extension) {
+		count++
+	}
 	pm.mutex.RUnlock()
+	if err := checkExtensionCount(count); err != nil {
+		return err
+	}
 
-	log.Printf("Installing single extension for server %s: %s", serverID, extension)
+	pm.log.Printf("Installing single extension for server %s: %s", serverID, extension)
 
 	// Set up environment for extension installation
-	env := os.Environ()
-	userDataDir := filepath.Join(pm.dataDir, serverID)
-	absDataDir, err := filepath.Abs(userDataDir)
-	if err != nil {
-		log.Printf("Failed to get absolute data dir path: %v", err)
-		absDataDir = userDataDir
-	}
-
-	env = append(env, fmt.Sprintf("XDG_DATA_HOME=%s", absDataDir))
+	env := pm.extensionInstallEnv(filepath.Join(pm.dataDir, serverID))
 
 	// Install the extension
-	success := pm.installExtension(env, extension, serverID, server.Name)
+	resolved, success := pm.installExtension(ctx, env, extension, serverID, server.Name)
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to install extension %s: %w", extension, err)
+	}
 	if !success {
 		return fmt.Errorf("failed to install extension: %s", extension)
 	}
@@ -1216,82 +2946,168 @@ func (pm *ProcessManager) InstallSingleExtension(serverID string, extension stri
 	if server.Extensions == nil {
 		server.Extensions = []string{}
 	}
-	// Add extension if not already present
+	// Add extension if not already present, otherwise record the newly resolved version
 	found := false
-	for _, ext := range server.Extensions {
-		if ext == extension {
+	for i, ext := range server.Extensions {
+		if extensionRefID(ext) == extensionRefID(resolved) {
+			server.Extensions[i] = resolved
 			found = true
 			break
 		}
 	}
 	if !found {
-		server.Extensions = append(server.Extensions, extension)
-		pm.saveServers()
+		server.Extensions = append(server.Extensions, resolved)
 	}
+	pm.saveServers()
 	pm.mutex.Unlock()
 
-	log.Printf("Successfully installed extension %s for server %s", extension, serverID)
+	pm.log.Printf("Successfully installed extension %s for server %s", extension, serverID)
 	return nil
 }
 
-func (pm *ProcessManager) InstallExtensionsWithProgress(serverID string, extensions []string, groupsWithUserSettings []string, onProgress func(step string, current int, total int)) error {
+// UninstallExtension removes an extension from a server's data directory and its extension list.
+// Settings written to settings.json by the extension's groups are left in place.
+func (pm *ProcessManager) UninstallExtension(ctx context.Context, serverID string, extension string) error {
 	pm.mutex.RLock()
 	server, exists := pm.servers[serverID]
 	if !exists {
 		pm.mutex.RUnlock()
 		return fmt.Errorf("server not found: %s", serverID)
 	}
+	installed := ""
+	for _, ext := range server.Extensions {
+		if extensionRefID(ext) == extensionRefID(extension) {
+			installed = ext
+			break
+		}
+	}
+	serverName := server.Name
 	pm.mutex.RUnlock()
+	if installed == "" {
+		return fmt.Errorf("%w: %s", ErrExtensionNotInstalled, extension)
+	}
 
-	if len(extensions) == 0 {
-		return nil
+	account, err := pm.serverCommandUser(serverID)
+	if err != nil {
+		return err
 	}
 
-	log.Printf("Installing extensions for server %s: %v", serverID, extensions)
+	pm.log.Printf("Uninstalling extension for server %s: %s", serverID, installed)
 
-	// Set up environment for extension installation
-	env := os.Environ()
+	timeout := GetConfig().Timeouts.ExtensionInstall()
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	// Get absolute path for XDG_DATA_HOME
-	userDataDir := filepath.Join(pm.dataDir, serverID)
-	absDataDir, err := filepath.Abs(userDataDir)
-	if err != nil {
-		log.Printf("Failed to get absolute data dir path: %v", err)
-		absDataDir = userDataDir
+	cmd := exec.CommandContext(cmdCtx, "code-server", "--uninstall-extension", extensionRefID(installed))
+	cmd.Env = pm.extensionInstallEnv(filepath.Join(pm.dataDir, serverID))
+	if err := runAs(cmd, account); err != nil {
+		return err
+	}
+	cmd.WaitDelay = 5 * time.Second
+
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	} else if cmdCtx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	// Already gone from the data directory (e.g. removed by hand); still drop it from the list
+	if err != nil && !strings.Contains(string(output), "is not installed") {
+		pm.logger.LogProcessEvent(serverID, serverName, "EXTENSION_UNINSTALL_FAILED",
+			fmt.Sprintf("Failed to uninstall %s: %v", installed, err))
+		return fmt.Errorf("failed to uninstall extension %s: %w", installed, err)
+	}
+
+	pm.mutex.Lock()
+	extensions := make([]string, 0, len(server.Extensions))
+	for _, ext := range server.Extensions {
+		if extensionRefID(ext) != extensionRefID(installed) {
+			extensions = append(extensions, ext)
+		}
 	}
+	server.Extensions = extensions
+	pm.saveServers()
+	pm.mutex.Unlock()
 
-	env = append(env,
-		fmt.Sprintf("XDG_DATA_HOME=%s", absDataDir),
-	)
+	pm.logger.LogProcessEvent(serverID, serverName, "EXTENSION_UNINSTALLED", fmt.Sprintf("Uninstalled %s", installed))
+	pm.log.Printf("Successfully uninstalled extension %s for server %s", installed, serverID)
 
-	// Install extensions one by one with progress reporting
-	successCount := 0
+	if _, groups := collectUserSettings(GetConfig(), []string{installed}); len(groups) > 0 {
+		message := fmt.Sprintf("Uninstalled %s, but settings.json still holds the user_settings of group(s) %s; remove them manually if no longer needed",
+			installed, strings.Join(groups, ", "))
+		pm.log.Printf("Warning: %s", message)
+		if pm.logManager != nil {
+			pm.logManager.AddServerLog(serverID, serverName, "WARN", "server", message)
+		}
+	}
+	return nil
+}
+
+func (pm *ProcessManager) InstallExtensionsWithProgress(ctx context.Context, serverID string, extensions []string, groupsWithUserSettings []string, onProgress func(step string, current int, total int)) error {
+	pm.mutex.RLock()
+	server, exists := pm.servers[serverID]
+	if !exists {
+		pm.mutex.RUnlock()
+		return fmt.Errorf("server not found: %s", serverID)
+	}
+	pm.mutex.RUnlock()
+
+	// Group settings are still applied when there is nothing to install
+	if len(extensions) == 0 && len(groupsWithUserSettings) == 0 {
+		return nil
+	}
+	if err := checkExtensionCount(len(extensions)); err != nil {
+		return err
+	}
+	if err := validateExtensionRefs(extensions); err != nil {
+		return err
+	}
+
+	pm.log.Printf("Installing extensions for server %s: %v", serverID, extensions)
+
+	// Set up environment for extension installation
+	env := pm.extensionInstallEnv(filepath.Join(pm.dataDir, serverID))
+
+	// Install extensions in parallel with progress reporting; steps are counted as installs start
 	totalSteps := len(extensions) + len(groupsWithUserSettings) // Extensions + user settings steps
 	currentStep := 0
+	var stepMutex sync.Mutex
 
-	for i, extension := range extensions {
+	results := pm.installExtensionBatch(ctx, env, extensions, serverID, server.Name, func(extension string) {
+		stepMutex.Lock()
+		defer stepMutex.Unlock()
 		currentStep++
+		pm.log.Printf("Installing extension %d/%d: %s", currentStep, len(extensions), extension)
 		if onProgress != nil {
 			onProgress(fmt.Sprintf("Installing extension: %s", extension), currentStep, totalSteps)
 		}
+	}, nil)
 
-		log.Printf("Installing extension %d/%d: %s", i+1, len(extensions), extension)
-
-		if pm.installExtension(env, extension, serverID, server.Name) {
+	successCount := 0
+	resolved := make([]string, 0, len(extensions))
+	for i, result := range results {
+		if result.ok {
+			resolved = append(resolved, result.resolved)
 			successCount++
 		} else {
-			log.Printf("Failed to install extension: %s", extension)
+			pm.log.Printf("Failed to install extension: %s", extensions[i])
 		}
 	}
+	// Steps of extensions skipped after an interruption still count toward the total
+	currentStep = len(extensions)
 
 	// Update server extensions list
 	pm.mutex.Lock()
 	if successCount > 0 {
-		server.Extensions = extensions
+		server.Extensions = mergeResolvedExtensions(extensions, resolved)
 		pm.saveServers()
 	}
 	pm.mutex.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("extension installation interrupted after %d/%d succeeded: %w", successCount, len(extensions), err)
+	}
+
 	// Apply user settings for each group separately with progress
 	for _, groupName := range groupsWithUserSettings {
 		currentStep++
@@ -1300,21 +3116,21 @@ func (pm *ProcessManager) InstallExtensionsWithProgress(serverID string, extensi
 		}
 
 		if err := pm.applyGroupUserSettings(serverID, groupName); err != nil {
-			log.Printf("Failed to apply user settings for group %s: %v", groupName, err)
+			pm.log.Printf("Failed to apply user settings for group %s: %v", groupName, err)
 			// Continue anyway, don't fail extension installation
 		}
 	}
 
 	if successCount == len(extensions) {
-		log.Printf("All extensions installed successfully for server %s", serverID)
+		pm.log.Printf("All extensions installed successfully for server %s", serverID)
 		return nil
 	} else {
-		log.Printf("Some extensions failed to install for server %s: %d/%d succeeded", serverID, successCount, len(extensions))
+		pm.log.Printf("Some extensions failed to install for server %s: %d/%d succeeded", serverID, successCount, len(extensions))
 		return fmt.Errorf("some extensions failed to install: %d/%d succeeded", successCount, len(extensions))
 	}
 }
 
-func (pm *ProcessManager) InitializeWorkspaceForServer(serverID, zipFilePath, githubURL string) error {
+func (pm *ProcessManager) InitializeWorkspaceForServer(ctx context.Context, serverID string, source WorkspaceSource) error {
 	pm.mutex.RLock()
 	server, exists := pm.servers[serverID]
 	if !exists {
@@ -1324,27 +3140,33 @@ func (pm *ProcessManager) InitializeWorkspaceForServer(serverID, zipFilePath, gi
 	workspacePath := server.WorkspacePath
 	pm.mutex.RUnlock()
 
-	// Initialize workspace from zip file or GitHub repository
-	if zipFilePath != "" {
-		log.Printf("Initializing workspace from zip file: %s", zipFilePath)
-		if err := pm.extractZipFile(zipFilePath, workspacePath); err != nil {
-			return fmt.Errorf("failed to initialize workspace from zip file: %v", err)
+	if err := pm.initializeWorkspace(ctx, source, workspacePath); err != nil {
+		return err
+	}
+	if account, err := pm.serverCommandUser(serverID); err != nil {
+		return err
+	} else if account != nil && pm.managesWorkspace(server) {
+		// The workspace was already handed over, so only its new contents need to be
+		entries, err := os.ReadDir(workspacePath)
+		if err != nil {
+			return err
 		}
-		log.Printf("Workspace successfully initialized from zip file for server %s", serverID)
-	} else if githubURL != "" {
-		log.Printf("Initializing workspace from GitHub repository: %s", githubURL)
-		if err := pm.cloneGithubRepo(githubURL, workspacePath); err != nil {
-			return fmt.Errorf("failed to clone GitHub repository: %v", err)
+		names := []string{"."}
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		if err := handOver(workspacePath, account, names...); err != nil {
+			return fmt.Errorf("failed to give the workspace to user %s: %v", account.Name, err)
 		}
-		log.Printf("Workspace successfully initialized from GitHub repository for server %s", serverID)
-	} else {
-		return fmt.Errorf("either zipFilePath or githubURL must be provided")
 	}
 
-	pm.logger.LogProcessEvent(serverID, server.Name, "WORKSPACE_INITIALIZED", "Workspace initialized successfully")
+	pm.logger.LogProcessEvent(serverID, server.Name, "WORKSPACE_INITIALIZED", fmt.Sprintf("Workspace initialized from %s source", source.Type))
 	return nil
 }
 
+// settingsPaths are what writing settings.json may create in a server's data directory
+var settingsPaths = []string{"code-server", "code-server/User", "code-server/User/settings.json"}
+
 // applyUserSettings merges user_settings from extension groups into VS Code settings.json
 func (pm *ProcessManager) applyUserSettings(serverID string, installedExtensions []string) error {
 	config := GetConfig()
@@ -1353,39 +3175,13 @@ func (pm *ProcessManager) applyUserSettings(serverID string, installedExtensions
 	}
 
 	// Collect user settings from all installed extension groups
-	userSettings := make(map[string]interface{})
-
-	// Map installed extensions to their extension groups
-	for groupName, group := range config.ExtensionGroups {
-		if group.UserSettings == nil || len(group.UserSettings) == 0 {
-			continue
-		}
-
-		// Check if any extensions from this group are installed
-		groupHasInstalledExtensions := false
-		for _, installedExt := range installedExtensions {
-			for _, groupExt := range group.Extensions {
-				if installedExt == groupExt {
-					groupHasInstalledExtensions = true
-					break
-				}
-			}
-			if groupHasInstalledExtensions {
-				break
-			}
-		}
-
-		// If this group has installed extensions, merge its user settings
-		if groupHasInstalledExtensions {
-			log.Printf("Applying user settings from extension group '%s' for server %s", groupName, serverID)
-			for key, value := range group.UserSettings {
-				userSettings[key] = value
-			}
-		}
+	userSettings, groups := collectUserSettings(config, installedExtensions)
+	for _, groupName := range groups {
+		pm.log.Printf("Applying user settings from extension group '%s' for server %s", groupName, serverID)
 	}
 
 	if len(userSettings) == 0 {
-		log.Printf("No user settings to apply for server %s", serverID)
+		pm.log.Printf("No user settings to apply for server %s", serverID)
 		return nil
 	}
 
@@ -1404,14 +3200,14 @@ func (pm *ProcessManager) applyUserSettings(serverID string, installedExtensions
 	existingSettings := make(map[string]interface{})
 	if data, err := os.ReadFile(settingsFile); err == nil {
 		if err := json.Unmarshal(data, &existingSettings); err != nil {
-			log.Printf("Warning: Could not parse existing settings.json for server %s: %v", serverID, err)
+			pm.log.Printf("Warning: Could not parse existing settings.json for server %s: %v", serverID, err)
 		}
 	}
 
 	// Merge user settings into existing settings (user settings take precedence)
 	for key, value := range userSettings {
 		existingSettings[key] = value
-		log.Printf("Applied setting %s = %v for server %s", key, value, serverID)
+		pm.log.Printf("Applied setting %s = %v for server %s", key, value, serverID)
 	}
 
 	// Write merged settings back to file
@@ -1423,11 +3219,59 @@ func (pm *ProcessManager) applyUserSettings(serverID string, installedExtensions
 	if err := os.WriteFile(settingsFile, data, 0644); err != nil {
 		return fmt.Errorf("failed to write settings file: %v", err)
 	}
+	if err := pm.handOverServerData(serverID, settingsPaths...); err != nil {
+		return fmt.Errorf("failed to give settings file to the server's user: %v", err)
+	}
 
-	log.Printf("Successfully applied %d user settings to %s", len(userSettings), settingsFile)
+	pm.log.Printf("Successfully applied %d user settings to %s", len(userSettings), settingsFile)
 	return nil
 }
 
+// collectUserSettings merges the user settings of every extension group with at least one
+// installed extension. Returns the settings and the names of the contributing groups, in the
+// (alphabetical) order they were merged, so the last group wins a conflicting key.
+func collectUserSettings(config *DevboxConfig, installedExtensions []string) (map[string]interface{}, []string) {
+	userSettings := make(map[string]interface{})
+	groups := []string{}
+
+	groupNames := make([]string, 0, len(config.ExtensionGroups))
+	for groupName := range config.ExtensionGroups {
+		groupNames = append(groupNames, groupName)
+	}
+	sort.Strings(groupNames)
+
+	// Map installed extensions to their extension groups
+	for _, groupName := range groupNames {
+		group := config.ExtensionGroups[groupName]
+		if group.UserSettings == nil || len(group.UserSettings) == 0 {
+			continue
+		}
+
+		// Check if any extensions from this group are installed
+		groupHasInstalledExtensions := false
+		for _, installedExt := range installedExtensions {
+			for _, groupExt := range group.Extensions {
+				if extensionRefID(installedExt) == extensionRefID(groupExt) {
+					groupHasInstalledExtensions = true
+					break
+				}
+			}
+			if groupHasInstalledExtensions {
+				break
+			}
+		}
+
+		// If this group has installed extensions, merge its user settings
+		if groupHasInstalledExtensions {
+			groups = append(groups, groupName)
+			for key, value := range group.UserSettings {
+				userSettings[key] = value
+			}
+		}
+	}
+	return userSettings, groups
+}
+
 // applyGroupUserSettings applies user settings for a specific extension group
 func (pm *ProcessManager) applyGroupUserSettings(serverID string, groupName string) error {
 	config := GetConfig()
@@ -1435,13 +3279,24 @@ func (pm *ProcessManager) applyGroupUserSettings(serverID string, groupName stri
 		return fmt.Errorf("config not available")
 	}
 
-	group, exists := config.ExtensionGroups[groupName]
-	if !exists {
+	if _, exists := config.ExtensionGroups[groupName]; !exists {
 		return fmt.Errorf("extension group %s not found", groupName)
 	}
 
-	if group.UserSettings == nil || len(group.UserSettings) == 0 {
-		log.Printf("No user settings to apply for group %s", groupName)
+	// Include the settings of groups it depends on; the group's own settings are applied last and win
+	expanded, err := expandExtensionGroups(config.ExtensionGroups, []string{groupName})
+	if err != nil {
+		return err
+	}
+	userSettings := make(map[string]interface{})
+	for _, key := range expanded {
+		for setting, value := range config.ExtensionGroups[key].UserSettings {
+			userSettings[setting] = value
+		}
+	}
+
+	if len(userSettings) == 0 {
+		pm.log.Printf("No user settings to apply for group %s", groupName)
 		return nil
 	}
 
@@ -1460,15 +3315,15 @@ func (pm *ProcessManager) applyGroupUserSettings(serverID string, groupName stri
 	existingSettings := make(map[string]interface{})
 	if data, err := os.ReadFile(settingsFile); err == nil {
 		if err := json.Unmarshal(data, &existingSettings); err != nil {
-			log.Printf("Warning: Could not parse existing settings.json for server %s: %v", serverID, err)
+			pm.log.Printf("Warning: Could not parse existing settings.json for server %s: %v", serverID, err)
 		}
 	}
 
 	// Apply user settings from this group
 	settingsApplied := 0
-	for key, value := range group.UserSettings {
+	for key, value := range userSettings {
 		existingSettings[key] = value
-		log.Printf("Applied setting %s = %v for group %s on server %s", key, value, groupName, serverID)
+		pm.log.Printf("Applied setting %s = %v for group %s on server %s", key, value, groupName, serverID)
 		settingsApplied++
 	}
 
@@ -1481,13 +3336,23 @@ func (pm *ProcessManager) applyGroupUserSettings(serverID string, groupName stri
 	if err := os.WriteFile(settingsFile, data, 0644); err != nil {
 		return fmt.Errorf("failed to write settings file: %v", err)
 	}
+	if err := pm.handOverServerData(serverID, settingsPaths...); err != nil {
+		return fmt.Errorf("failed to give settings file to the server's user: %v", err)
+	}
 
-	log.Printf("Successfully applied %d user settings for group %s to %s", settingsApplied, groupName, settingsFile)
+	pm.log.Printf("Successfully applied %d user settings for group %s to %s", settingsApplied, groupName, settingsFile)
 	return nil
 }
 
 // InitializeExtensionProgress creates initial progress tracking for extension installation
 func (pm *ProcessManager) InitializeExtensionProgress(serverID string, extensions []string) (*ExtensionInstallationProgress, error) {
+	if err := checkExtensionCount(len(extensions)); err != nil {
+		return nil, err
+	}
+	if err := validateExtensionRefs(extensions); err != nil {
+		return nil, err
+	}
+
 	pm.extensionProgressMutex.Lock()
 	defer pm.extensionProgressMutex.Unlock()
 
@@ -1518,7 +3383,7 @@ func (pm *ProcessManager) InitializeExtensionProgress(serverID string, extension
 	}
 
 	pm.extensionProgress[serverID] = progress
-	log.Printf("Initialized extension progress for server %s with %d extensions", serverID, len(extensions))
+	pm.log.Printf("Initialized extension progress for server %s with %d extensions", serverID, len(extensions))
 	return progress, nil
 }
 
@@ -1532,7 +3397,10 @@ func (pm *ProcessManager) GetExtensionProgress(serverID string) (*ExtensionInsta
 		return nil, fmt.Errorf("no extension installation progress found for server: %s", serverID)
 	}
 
-	return progress, nil
+	// Return a copy, since parallel installs keep updating the original
+	snapshot := *progress
+	snapshot.Extensions = append([]ExtensionProgress(nil), progress.Extensions...)
+	return &snapshot, nil
 }
 
 // installExtensionsProgressively installs extensions one by one with progress tracking
@@ -1550,53 +3418,47 @@ func (pm *ProcessManager) installExtensionsProgressively(serverID string, extens
 		return
 	}
 
-	log.Printf("Installing extensions progressively for server %s: %v", serverID, extensions)
+	pm.log.Printf("Installing extensions progressively for server %s: %v", serverID, extensions)
 
 	// Set up environment for extension installation
-	env := os.Environ()
-
-	// Get absolute path for XDG_DATA_HOME
-	userDataDir := filepath.Join(pm.dataDir, serverID)
-	absDataDir, err := filepath.Abs(userDataDir)
-	if err != nil {
-		log.Printf("Failed to get absolute data dir path: %v", err)
-		absDataDir = userDataDir
-	}
+	env := pm.extensionInstallEnv(filepath.Join(pm.dataDir, serverID))
 
-	env = append(env,
-		fmt.Sprintf("XDG_DATA_HOME=%s", absDataDir),
-	)
-
-	// Install extensions one by one
-	for i, extension := range extensions {
+	// Install extensions in parallel; progress is updated under extensionProgressMutex as each starts and ends
+	results := pm.installExtensionBatch(context.Background(), env, extensions, serverID, server.Name, func(extension string) {
+		pm.log.Printf("Installing extension: %s", extension)
 		pm.updateExtensionStatus(serverID, extension, ExtensionInstalling)
-
-		log.Printf("Installing extension %d/%d: %s", i+1, len(extensions), extension)
-
-		success := pm.installExtension(env, extension, serverID, server.Name)
-
-		if success {
+	}, func(extension string, result extensionInstallResult, duration time.Duration) {
+		pm.recordExtensionDuration(serverID, extension, duration)
+		if result.ok {
 			pm.updateExtensionStatus(serverID, extension, ExtensionCompleted)
 		} else {
 			pm.updateExtensionStatus(serverID, extension, ExtensionFailed)
 		}
+	})
+
+	// Every install has finished, so user settings below apply on top of all of them
+	resolved := make([]string, 0, len(extensions))
+	for _, result := range results {
+		if result.ok {
+			resolved = append(resolved, result.resolved)
+		}
 	}
 
 	// Update server extensions list with successfully installed extensions
 	pm.mutex.Lock()
 	if server, exists := pm.servers[serverID]; exists {
-		server.Extensions = extensions
+		server.Extensions = mergeResolvedExtensions(extensions, resolved)
 		pm.saveServers()
 	}
 	pm.mutex.Unlock()
 
 	// Apply user settings after extension installation
 	if err := pm.applyUserSettings(serverID, extensions); err != nil {
-		log.Printf("Failed to apply user settings for server %s: %v", serverID, err)
+		pm.log.Printf("Failed to apply user settings for server %s: %v", serverID, err)
 	}
 
 	pm.markExtensionInstallationComplete(serverID)
-	log.Printf("Extension installation completed for server %s", serverID)
+	pm.log.Printf("Extension installation completed for server %s", serverID)
 }
 
 // updateExtensionStatus updates the status of a specific extension
@@ -1620,19 +3482,56 @@ func (pm *ProcessManager) updateExtensionStatus(serverID string, extensionName s
 				progress.CurrentExtension = extensionName
 			}
 
-			// Update counters
-			if oldStatus == ExtensionPending && status == ExtensionCompleted {
-				progress.Completed++
-			} else if oldStatus == ExtensionPending && status == ExtensionFailed {
-				progress.Failed++
-			} else if oldStatus == ExtensionInstalling && status == ExtensionCompleted {
+			// Update counters, moving an extension that is installed again out of its previous
+			// outcome so it's only ever counted once
+			if oldStatus == status {
+				break
+			}
+			switch oldStatus {
+			case ExtensionCompleted:
+				progress.Completed--
+			case ExtensionFailed:
+				progress.Failed--
+			}
+			switch status {
+			case ExtensionCompleted:
 				progress.Completed++
-				progress.CurrentExtension = ""
-			} else if oldStatus == ExtensionInstalling && status == ExtensionFailed {
+			case ExtensionFailed:
 				progress.Failed++
-				progress.CurrentExtension = ""
 			}
+			if oldStatus == ExtensionInstalling {
+				progress.CurrentExtension = installingExtension(progress)
+			}
+
+			break
+		}
+	}
+}
+
+// installingExtension returns an extension still being installed, with installs running in
+// parallel, or "" if none is. Requires extensionProgressMutex to be held.
+func installingExtension(progress *ExtensionInstallationProgress) string {
+	for _, extension := range progress.Extensions {
+		if extension.Status == ExtensionInstalling {
+			return extension.Name
+		}
+	}
+	return ""
+}
+
+// recordExtensionDuration stores how long a specific extension took to install
+func (pm *ProcessManager) recordExtensionDuration(serverID string, extensionName string, duration time.Duration) {
+	pm.extensionProgressMutex.Lock()
+	defer pm.extensionProgressMutex.Unlock()
+
+	progress, exists := pm.extensionProgress[serverID]
+	if !exists {
+		return
+	}
 
+	for i := range progress.Extensions {
+		if progress.Extensions[i].Name == extensionName {
+			progress.Extensions[i].DurationMs = duration.Milliseconds()
 			break
 		}
 	}
@@ -1651,6 +3550,17 @@ func (pm *ProcessManager) markExtensionInstallationComplete(serverID string) {
 	progress.IsComplete = true
 	progress.CurrentExtension = ""
 
-	log.Printf("Extension installation marked as complete for server %s: %d completed, %d failed",
+	pm.log.Printf("Extension installation marked as complete for server %s: %d completed, %d failed",
 		serverID, progress.Completed, progress.Failed)
+
+	// Surface the slowest extension so install times can be tuned
+	var slowest *ExtensionProgress
+	for i := range progress.Extensions {
+		if slowest == nil || progress.Extensions[i].DurationMs > slowest.DurationMs {
+			slowest = &progress.Extensions[i]
+		}
+	}
+	if slowest != nil && slowest.DurationMs > 0 {
+		pm.log.Printf("Slowest extension for server %s: %s (%dms)", serverID, slowest.Name, slowest.DurationMs)
+	}
 }
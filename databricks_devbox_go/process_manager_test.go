@@ -0,0 +1,432 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// Tests share the default config; it is set once, before any manager's routines read it
+	globalConfig.Store(getDefaultConfig())
+	os.Exit(m.Run())
+}
+
+// newTestProcessManager returns a manager that keeps its state in memory, launches nothing and
+// keeps data, logs and workspaces in a temporary directory. It is closed when the test ends.
+func newTestProcessManager(t *testing.T) *ProcessManager {
+	t.Helper()
+	pm, err := NewProcessManager(nil, WithBaseDir(t.TempDir()), WithStateStore(NewMemoryStateStore()), WithProcessLauncher(NewFakeProcessLauncher()))
+	if err != nil {
+		t.Fatalf("NewProcessManager: %v", err)
+	}
+	t.Cleanup(pm.Close)
+	return pm
+}
+
+// testLauncher returns the fake launcher a test manager starts its servers with
+func testLauncher(pm *ProcessManager) *FakeProcessLauncher {
+	return pm.launchers[BackendProcess].(*FakeProcessLauncher)
+}
+
+// zipEntry is a file or, when link is set, a symlink to write into a test archive
+type zipEntry struct {
+	name    string
+	content string
+	link    bool
+}
+
+func writeTestZip(t *testing.T, path string, entries []zipEntry) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	writer := zip.NewWriter(file)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		if entry.link {
+			header.SetMode(os.ModeSymlink | 0777)
+		} else {
+			header.SetMode(0644)
+		}
+		w, err := writer.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tarEntry is a regular file, or a symlink or hard link when typeflag says so, to write into a test archive
+type tarEntry struct {
+	name     string
+	typeflag byte
+	content  string // File content, or the link target
+}
+
+func writeTestTarGz(t *testing.T, path string, entries []tarEntry) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	writer := tar.NewWriter(gzipWriter)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Typeflag: entry.typeflag, Mode: 0644}
+		if entry.typeflag == tar.TypeReg {
+			header.Size = int64(len(entry.content))
+		} else {
+			header.Linkname = entry.content
+		}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if entry.typeflag == tar.TypeReg {
+			if _, err := writer.Write([]byte(entry.content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newExtractTarget returns an archive path and an empty workspace two levels below the test
+// directory, so entries that escape have somewhere to land
+func newExtractTarget(t *testing.T) (archive, workspace string) {
+	t.Helper()
+	base := t.TempDir()
+	workspace = filepath.Join(base, "workspace", "server")
+	if err := os.MkdirAll(workspace, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(base, "upload.zip"), workspace
+}
+
+func TestExtractZipSkipsTraversal(t *testing.T) {
+	pm := newTestProcessManager(t)
+	archive, workspace := newExtractTarget(t)
+	writeTestZip(t, archive, []zipEntry{
+		{name: "../../etc/x", content: "escaped"},
+		{name: "/abs/x", content: "escaped"},
+		{name: "ok/file.txt", content: "kept"},
+	})
+
+	if err := pm.extractArchive(archive, workspace); err != nil {
+		t.Fatalf("extractArchive: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(workspace, "..", "..", "etc", "x")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("../../etc/x was extracted outside the workspace (stat err %v)", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(workspace, "ok", "file.txt")); err != nil || string(data) != "kept" {
+		t.Errorf("ok/file.txt = %q, %v; want the file extracted", data, err)
+	}
+}
+
+func TestExtractZipSizeLimit(t *testing.T) {
+	pm := newTestProcessManager(t)
+	archive, workspace := newExtractTarget(t)
+	writeTestZip(t, archive, []zipEntry{
+		{name: "big.bin", content: string(make([]byte, 2<<20))},
+	})
+
+	if err := pm.extractArchiveWithLimit(archive, workspace, 1); !errors.Is(err, ErrArchiveTooLarge) {
+		t.Fatalf("extractArchive error = %v, want ErrArchiveTooLarge", err)
+	}
+}
+
+func TestExtractZipSymlinkChain(t *testing.T) {
+	pm := newTestProcessManager(t)
+	archive, workspace := newExtractTarget(t)
+	writeTestZip(t, archive, []zipEntry{
+		// d points at the workspace, so d/x -> .. would really point at its parent
+		{name: "d", content: ".", link: true},
+		{name: "d/x", content: "..", link: true},
+		{name: "x/evil", content: "escaped"},
+		// a -> s/.. looks harmless until s -> . is created after it
+		{name: "a", content: "s/..", link: true},
+		{name: "s", content: ".", link: true},
+	})
+
+	if err := pm.extractArchive(archive, workspace); err != nil {
+		t.Fatalf("extractArchive: %v", err)
+	}
+
+	parent := filepath.Dir(workspace)
+	if _, err := os.Lstat(filepath.Join(parent, "evil")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("x/evil was written to the workspace's parent (stat err %v)", err)
+	}
+	if target, err := os.Readlink(filepath.Join(workspace, "x")); err == nil {
+		t.Errorf("x is a symlink to %q, want it skipped", target)
+	}
+	if _, err := os.Lstat(filepath.Join(workspace, "a")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("a -> s/.. was kept although it resolves outside the workspace (stat err %v)", err)
+	}
+	if target, err := os.Readlink(filepath.Join(workspace, "d")); err != nil || target != "." {
+		t.Errorf("d -> %q, %v; want the link to the workspace kept", target, err)
+	}
+}
+
+func TestExtractTarGzSymlinkChain(t *testing.T) {
+	pm := newTestProcessManager(t)
+	zipPath, workspace := newExtractTarget(t)
+	archive := filepath.Join(filepath.Dir(zipPath), "upload.tar.gz")
+	writeTestTarGz(t, archive, []tarEntry{
+		{name: "../../etc/x", typeflag: tar.TypeReg, content: "escaped"},
+		{name: "d", typeflag: tar.TypeSymlink, content: "."},
+		{name: "d/x", typeflag: tar.TypeSymlink, content: ".."},
+		{name: "x/evil", typeflag: tar.TypeReg, content: "escaped"},
+		{name: "hard", typeflag: tar.TypeLink, content: "x/evil"},
+	})
+
+	if err := pm.extractArchive(archive, workspace); err != nil {
+		t.Fatalf("extractArchive: %v", err)
+	}
+
+	parent := filepath.Dir(workspace)
+	for _, path := range []string{filepath.Join(parent, "evil"), filepath.Join(parent, "..", "etc", "x")} {
+		if _, err := os.Lstat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s was written outside the workspace (stat err %v)", path, err)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(workspace, "hard")); err != nil || string(data) != "escaped" {
+		t.Errorf("hard = %q, %v; want a hard link to x/evil inside the workspace", data, err)
+	}
+}
+
+func TestPortsOfDeletedServersAreReused(t *testing.T) {
+	pm := newTestProcessManager(t)
+	ctx := context.Background()
+
+	var servers []*ServerInstance
+	for i := range 10 {
+		server, err := pm.CreateServer(ctx, fmt.Sprintf("server-%d", i), "", nil, nil, "", 0)
+		if err != nil {
+			t.Fatalf("CreateServer %d: %v", i, err)
+		}
+		servers = append(servers, server)
+	}
+
+	freed := map[int]bool{}
+	for _, server := range servers[:5] {
+		if err := pm.DeleteServer(server.ID, DefaultDeleteOptions()); err != nil {
+			t.Fatalf("DeleteServer %s: %v", server.Name, err)
+		}
+		freed[server.Port] = true
+	}
+
+	for i := range 5 {
+		server, err := pm.CreateServer(ctx, fmt.Sprintf("replacement-%d", i), "", nil, nil, "", 0)
+		if err != nil {
+			t.Fatalf("CreateServer replacement %d: %v", i, err)
+		}
+		if !freed[server.Port] {
+			t.Errorf("replacement-%d got port %d, want one of the freed ports %v", i, server.Port, freed)
+		}
+		delete(freed, server.Port)
+	}
+}
+
+func TestFailedCreateReleasesItsPort(t *testing.T) {
+	pm := newTestProcessManager(t)
+	archive, _ := newExtractTarget(t)
+	if err := os.WriteFile(archive, []byte("not a zip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	port, err := pm.peekNextAvailablePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := &WorkspaceSource{Type: "zip", Location: archive}
+	if _, err := pm.CreateServer(context.Background(), "broken", "", nil, source, "", 0); err == nil {
+		t.Fatal("CreateServer from a corrupt archive succeeded, want an error")
+	}
+
+	if next, err := pm.peekNextAvailablePort(); err != nil || next != port {
+		t.Errorf("next free port after a failed create = %d, %v; want %d given back", next, err, port)
+	}
+	for _, dir := range []string{pm.workspaceDir, pm.dataDir} {
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if entry.IsDir() {
+				t.Errorf("failed create left %s behind", filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+}
+
+func TestStopHoldsServerUntilProcessExits(t *testing.T) {
+	pm := newTestProcessManager(t)
+	server, err := pm.CreateServer(context.Background(), "slow-stop", "", nil, nil, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pm.StartServer(server.ID); err != nil {
+		t.Fatal(err)
+	}
+	running, _ := pm.GetServer(server.ID)
+	proc := testLauncher(pm).Process(*running.PID)
+	proc.IgnoreTerm()
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- pm.StopServer(server.ID) }()
+
+	// While the old process ignores SIGTERM, a start must not launch a second one next to it
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := pm.StartServer(server.ID)
+		if errors.Is(err, ErrServerBusy) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("StartServer during a stop = %v, want ErrServerBusy", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case err := <-stopped:
+		t.Fatalf("StopServer returned (%v) before the process exited", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	proc.Exit(nil)
+	if err := <-stopped; err != nil {
+		t.Fatalf("StopServer: %v", err)
+	}
+	if err := pm.StartServer(server.ID); err != nil {
+		t.Fatalf("StartServer after the stop: %v", err)
+	}
+	if launched := len(testLauncher(pm).Commands()); launched != 2 {
+		t.Errorf("launched %d processes, want 2", launched)
+	}
+}
+
+func TestStartRelaunchesOnlyWhenPortWasInUse(t *testing.T) {
+	pm := newTestProcessManager(t)
+	launcher := testLauncher(pm)
+	server, err := pm.CreateServer(context.Background(), "bind-retry", "", nil, nil, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A healthy launch returns at once instead of being watched for an early exit
+	began := time.Now()
+	if err := pm.StartServer(server.ID); err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(began); took >= bindCheckWindow {
+		t.Errorf("StartServer took %s, want it to return without waiting out the bind check", took)
+	}
+	if err := pm.StopServer(server.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// A launch that reports its address in use is relaunched once it has exited
+	launcher.ExitNextWith("Error: listen EADDRINUSE: address already in use 0.0.0.0:8010\n", errors.New("exit status 1"))
+	if err := pm.StartServer(server.ID); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(launcher.Commands()) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("server was launched %d times, want a relaunch after the failed bind", len(launcher.Commands()))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		pm.mutex.RLock()
+		status := server.Status
+		pm.mutex.RUnlock()
+		if status == StatusRunning {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("relaunched server has status %s, want running", status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// Other early exits are left to the restart policy
+	if err := pm.StopServer(server.ID); err != nil {
+		t.Fatal(err)
+	}
+	launcher.ExitNextWith("Error: Cannot find module 'vscode'\n", errors.New("exit status 1"))
+	if err := pm.StartServer(server.ID); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(bindRetryDelay + 500*time.Millisecond)
+	if launched := len(launcher.Commands()); launched != 4 {
+		t.Errorf("server was launched %d times, want no relaunch after an unrelated failure", launched)
+	}
+}
+
+func TestValidateExtensionRef(t *testing.T) {
+	for ref, valid := range map[string]bool{
+		"ms-python.python":                     true,
+		"ms-python.python@2024.2.1":            true,
+		"redhat.vscode-yaml@1.15.0-beta+build": true,
+		"https://example.com/ext.vsix":         true,
+		"/tmp/uploads/ext-1.0.0.vsix":          true,
+		"":                                     false,
+		"python":                               false,
+		".python":                              false,
+		"--force":                              false,
+		"--install-extension=evil.vsix":        false,
+		"ms-python.python@":                    false,
+		"ms-python.python;rm -rf /":            false,
+		"ms python.python":                     false,
+		"https://example.com/a b.vsix":         false,
+		"evil.vsix\n--force":                   false,
+	} {
+		err := validateExtensionRef(ref)
+		if valid && err != nil {
+			t.Errorf("validateExtensionRef(%q) = %v, want it accepted", ref, err)
+		}
+		if !valid && !errors.Is(err, ErrInvalidExtension) {
+			t.Errorf("validateExtensionRef(%q) = %v, want ErrInvalidExtension", ref, err)
+		}
+	}
+}
+
+func TestCreateServerRejectsInvalidExtensionsBeforeReserving(t *testing.T) {
+	pm := newTestProcessManager(t)
+	port, err := pm.peekNextAvailablePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = pm.CreateServer(context.Background(), "bad-extension", "", []string{"ms-python.python", "--force"}, nil, "", 0)
+	if !errors.Is(err, ErrInvalidExtension) {
+		t.Fatalf("CreateServer with an option as extension = %v, want ErrInvalidExtension", err)
+	}
+	if next, _ := pm.peekNextAvailablePort(); next != port {
+		t.Errorf("next free port = %d after a rejected create, want %d", next, port)
+	}
+	if servers := pm.ListServers(); len(servers) != 0 {
+		t.Errorf("rejected create left %d servers", len(servers))
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// processTreeSampleWindow is how long CPU usage is measured over for a process breakdown
+const processTreeSampleWindow = 500 * time.Millisecond
+
+// ErrNotServerProcess is returned when a PID is not a descendant of the server's process
+var ErrNotServerProcess = errors.New("process does not belong to server")
+
+// ServerProcess describes one process in a server's process tree
+type ServerProcess struct {
+	PID        int32   `json:"pid"`
+	PPID       int32   `json:"ppid"`
+	Name       string  `json:"name"`
+	Cmdline    string  `json:"cmdline"`
+	CPUPercent float64 `json:"cpu_percent"` // Usage over processTreeSampleWindow, as a percentage of one core
+	MemoryMB   float64 `json:"memory_mb"`
+}
+
+// GetServerProcesses lists the server's root process and all of its descendants (extension
+// host, language servers, terminals...) with their CPU and memory usage, busiest first
+func (pm *ProcessManager) GetServerProcesses(id string) ([]ServerProcess, error) {
+	pm.mutex.RLock()
+	server, exists := pm.servers[id]
+	if !exists {
+		pm.mutex.RUnlock()
+		return nil, fmt.Errorf("server not found: %s", id)
+	}
+	if server.Status != StatusRunning || server.PID == nil {
+		pm.mutex.RUnlock()
+		return nil, fmt.Errorf("server is not running")
+	}
+	pid := *server.PID
+	pm.mutex.RUnlock()
+
+	root, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach to process %d: %v", pid, err)
+	}
+	procs := append([]*process.Process{root}, processDescendants(root)...)
+
+	// Measure every process over the same window
+	before := make(map[int32]float64, len(procs))
+	for _, proc := range procs {
+		if times, err := proc.Times(); err == nil {
+			before[proc.Pid] = times.User + times.System
+		}
+	}
+	start := time.Now()
+	time.Sleep(processTreeSampleWindow)
+	elapsed := time.Since(start).Seconds()
+
+	result := make([]ServerProcess, 0, len(procs))
+	for _, proc := range procs {
+		info := ServerProcess{PID: proc.Pid}
+		if running, err := proc.IsRunning(); err != nil || !running {
+			continue // Exited during the sampling window
+		}
+		if ppid, err := proc.Ppid(); err == nil {
+			info.PPID = ppid
+		}
+		if name, err := proc.Name(); err == nil {
+			info.Name = name
+		}
+		if cmdline, err := proc.Cmdline(); err == nil {
+			info.Cmdline = cmdline
+		}
+		if times, err := proc.Times(); err == nil {
+			if previous, sampled := before[proc.Pid]; sampled {
+				info.CPUPercent = max((times.User+times.System-previous)/elapsed*100, 0)
+			}
+		}
+		if memInfo, err := proc.MemoryInfo(); err == nil {
+			info.MemoryMB = float64(memInfo.RSS) / 1024 / 1024
+		}
+		result = append(result, info)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].CPUPercent > result[j].CPUPercent })
+	return result, nil
+}
+
+// KillServerProcess kills one descendant of the server's process, e.g. a runaway language server.
+// The server's main process itself is refused; use stop or restart for that.
+func (pm *ProcessManager) KillServerProcess(id string, pid int32) error {
+	pm.mutex.RLock()
+	server, exists := pm.servers[id]
+	if !exists {
+		pm.mutex.RUnlock()
+		return fmt.Errorf("server not found: %s", id)
+	}
+	if server.Status != StatusRunning || server.PID == nil {
+		pm.mutex.RUnlock()
+		return fmt.Errorf("server is not running")
+	}
+	rootPID := int32(*server.PID)
+	name := server.Name
+	pm.mutex.RUnlock()
+
+	if pid == rootPID {
+		return fmt.Errorf("%w: %d is the server's main process, stop or restart the server instead", ErrNotServerProcess, pid)
+	}
+
+	root, err := process.NewProcess(rootPID)
+	if err != nil {
+		return fmt.Errorf("failed to attach to process %d: %v", rootPID, err)
+	}
+
+	// Only kill the PID if it is currently part of this server's process tree
+	var target *process.Process
+	for _, proc := range processDescendants(root) {
+		if proc.Pid == pid {
+			target = proc
+			break
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("%w: %d is not a child of server %s", ErrNotServerProcess, pid, id)
+	}
+
+	processName, _ := target.Name()
+	if err := target.Kill(); err != nil {
+		return fmt.Errorf("failed to kill process %d: %v", pid, err)
+	}
+
+	message := fmt.Sprintf("Killed child process %s (PID %d)", processName, pid)
+	pm.log.Printf("Server %s: %s", name, message)
+	pm.logger.LogProcessEvent(id, name, "CHILD_PROCESS_KILLED", message)
+	if pm.logManager != nil {
+		pm.logManager.AddServerLog(id, name, "WARN", "server", message)
+	}
+	return nil
+}
+
+// processDescendants returns all children of proc, recursively
+func processDescendants(proc *process.Process) []*process.Process {
+	children, err := proc.Children()
+	if err != nil {
+		return nil // No children (gopsutil reports that as an error)
+	}
+
+	descendants := []*process.Process{}
+	for _, child := range children {
+		descendants = append(descendants, child)
+		descendants = append(descendants, processDescendants(child)...)
+	}
+	return descendants
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// startTestProcess runs a real command for the test, killing it at cleanup
+func startTestProcess(t *testing.T, name string, args ...string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	return cmd
+}
+
+func TestKillServerProcessOnlyKillsDescendants(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the process tree is built with sh")
+	}
+	pm := newTestProcessManager(t)
+	server, err := pm.CreateServer(context.Background(), "tree", "", nil, nil, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The server's main process with one child, and a process outside its tree
+	root := startTestProcess(t, "sh", "-c", "sleep 30 & wait")
+	unrelated := startTestProcess(t, "sleep", "30")
+	rootPID := root.Process.Pid
+	pm.mutex.Lock()
+	pm.servers[server.ID].Status = StatusRunning
+	pm.servers[server.ID].PID = &rootPID
+	pm.mutex.Unlock()
+
+	var child *process.Process
+	for deadline := time.Now().Add(5 * time.Second); child == nil; {
+		rootProc, err := process.NewProcess(int32(rootPID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if descendants := processDescendants(rootProc); len(descendants) > 0 {
+			child = descendants[0]
+		} else if time.Now().After(deadline) {
+			t.Fatal("the server's child process never appeared")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := pm.KillServerProcess(server.ID, int32(unrelated.Process.Pid)); !errors.Is(err, ErrNotServerProcess) {
+		t.Errorf("killing a process outside the server's tree = %v, want ErrNotServerProcess", err)
+	}
+	if err := pm.KillServerProcess(server.ID, int32(rootPID)); !errors.Is(err, ErrNotServerProcess) {
+		t.Errorf("killing the server's main process = %v, want ErrNotServerProcess", err)
+	}
+	for _, pid := range []int{unrelated.Process.Pid, rootPID} {
+		if processGone(osProcessMetrics(pid)) {
+			t.Errorf("refused kill still ended process %d", pid)
+		}
+	}
+
+	if err := pm.KillServerProcess(server.ID, child.Pid); err != nil {
+		t.Fatalf("killing the server's child: %v", err)
+	}
+	// The shell reaps its child and exits once it is gone
+	exited := make(chan struct{})
+	go func() {
+		root.Wait()
+		close(exited)
+	}()
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Error("the server's child process was not killed")
+	}
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net"
 	"net/http"
 	"net/http/httputil"
@@ -15,10 +17,17 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins for development
-	},
+// newUpgrader builds a WebSocket upgrader from the configured buffer and compression settings
+func newUpgrader(settings WebSocketSettings, subprotocols []string) websocket.Upgrader {
+	return websocket.Upgrader{
+		ReadBufferSize:    settings.ReadBufferSize,
+		WriteBufferSize:   settings.WriteBufferSize,
+		EnableCompression: settings.Compression(),
+		Subprotocols:      subprotocols,
+		CheckOrigin: func(r *http.Request) bool {
+			return true // Allow all origins for development
+		},
+	}
 }
 
 // coalesce returns the first non-empty string from the given arguments
@@ -31,6 +40,23 @@ func coalesce(values ...string) string {
 	return ""
 }
 
+// limitWebSocketReads caps the size of a single message read from each connection
+func limitWebSocketReads(conns ...*websocket.Conn) {
+	limit := GetConfig().Proxy.MaxWebSocketMessageBytes()
+	for _, conn := range conns {
+		conn.SetReadLimit(limit)
+	}
+}
+
+// closeWebSocketTooBig tells both ends the relay is closing because a message exceeded the read limit
+func closeWebSocketTooBig(conns ...*websocket.Conn) {
+	message := websocket.FormatCloseMessage(websocket.CloseMessageTooBig, "message exceeds proxy size limit")
+	deadline := time.Now().Add(time.Second)
+	for _, conn := range conns {
+		conn.WriteControl(websocket.CloseMessage, message, deadline)
+	}
+}
+
 func proxyToCodeServer(pm *ProcessManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		portStr := c.Param("port")
@@ -63,6 +89,12 @@ func proxyToCodeServer(pm *ProcessManager) gin.HandlerFunc {
 		} else {
 			fmt.Printf("DEBUG: Attempting to proxy to server - %s on port %d, status: %s\n", server.Name, server.Port, server.Status)
 		}
+		// The manager's own health checks don't keep a server from being stopped as idle
+		if isHealthProbe(c.Request) {
+			c.Request.Header.Del(healthProbeHeader)
+		} else {
+			pm.RecordAccess(port)
+		}
 
 		// Note: We no longer check server status here - let the proxy attempt to connect
 		// and provide a clear error if the backend isn't responding
@@ -79,6 +111,7 @@ func proxyToCodeServer(pm *ProcessManager) gin.HandlerFunc {
 
 					if isWebSocketRequest(c.Request) {
 						fmt.Printf("DEBUG: Streamlit WebSocket request, connecting directly to port %d, path: %s\n", streamlitPort, streamlitPath)
+						defer pm.trackConnection(port)()
 						handleStreamlitWebSocketProxy(c, streamlitPort, streamlitPath)
 						return
 					} else {
@@ -93,6 +126,7 @@ func proxyToCodeServer(pm *ProcessManager) gin.HandlerFunc {
 		// Check if this is a WebSocket upgrade request
 		if isWebSocketRequest(c.Request) {
 			fmt.Printf("DEBUG: WebSocket request detected\n")
+			defer pm.trackConnection(port)()
 			handleWebSocketProxy(c, port)
 			return
 		}
@@ -144,30 +178,20 @@ func handleWebSocketProxy(c *gin.Context, targetPort int) {
 		headers.Set("Origin", "http://localhost:"+strconv.Itoa(targetPort))
 		// Note: Don't set Sec-Websocket-Protocol and Sec-Websocket-Extensions manually
 		// as the Go websocket library manages these automatically
-		if cookie := c.Request.Header.Get("Cookie"); cookie != "" {
+		if cookie := c.Request.Header.Get("Cookie"); cookie != "" && proxyHeaderAllowed(GetConfig().Proxy, "Cookie") {
 			headers.Set("Cookie", cookie)
 		}
-		if userAgent := c.Request.Header.Get("User-Agent"); userAgent != "" {
+		if userAgent := c.Request.Header.Get("User-Agent"); userAgent != "" && proxyHeaderAllowed(GetConfig().Proxy, "User-Agent") {
 			headers.Set("User-Agent", userAgent)
 		}
 	}
 
 	// Create upgrader - use enhanced version for Streamlit, basic for others
-	var clientUpgrader websocket.Upgrader
+	var subprotocols []string
 	if isStreamlitPath {
-		clientUpgrader = websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true
-			},
-			Subprotocols: websocket.Subprotocols(c.Request),
-		}
-	} else {
-		clientUpgrader = websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true
-			},
-		}
+		subprotocols = websocket.Subprotocols(c.Request)
 	}
+	clientUpgrader := newUpgrader(GetConfig().WebSocket.Proxy, subprotocols)
 
 	// Upgrade the client connection
 	clientConn, err := clientUpgrader.Upgrade(c.Writer, c.Request, nil)
@@ -195,6 +219,8 @@ func handleWebSocketProxy(c *gin.Context, targetPort int) {
 
 	fmt.Printf("DEBUG WS PROXY: Successfully connected to target WebSocket (Streamlit-enhanced: %v)\n", isStreamlitPath)
 
+	limitWebSocketReads(clientConn, targetConn)
+
 	// Proxy messages bidirectionally
 	done := make(chan struct{})
 	var closeOnce sync.Once
@@ -205,6 +231,11 @@ func handleWebSocketProxy(c *gin.Context, targetPort int) {
 		for {
 			messageType, message, err := clientConn.ReadMessage()
 			if err != nil {
+				if errors.Is(err, websocket.ErrReadLimit) {
+					log.Printf("Warning: WebSocket proxy message from client exceeds size limit, closing")
+					closeWebSocketTooBig(clientConn, targetConn)
+					return
+				}
 				if !websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 					fmt.Printf("DEBUG WS PROXY: Client connection closed normally\n")
 				} else {
@@ -225,6 +256,11 @@ func handleWebSocketProxy(c *gin.Context, targetPort int) {
 		for {
 			messageType, message, err := targetConn.ReadMessage()
 			if err != nil {
+				if errors.Is(err, websocket.ErrReadLimit) {
+					log.Printf("Warning: WebSocket proxy message from target exceeds size limit, closing")
+					closeWebSocketTooBig(clientConn, targetConn)
+					return
+				}
 				if !websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 					fmt.Printf("DEBUG WS PROXY: Target connection closed normally\n")
 				} else {
@@ -295,10 +331,14 @@ func handleHTTPProxy(c *gin.Context, targetPort int) {
 			scheme = "https"
 		}
 
+		// Drop inbound headers that aren't allowed through (proxy config in devbox.yaml)
+		proxyConfig := GetConfig().Proxy
+		filterProxyHeaders(req.Header, proxyConfig)
+		stripUntrustedIdentity(req.Header, c.Request.RemoteAddr, proxyConfig)
+
 		// Set critical nginx-style proxy headers for WebSocket support
-		req.Header.Set("X-Forwarded-For", coalesce(c.Request.Header.Get("X-Forwarded-For"), c.ClientIP()))
-		req.Header.Set("X-Forwarded-Host", coalesce(c.Request.Header.Get("X-Forwarded-Host"), c.Request.Host))
-		req.Header.Set("X-Forwarded-Preferred-Username", c.Request.Header.Get("X-Forwarded-Preferred-Username"))
+		req.Header.Set("X-Forwarded-For", coalesce(req.Header.Get("X-Forwarded-For"), c.ClientIP()))
+		req.Header.Set("X-Forwarded-Host", coalesce(req.Header.Get("X-Forwarded-Host"), c.Request.Host))
 		req.Header.Set("X-Forwarded-Proto", scheme)
 		req.Header.Set("Host", target.Host)
 
@@ -345,20 +385,15 @@ func handleStreamlitWebSocketProxy(c *gin.Context, targetPort int, targetPath st
 	headers := http.Header{}
 	// For localhost backend connections, use localhost origin to avoid CORS issues
 	headers.Set("Origin", "http://localhost:"+strconv.Itoa(targetPort))
-	if cookie := c.Request.Header.Get("Cookie"); cookie != "" {
+	if cookie := c.Request.Header.Get("Cookie"); cookie != "" && proxyHeaderAllowed(GetConfig().Proxy, "Cookie") {
 		headers.Set("Cookie", cookie)
 	}
-	if userAgent := c.Request.Header.Get("User-Agent"); userAgent != "" {
+	if userAgent := c.Request.Header.Get("User-Agent"); userAgent != "" && proxyHeaderAllowed(GetConfig().Proxy, "User-Agent") {
 		headers.Set("User-Agent", userAgent)
 	}
 
 	// Create upgrader with Streamlit subprotocol support
-	clientUpgrader := websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true
-		},
-		Subprotocols: websocket.Subprotocols(c.Request),
-	}
+	clientUpgrader := newUpgrader(GetConfig().WebSocket.Proxy, websocket.Subprotocols(c.Request))
 
 	// Upgrade the client connection
 	clientConn, err := clientUpgrader.Upgrade(c.Writer, c.Request, nil)
@@ -386,6 +421,8 @@ func handleStreamlitWebSocketProxy(c *gin.Context, targetPort int, targetPath st
 
 	fmt.Printf("DEBUG STREAMLIT WS: Successfully connected to Streamlit WebSocket\n")
 
+	limitWebSocketReads(clientConn, targetConn)
+
 	// Proxy messages bidirectionally
 	done := make(chan struct{})
 	var closeOnce sync.Once
@@ -396,6 +433,11 @@ func handleStreamlitWebSocketProxy(c *gin.Context, targetPort int, targetPath st
 		for {
 			messageType, message, err := clientConn.ReadMessage()
 			if err != nil {
+				if errors.Is(err, websocket.ErrReadLimit) {
+					log.Printf("Warning: Streamlit WebSocket message from client exceeds size limit, closing")
+					closeWebSocketTooBig(clientConn, targetConn)
+					return
+				}
 				if !websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 					fmt.Printf("DEBUG STREAMLIT WS: Client connection closed normally\n")
 				} else {
@@ -416,6 +458,11 @@ func handleStreamlitWebSocketProxy(c *gin.Context, targetPort int, targetPath st
 		for {
 			messageType, message, err := targetConn.ReadMessage()
 			if err != nil {
+				if errors.Is(err, websocket.ErrReadLimit) {
+					log.Printf("Warning: Streamlit WebSocket message from Streamlit exceeds size limit, closing")
+					closeWebSocketTooBig(clientConn, targetConn)
+					return
+				}
 				if !websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 					fmt.Printf("DEBUG STREAMLIT WS: Streamlit connection closed normally\n")
 				} else {
@@ -483,10 +530,14 @@ func handleStreamlitHTTPProxy(c *gin.Context, targetPort int, targetPath string)
 			scheme = "https"
 		}
 
+		// Drop inbound headers that aren't allowed through (proxy config in devbox.yaml)
+		proxyConfig := GetConfig().Proxy
+		filterProxyHeaders(req.Header, proxyConfig)
+		stripUntrustedIdentity(req.Header, c.Request.RemoteAddr, proxyConfig)
+
 		// Set nginx-style proxy headers
-		req.Header.Set("X-Forwarded-For", coalesce(c.Request.Header.Get("X-Forwarded-For"), c.ClientIP()))
-		req.Header.Set("X-Forwarded-Host", coalesce(c.Request.Header.Get("X-Forwarded-Host"), c.Request.Host))
-		req.Header.Set("X-Forwarded-Preferred-Username", c.Request.Header.Get("X-Forwarded-Preferred-Username"))
+		req.Header.Set("X-Forwarded-For", coalesce(req.Header.Get("X-Forwarded-For"), c.ClientIP()))
+		req.Header.Set("X-Forwarded-Host", coalesce(req.Header.Get("X-Forwarded-Host"), c.Request.Host))
 		req.Header.Set("X-Forwarded-Proto", scheme)
 		req.Header.Set("Host", target.Host)
 
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// identityHeaders carry the authenticated user as asserted by the upstream proxy (e.g. Databricks Apps)
+var identityHeaders = []string{
+	"X-Forwarded-Preferred-Username",
+	"X-Forwarded-User",
+	"X-Forwarded-Email",
+}
+
+// protocolHeaders are always forwarded, even with an allowlist, since requests can't be proxied without them
+var protocolHeaders = map[string]bool{
+	"Connection":        true,
+	"Upgrade":           true,
+	"Content-Type":      true,
+	"Content-Length":    true,
+	"Content-Encoding":  true,
+	"Transfer-Encoding": true,
+}
+
+// proxyHeaderAllowed reports whether an inbound header may be forwarded to a server, using the
+// proxy.strip_headers denylist and proxy.allow_headers allowlist of config (empty allows all)
+func proxyHeaderAllowed(config ProxyConfig, name string) bool {
+	name = http.CanonicalHeaderKey(name)
+
+	for _, stripped := range config.StripHeaders {
+		if stripped == name {
+			return false
+		}
+	}
+	if len(config.AllowHeaders) == 0 || protocolHeaders[name] || strings.HasPrefix(name, "Sec-Websocket-") {
+		return true
+	}
+	for _, allowed := range config.AllowHeaders {
+		if allowed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// filterProxyHeaders removes inbound headers that config doesn't allow to be forwarded to a server
+func filterProxyHeaders(header http.Header, config ProxyConfig) {
+	for name := range header {
+		if !proxyHeaderAllowed(config, name) {
+			header.Del(name)
+		}
+	}
+}
+
+// parseTrustedProxy parses a trusted proxy entry, either a CIDR or a bare IP
+func parseTrustedProxy(entry string) (*net.IPNet, error) {
+	entry = strings.TrimSpace(entry)
+	if strings.Contains(entry, "/") {
+		_, network, err := net.ParseCIDR(entry)
+		return network, err
+	}
+
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil, fmt.Errorf("not an IP address or CIDR")
+	}
+	bits := 8 * net.IPv4len
+	if ip.To4() == nil {
+		bits = 8 * net.IPv6len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// isTrustedProxy reports whether the direct peer of a request is one of config's trusted proxies.
+// remoteAddr is the connection address, never X-Forwarded-For, which the client controls.
+func isTrustedProxy(config ProxyConfig, remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, entry := range config.TrustedProxies {
+		network, err := parseTrustedProxy(entry)
+		if err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripUntrustedIdentity removes identity headers unless the request came from a trusted proxy,
+// so clients can't claim to be another user by setting them directly
+func stripUntrustedIdentity(header http.Header, remoteAddr string, config ProxyConfig) {
+	if isTrustedProxy(config, remoteAddr) {
+		return
+	}
+	for _, name := range identityHeaders {
+		header.Del(name)
+	}
+}
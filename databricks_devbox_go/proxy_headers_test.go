@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestFilterProxyHeaders(t *testing.T) {
+	config := ProxyConfig{
+		AllowHeaders: []string{"Cookie", "Accept"},
+		StripHeaders: []string{"Cookie", "X-Forwarded-User"},
+	}
+	header := http.Header{}
+	for _, name := range []string{"Cookie", "Accept", "Authorization", "X-Forwarded-User", "Content-Type", "Upgrade", "Sec-Websocket-Key"} {
+		header.Set(name, "value")
+	}
+
+	filterProxyHeaders(header, config)
+
+	// The denylist wins over the allowlist; protocol headers pass without being allowed
+	for name, want := range map[string]bool{
+		"Cookie":            false,
+		"Accept":            true,
+		"Authorization":     false,
+		"X-Forwarded-User":  false,
+		"Content-Type":      true,
+		"Upgrade":           true,
+		"Sec-Websocket-Key": true,
+	} {
+		if got := header.Get(name) != ""; got != want {
+			t.Errorf("%s forwarded = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestStripUntrustedIdentity(t *testing.T) {
+	config := ProxyConfig{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.7"}}
+	for _, tc := range []struct {
+		remoteAddr string
+		kept       bool
+	}{
+		{"10.1.2.3:41000", true},
+		{"192.168.1.7:41000", true},
+		{"192.168.1.8:41000", false},
+		{"[::1]:41000", false},
+		{"not-an-address", false},
+	} {
+		header := http.Header{}
+		for _, name := range identityHeaders {
+			header.Set(name, "someone@example.com")
+		}
+		header.Set("X-Other", "kept")
+
+		stripUntrustedIdentity(header, tc.remoteAddr, config)
+
+		for _, name := range identityHeaders {
+			if kept := header.Get(name) != ""; kept != tc.kept {
+				t.Errorf("from %s: %s kept = %v, want %v", tc.remoteAddr, name, kept, tc.kept)
+			}
+		}
+		if header.Get("X-Other") != "kept" {
+			t.Errorf("from %s: unrelated header was removed", tc.remoteAddr)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+// listenerPort returns the port of a test server's URL
+func listenerPort(t *testing.T, rawURL string) int {
+	t.Helper()
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(parsed.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return port
+}
+
+// newTestUpstream serves handler as a stand-in for a server's editor, returning its port
+func newTestUpstream(t *testing.T, handler http.HandlerFunc) int {
+	t.Helper()
+	upstream := httptest.NewServer(handler)
+	t.Cleanup(upstream.Close)
+	return listenerPort(t, upstream.URL)
+}
+
+// newTestProxy serves a test manager's routes on a real listener, since the reverse proxy
+// needs a connection it can close-notify on
+func newTestProxy(t *testing.T, pm *ProcessManager) string {
+	t.Helper()
+	proxy := httptest.NewServer(newTestRouter(pm))
+	t.Cleanup(proxy.Close)
+	return proxy.URL
+}
+
+func TestProxyForwardsToServerPort(t *testing.T) {
+	pm := newTestProcessManager(t)
+	port := newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.Path+"?"+r.URL.RawQuery)
+	})
+	proxyURL := newTestProxy(t, pm)
+
+	resp, err := http.Get(fmt.Sprintf("%s/vscode/%d/static/app.js?v=1", proxyURL, port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK || string(body) != "/static/app.js?v=1" {
+		t.Errorf("proxied response = %d %q, want 200 with the path after /vscode/{port}", resp.StatusCode, body)
+	}
+}
+
+func TestProxyReportsUnreachableServer(t *testing.T) {
+	pm := newTestProcessManager(t)
+	proxyURL := newTestProxy(t, pm)
+
+	// Nothing listens on a closed server's port any more
+	closed := httptest.NewServer(http.NotFoundHandler())
+	port := listenerPort(t, closed.URL)
+	closed.Close()
+
+	resp, err := http.Get(fmt.Sprintf("%s/vscode/%d/", proxyURL, port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("proxying to a closed port = %d, want 502", resp.StatusCode)
+	}
+}
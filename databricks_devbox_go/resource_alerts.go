@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+)
+
+// resourceAlertClearRatio is the fraction of a threshold usage must drop below before an
+// active alert clears, so values hovering around the threshold don't re-alert on every tick
+const resourceAlertClearRatio = 0.9
+
+// resourceAlertState tracks which resource alerts are currently active for a server
+type resourceAlertState struct {
+	cpu    bool
+	memory bool
+}
+
+// checkResourceAlerts compares a server's latest metrics against the configured thresholds,
+// logging a WARN when one is first exceeded and an INFO once usage has recovered.
+// Callers must hold pm.mutex.
+func (pm *ProcessManager) checkResourceAlerts(server *ServerInstance, cpuPercent, memoryMB float64) {
+	thresholds := GetConfig().Alerts
+	if thresholds.CPUPercent <= 0 && thresholds.MemoryMB <= 0 {
+		return
+	}
+
+	state, exists := pm.resourceAlerts[server.ID]
+	if !exists {
+		state = &resourceAlertState{}
+		pm.resourceAlerts[server.ID] = state
+	}
+
+	state.cpu = pm.evaluateResourceAlert(server, "CPU", state.cpu, cpuPercent, thresholds.CPUPercent, "%.1f%%")
+	state.memory = pm.evaluateResourceAlert(server, "memory", state.memory, memoryMB, thresholds.MemoryMB, "%.0fMB")
+}
+
+// evaluateResourceAlert applies the threshold with hysteresis and returns whether the alert is active
+func (pm *ProcessManager) evaluateResourceAlert(server *ServerInstance, resource string, active bool, value, threshold float64, format string) bool {
+	if threshold <= 0 {
+		return false
+	}
+
+	switch {
+	case !active && value > threshold:
+		message := fmt.Sprintf("%s usage "+format+" exceeds threshold "+format, resource, value, threshold)
+		pm.log.Printf("Resource alert for server %s: %s", server.Name, message)
+		pm.logger.LogProcessEvent(server.ID, server.Name, "RESOURCE_ALERT", message)
+		if pm.logManager != nil {
+			pm.logManager.AddServerLog(server.ID, server.Name, "WARN", "server", message)
+		}
+		return true
+	case active && value < threshold*resourceAlertClearRatio:
+		message := fmt.Sprintf("%s usage "+format+" back below threshold "+format, resource, value, threshold)
+		pm.logger.LogProcessEvent(server.ID, server.Name, "RESOURCE_ALERT_CLEARED", message)
+		if pm.logManager != nil {
+			pm.logManager.AddServerLog(server.ID, server.Name, "INFO", "server", message)
+		}
+		return false
+	default:
+		return active
+	}
+}
+
+// clearResourceAlerts forgets alert state for a server that is no longer running.
+// Callers must hold pm.mutex.
+func (pm *ProcessManager) clearResourceAlerts(id string) {
+	delete(pm.resourceAlerts, id)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+)
+
+// ResourceLimits are optional per-server limits applied to the launched code-server process
+type ResourceLimits struct {
+	Nice     *int    `json:"nice,omitempty"`      // Scheduling niceness, -20 (highest priority) to 19 (lowest)
+	CPUCores float64 `json:"cpu_cores,omitempty"` // cgroup CPU limit in cores (Linux only)
+	MemoryMB int     `json:"memory_mb,omitempty"` // cgroup memory limit (Linux only)
+}
+
+// Validate checks that the limits are within their allowed ranges
+func (l *ResourceLimits) Validate() error {
+	if l.Nice != nil && (*l.Nice < -20 || *l.Nice > 19) {
+		return fmt.Errorf("nice must be between -20 and 19, got %d", *l.Nice)
+	}
+	if l.CPUCores < 0 {
+		return fmt.Errorf("cpu_cores must not be negative")
+	}
+	if l.MemoryMB < 0 {
+		return fmt.Errorf("memory_mb must not be negative")
+	}
+	return nil
+}
+
+// String describes the limits for logging
+func (l *ResourceLimits) String() string {
+	if l.isEmpty() {
+		return "none"
+	}
+	nice := "unset"
+	if l.Nice != nil {
+		nice = fmt.Sprintf("%d", *l.Nice)
+	}
+	return fmt.Sprintf("nice=%s cpu_cores=%g memory_mb=%d", nice, l.CPUCores, l.MemoryMB)
+}
+
+// isEmpty reports whether no limit is set
+func (l *ResourceLimits) isEmpty() bool {
+	return l == nil || (l.Nice == nil && l.CPUCores == 0 && l.MemoryMB == 0)
+}
+
+// SetResourceLimits stores a server's resource limits, applying them immediately if it is running.
+// Passing empty limits clears them; running processes keep their current limits until restarted.
+func (pm *ProcessManager) SetResourceLimits(id string, limits *ResourceLimits) (*ServerInstance, error) {
+	pm.mutex.Lock()
+	server, exists := pm.servers[id]
+	if !exists {
+		pm.mutex.Unlock()
+		return nil, fmt.Errorf("server not found: %s", id)
+	}
+
+	if limits.isEmpty() {
+		server.ResourceLimits = nil
+	} else {
+		server.ResourceLimits = limits
+	}
+	pm.saveServers()
+
+	var pid int
+	running := server.Status == StatusRunning && server.PID != nil
+	if running {
+		pid = *server.PID
+	}
+	name := server.Name
+	pm.mutex.Unlock()
+
+	pm.logger.LogProcessEvent(id, name, "RESOURCE_LIMITS_UPDATED", limits.String())
+	if running && !limits.isEmpty() {
+		pm.applyResourceLimits(id, name, pid, limits)
+	}
+
+	return server, nil
+}
+
+// applyResourceLimits applies niceness and cgroup limits to a launched process. Limits are
+// best-effort: failures (e.g. missing privileges or cgroup v2) are logged and the server keeps running.
+func (pm *ProcessManager) applyResourceLimits(id, name string, pid int, limits *ResourceLimits) {
+	if limits.isEmpty() {
+		return
+	}
+
+	if limits.Nice != nil {
+		if err := setProcessNice(pid, *limits.Nice); err != nil {
+			pm.log.Printf("Failed to set niceness %d for server %s (PID %d): %v", *limits.Nice, name, pid, err)
+			pm.logger.LogProcessEvent(id, name, "RESOURCE_LIMITS_FAILED", fmt.Sprintf("niceness: %v", err))
+		} else {
+			pm.log.Printf("Set niceness %d for server %s (PID %d)", *limits.Nice, name, pid)
+		}
+	}
+
+	if limits.CPUCores > 0 || limits.MemoryMB > 0 {
+		if err := applyCgroupLimits(id, pid, limits); err != nil {
+			pm.log.Printf("Failed to apply cgroup limits for server %s (PID %d): %v", name, pid, err)
+			pm.logger.LogProcessEvent(id, name, "RESOURCE_LIMITS_FAILED", fmt.Sprintf("cgroup: %v", err))
+		} else {
+			pm.log.Printf("Applied cgroup limits for server %s (PID %d): %s", name, pid, limits)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "syscall"
+
+// setProcessNice sets the scheduling niceness of a process
+func setProcessNice(pid, nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice)
+}
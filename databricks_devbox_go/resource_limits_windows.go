@@ -0,0 +1,8 @@
+package main
+
+import "fmt"
+
+// setProcessNice is not supported on Windows
+func setProcessNice(pid, nice int) error {
+	return fmt.Errorf("process niceness is not supported on windows")
+}
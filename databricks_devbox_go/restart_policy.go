@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// RestartPolicy decides whether a server whose process exits on its own is started again
+type RestartPolicy string
+
+const (
+	RestartNever     RestartPolicy = "never"
+	RestartOnFailure RestartPolicy = "on-failure" // Only after a non-zero exit or a crash
+	RestartAlways    RestartPolicy = "always"
+)
+
+// restartBackoffBase is the delay before the first automatic restart; it doubles per attempt
+const restartBackoffBase = time.Second
+
+// restartStableAfter is how long a server must run before a crash starts the attempt count over
+const restartStableAfter = 10 * time.Minute
+
+// Validate checks that the policy is one of the known values (empty means never)
+func (p RestartPolicy) Validate() error {
+	switch p {
+	case "", RestartNever, RestartOnFailure, RestartAlways:
+		return nil
+	default:
+		return fmt.Errorf("unknown restart policy %q (expected never, on-failure or always)", p)
+	}
+}
+
+// restartsAfter reports whether the policy restarts a process that exited with exitErr
+func (p RestartPolicy) restartsAfter(exitErr error) bool {
+	switch p {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return exitErr != nil
+	default:
+		return false
+	}
+}
+
+// restartBackoff returns the delay before automatic restart attempt n (1-based), doubling
+// from restartBackoffBase and capped at server.restart_backoff_max_seconds
+func restartBackoff(attempt int) time.Duration {
+	maxDelay := GetConfig().Server.RestartBackoffMax()
+	delay := restartBackoffBase
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// SetRestartPolicy changes how a server is supervised when its process exits on its own
+func (pm *ProcessManager) SetRestartPolicy(id string, policy RestartPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	server, exists := pm.servers[id]
+	if !exists {
+		return fmt.Errorf("server not found: %s", id)
+	}
+
+	if policy == RestartNever {
+		policy = ""
+	}
+	server.RestartPolicy = policy
+	server.RestartCount = 0
+	pm.logger.LogProcessEvent(id, server.Name, "RESTART_POLICY_UPDATED", fmt.Sprintf("Restart policy set to %s", server.restartPolicy()))
+	pm.saveServers()
+	return nil
+}
+
+// restartPolicy returns the server's policy, defaulting to never
+func (s *ServerInstance) restartPolicy() RestartPolicy {
+	if s.RestartPolicy == "" {
+		return RestartNever
+	}
+	return s.RestartPolicy
+}
+
+// nextRestartAttempt counts another automatic restart of server, returning its attempt number,
+// or false once server.restart_max_attempts is used up. Callers must hold pm.mutex.
+func (pm *ProcessManager) nextRestartAttempt(server *ServerInstance) (int, bool) {
+	if server.RestartCount >= GetConfig().Server.RestartMaxAttempts {
+		message := fmt.Sprintf("Giving up after %d automatic restarts", server.RestartCount)
+		pm.logger.LogProcessEvent(server.ID, server.Name, "RESTART_GAVE_UP", message)
+		if pm.logManager != nil {
+			pm.logManager.AddServerLog(server.ID, server.Name, "ERROR", "server", message)
+		}
+		return 0, false
+	}
+	server.RestartCount++
+	pm.saveServers()
+	return server.RestartCount, true
+}
+
+// cancelAutoRestart resets a server's restart count, so a pending automatic restart is dropped
+// when the user starts or stops the server themselves
+func (pm *ProcessManager) cancelAutoRestart(id string) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	if server, exists := pm.servers[id]; exists && server.RestartCount != 0 {
+		server.RestartCount = 0
+		pm.saveServers()
+	}
+}
+
+// superviseRestart starts a crashed server again after an exponential backoff, retrying failed
+// starts until the attempts run out. It gives up quietly if the server was deleted, started or
+// stopped by someone else, or had its policy changed in the meantime.
+func (pm *ProcessManager) superviseRestart(id string, attempt int) {
+	for {
+		delay := restartBackoff(attempt)
+		pm.mutex.RLock()
+		server, exists := pm.servers[id]
+		if !exists {
+			pm.mutex.RUnlock()
+			return
+		}
+		name := server.Name
+		pm.mutex.RUnlock()
+
+		message := fmt.Sprintf("Restarting in %s (attempt %d/%d)", delay, attempt, GetConfig().Server.RestartMaxAttempts)
+		pm.logger.LogProcessEvent(id, name, "RESTART_SCHEDULED", message)
+		if pm.logManager != nil {
+			pm.logManager.AddServerLog(id, name, "WARN", "server", message)
+		}
+		select {
+		case <-pm.done:
+			return
+		case <-time.After(delay):
+		}
+
+		pm.mutex.RLock()
+		server, exists = pm.servers[id]
+		wanted := exists && server.Status != StatusRunning && server.RestartCount == attempt && server.restartPolicy() != RestartNever
+		pm.mutex.RUnlock()
+		if !wanted {
+			return
+		}
+
+		if err := pm.beginOperation(id, "restart"); err != nil {
+			return // Someone else is operating on the server
+		}
+		err := pm.startServer(id)
+		pm.endOperation(id)
+		if err == nil {
+			pm.logger.LogProcessEvent(id, name, "AUTO_RESTARTED", fmt.Sprintf("Restarted after attempt %d", attempt))
+			return
+		}
+		pm.logger.LogProcessEvent(id, name, "AUTO_RESTART_FAILED", err.Error())
+
+		pm.mutex.Lock()
+		server, exists = pm.servers[id]
+		ok := false
+		if exists && server.RestartCount == attempt {
+			attempt, ok = pm.nextRestartAttempt(server)
+		}
+		pm.mutex.Unlock()
+		if !ok {
+			return
+		}
+	}
+}
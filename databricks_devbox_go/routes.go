@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"net/http"
@@ -9,13 +11,52 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type CreateServerRequest struct {
-	Name       string   `json:"name" binding:"required"`
-	Extensions []string `json:"extensions"`
+	Name       string           `json:"name" binding:"required"`
+	Extensions []string         `json:"extensions"`
+	Runtime    string           `json:"runtime"`   // Runtime key from config; empty means code-server
+	Workspace  *WorkspaceSource `json:"workspace"` // Optional workspace initializer source
+	// PostInitCommand overrides the configured server.post_init_command
+	PostInitCommand string `json:"post_init_command"`
+	// WorkspacePath adopts an existing absolute directory as the workspace instead of creating one;
+	// a .code-workspace file adopts its directory and opens the multi-root workspace it describes
+	WorkspacePath string `json:"workspace_path"`
+	// ExtraArgs are additional editor flags, e.g. ["--locale", "de"]
+	ExtraArgs []string `json:"extra_args"`
+	// Port requests a specific port within server.code_server_port_range instead of the next free one
+	Port int `json:"port"`
+}
+
+// UpdateServerRequest holds the per-server settings that can be changed with PATCH /servers/:id;
+// omitted fields are left unchanged
+type UpdateServerRequest struct {
+	ResourceLimits *ResourceLimits `json:"resource_limits"`
+	Runtime        *string         `json:"runtime"`
+	Hooks          *LifecycleHooks `json:"hooks"` // Replaces the server's hooks; an empty object restores the defaults
+	ExtraArgs      *[]string       `json:"extra_args"`
+	EditorFlags    *EditorFlags    `json:"editor_flags"`   // Replaces the server's overrides; {} follows the config again
+	RestartPolicy  *RestartPolicy  `json:"restart_policy"` // never, on-failure or always
+	Backend        *string         `json:"backend"`        // process or docker; "" follows server.backend
+	RunAsUser      *string         `json:"run_as_user"`    // OS user the server runs as; "" follows server.run_as_user
+	WorkspaceFile  *string         `json:"workspace_file"` // .code-workspace file relative to the workspace; "" opens the directory
+	OpenPath       *string         `json:"open_path"`      // File relative to the workspace opened in the editor; "" clears it
+}
+
+// CommitWorkspaceRequest is the body of POST /servers/:id/git/commit
+type CommitWorkspaceRequest struct {
+	Message string `json:"message" binding:"required"`
+	Push    bool   `json:"push"`
+}
+
+// MaintenanceRequest is the body of POST /maintenance
+type MaintenanceRequest struct {
+	Enabled *bool  `json:"enabled" binding:"required"`
+	Message string `json:"message"`
 }
 
 type CreateServerFromTemplateRequest struct {
@@ -24,6 +65,15 @@ type CreateServerFromTemplateRequest struct {
 	TabName    string `json:"tab_name" binding:"required"`
 }
 
+// setupProxyRoutes registers the code-server proxy endpoints, either alongside the API or on
+// their own listener (DEVBOX_PROXY_PORT)
+func setupProxyRoutes(r *gin.Engine, pm *ProcessManager) {
+	// Proxied editor connections outlive the server's read and write timeouts
+	noDeadline := NoDeadlineMiddleware()
+	r.Any("/vscode/:port/*path", noDeadline, proxyToCodeServer(pm))
+	r.Any("/vscode/:port", noDeadline, proxyToCodeServer(pm))
+}
+
 func setupRoutes(r *gin.Engine, pm *ProcessManager, lm *LogManager) {
 	// Health check
 	r.GET("/health", func(c *gin.Context) {
@@ -35,43 +85,89 @@ func setupRoutes(r *gin.Engine, pm *ProcessManager, lm *LogManager) {
 
 	// Configuration endpoint
 	r.GET("/config", getConfig())
+	r.GET("/config/ui", getUIConfig())
 
 	// Templates endpoint
 	r.GET("/templates", getTemplates())
 
+	// Long-running synchronous endpoints are bounded by the configured operation timeout
+	withTimeout := OperationTimeoutMiddleware()
+	// Streaming endpoints outlive the server's read and write timeouts
+	noDeadline := NoDeadlineMiddleware()
+
+	// Mutating endpoints are refused while maintenance mode is on
+	blocked := MaintenanceMiddleware(pm)
+	r.GET("/maintenance", getMaintenance(pm))
+	r.POST("/maintenance", setMaintenance(pm))
+
+	// Shared base extensions inherited by newly created servers
+	r.GET("/extensions/seed", getBaseExtensions(pm))
+	r.POST("/extensions/seed", blocked, withTimeout, seedBaseExtensions(pm))
+	r.GET("/extensions/search", searchExtensions())
+
 	// Server management endpoints
 	r.GET("/servers", listServers(pm))
-	r.POST("/servers", createServer(pm))
-	r.POST("/servers/create-with-workspace", createServerWithWorkspace(pm))
-	r.POST("/servers/create-from-template", createServerFromTemplate(pm))
+	r.GET("/servers/defaults", getServerDefaults())
+	r.POST("/servers", blocked, withTimeout, createServer(pm))
+	r.POST("/servers/create-with-workspace", blocked, withTimeout, createServerWithWorkspace(pm))
+	r.POST("/servers/create-from-template", blocked, withTimeout, createServerFromTemplate(pm))
 
 	// Multi-step server creation endpoints
-	r.POST("/servers/create-metadata", createServerMetadata(pm))
-	r.POST("/servers/:id/install-extensions", installServerExtensions(pm))
-	r.POST("/servers/:id/install-extension", installSingleExtension(pm))
-	r.POST("/servers/:id/apply-group-settings", applyGroupSettings(pm))
-	r.POST("/servers/:id/clone-workspace", cloneServerWorkspace(pm))
-
-	r.POST("/servers/:id/start", startServer(pm))
-	r.POST("/servers/:id/stop", stopServer(pm))
-	r.POST("/servers/:id/restart", restartServer(pm))
-	r.DELETE("/servers/:id", deleteServer(pm))
+	r.POST("/servers/create-metadata", blocked, createServerMetadata(pm))
+	r.POST("/servers/plan", planServer(pm))
+	r.GET("/servers/export", exportServers(pm))
+	r.POST("/servers/import", blocked, withTimeout, importServers(pm))
+	r.POST("/servers/snapshot", snapshotServers(pm))
+	r.POST("/servers/:id/install-extensions", blocked, withTimeout, installServerExtensions(pm))
+	r.POST("/servers/:id/install-extension", blocked, withTimeout, installSingleExtension(pm))
+	r.POST("/servers/:id/install-vsix", blocked, withTimeout, installVSIX(pm))
+	r.GET("/servers/:id/extensions", withTimeout, getInstalledExtensions(pm))
+	r.DELETE("/servers/:id/extensions/:ext", blocked, withTimeout, uninstallExtension(pm))
+	r.POST("/servers/:id/apply-group-settings", blocked, applyGroupSettings(pm))
+	r.POST("/servers/:id/clone-workspace", blocked, withTimeout, cloneServerWorkspace(pm))
+
+	r.POST("/servers/:id/start", blocked, startServer(pm))
+	r.POST("/servers/:id/stop", blocked, stopServer(pm))
+	r.POST("/servers/:id/restart", blocked, restartServer(pm))
+	r.POST("/servers/:id/reset-config", blocked, withTimeout, resetServerConfig(pm))
+	r.GET("/servers/:id", getServer(pm))
+	r.PATCH("/servers/:id", blocked, updateServer(pm))
+	r.DELETE("/servers/:id", blocked, deleteServer(pm))
 	r.GET("/servers/:id/health", getServerHealth(pm))
+	r.GET("/servers/:id/describe", describeServer(pm))
+	r.GET("/servers/:id/processes", getServerProcesses(pm))
+	r.POST("/servers/:id/processes/:pid/kill", blocked, killServerProcess(pm))
+	r.GET("/servers/:id/git/status", getWorkspaceGitStatus(pm))
+	r.POST("/servers/:id/git/commit", blocked, withTimeout, commitWorkspace(pm))
 	r.GET("/servers/:id/logs", getServerLogs(pm))
+	r.GET("/servers/:id/logs/combined", getCombinedServerLogs(pm))
 	r.POST("/servers/:id/refresh-status", refreshServerStatus(pm))
 	r.POST("/servers/refresh-all", refreshAllServersStatus(pm))
 
+	// Port allocation within server.code_server_port_range
+	r.GET("/ports", getPorts(pm))
+
+	// In-memory log buffer, optionally filtered with ?source=stdout,stderr
+	r.GET("/logs", getLogs(lm))
+	r.GET("/logs/:serverId", getLogs(lm))
+
 	// WebSocket endpoint for real-time logs
-	r.GET("/ws/logs", func(c *gin.Context) {
+	r.GET("/ws/logs", noDeadline, func(c *gin.Context) {
 		lm.HandleWebSocket(c.Writer, c.Request)
 	})
-	r.GET("/ws/logs/:serverId", func(c *gin.Context) {
+	r.GET("/ws/logs/:serverId", noDeadline, func(c *gin.Context) {
 		lm.HandleWebSocket(c.Writer, c.Request)
 	})
 
-	// Proxy endpoints for code-server
-	r.Any("/vscode/:port/*path", proxyToCodeServer(pm))
-	r.Any("/vscode/:port", proxyToCodeServer(pm))
+	// WebSocket endpoint following a server's on-disk process log
+	r.GET("/ws/logs/:serverId/tail", noDeadline, func(c *gin.Context) {
+		lines, err := logLinesParam(c, 100)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		pm.HandleLogTailWebSocket(c.Writer, c.Request, c.Param("serverId"), lines)
+	})
 
 	// Create a sub-filesystem for the web UI assets directory
 	assetsSubFS, _ := fs.Sub(webUIFS, "web_ui_dist/assets")
@@ -111,10 +207,46 @@ func setupRoutes(r *gin.Engine, pm *ProcessManager, lm *LogManager) {
 	})
 }
 
+// errorStatus maps process manager errors to the HTTP status returned to clients
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrServerBusy):
+		return http.StatusConflict
+	case errors.Is(err, ErrMemoryBudgetExceeded), errors.Is(err, ErrPortRangeExhausted), errors.Is(err, ErrCodeServerNotFound):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrNotServerProcess):
+		return http.StatusForbidden
+	case errors.Is(err, ErrNotGitRepository), errors.Is(err, ErrInvalidPort), errors.Is(err, ErrTooManyExtensions),
+		errors.Is(err, ErrInvalidExtension), errors.Is(err, ErrGitRefNotFound), errors.Is(err, ErrUnknownExtensionGroup),
+		errors.Is(err, ErrInvalidCloneOptions):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrPortInUse):
+		return http.StatusConflict
+	case errors.Is(err, ErrExtensionNotInstalled):
+		return http.StatusNotFound
+	case errors.Is(err, ErrArchiveTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func listServers(pm *ProcessManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		servers := pm.ListServers()
-		c.JSON(http.StatusOK, servers)
+		responses := newServerResponses(servers)
+
+		// Optionally inline the latest lifecycle event and health snapshot, e.g. ?include=health,events
+		if include := c.Query("include"); include != "" {
+			includes := parseIncludes(include)
+			for _, response := range responses {
+				pm.addIncludes(response, includes)
+			}
+		}
+
+		c.JSON(http.StatusOK, responses)
 	}
 }
 
@@ -136,124 +268,705 @@ func createServerWithWorkspace(pm *ProcessManager) gin.HandlerFunc {
 			}
 		}
 
-		githubURL := c.PostForm("github_url")
-		zipFilePath := ""
+		source, cleanup, err := workspaceSourceFromForm(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer cleanup()
+
+		port := 0
+		if portStr := c.PostForm("port"); portStr != "" {
+			if port, err = strconv.Atoi(portStr); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid port: " + portStr})
+				return
+			}
+		}
+
+		server, err := pm.CreateServer(c.Request.Context(), name, "", extensions, source, c.PostForm("post_init_command"), port)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, newCreatedServerResponse(server, extensions))
+	}
+}
+
+// workspaceSourceFromForm reads a workspace source from multipart form data: an uploaded
+// zip_file (.zip or .tar.gz), a github_url, or a generic source_type/source_location pair for custom initializers.
+// Returns nil when no source was given; cleanup removes any uploaded temporary file.
+func workspaceSourceFromForm(c *gin.Context) (*WorkspaceSource, func(), error) {
+	cleanup := func() {}
+
+	// Handle file upload if present
+	if file, err := c.FormFile("zip_file"); err == nil {
+		// Save uploaded file to temporary location
+		tempFile := filepath.Join(os.TempDir(), file.Filename)
+		if err := c.SaveUploadedFile(file, tempFile); err != nil {
+			return nil, cleanup, fmt.Errorf("failed to save uploaded file")
+		}
+		return &WorkspaceSource{Type: "zip", Location: tempFile}, func() { os.Remove(tempFile) }, nil
+	}
+
+	if githubURL := c.PostForm("github_url"); githubURL != "" {
+		source := &WorkspaceSource{Type: "github", Location: githubURL}
+		// Optional github_branch/github_depth narrow the clone
+		for field, option := range map[string]string{"github_branch": "branch", "github_depth": "depth"} {
+			if value := c.PostForm(field); value != "" {
+				if source.Options == nil {
+					source.Options = make(map[string]string)
+				}
+				source.Options[option] = value
+			}
+		}
+		return source, cleanup, nil
+	}
+
+	if sourceType := c.PostForm("source_type"); sourceType != "" {
+		source := &WorkspaceSource{Type: sourceType, Location: c.PostForm("source_location")}
+		if err := validateRequestedWorkspaceSource(*source); err != nil {
+			return nil, cleanup, err
+		}
+		if optionsStr := c.PostForm("source_options"); optionsStr != "" {
+			if err := json.Unmarshal([]byte(optionsStr), &source.Options); err != nil {
+				return nil, cleanup, fmt.Errorf("invalid source_options format: %v", err)
+			}
+		}
+		return source, cleanup, nil
+	}
+
+	return nil, cleanup, nil
+}
+
+// exportServers returns portable definitions of all servers for backup or migration
+func exportServers(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, pm.ExportServers())
+	}
+}
+
+// getMaintenance reports whether maintenance mode is on
+func getMaintenance(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   pm.Maintenance(),
+		})
+	}
+}
+
+// setMaintenance turns maintenance mode on or off
+func setMaintenance(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MaintenanceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   pm.SetMaintenance(*req.Enabled, req.Message),
+		})
+	}
+}
+
+// getPorts lists used and free ports, e.g. to debug port exhaustion
+func getPorts(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   pm.PortUsage(),
+		})
+	}
+}
+
+// snapshotServers writes a timestamped copy of servers.json, e.g. before a bulk delete
+func snapshotServers(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path, err := pm.SnapshotServers()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   gin.H{"path": path},
+		})
+	}
+}
+
+// importServers recreates servers from an export, allocating fresh IDs and ports
+func importServers(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ServersExport
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Version != serverExportVersion {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported export version %d (expected %d)", req.Version, serverExportVersion)})
+			return
+		}
+
+		results, err := pm.ImportServers(c.Request.Context(), req)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error(), "data": results})
+			return
+		}
+
+		imported := 0
+		for _, result := range results {
+			if result.Error == "" {
+				imported++
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": fmt.Sprintf("Imported %d/%d servers", imported, len(results)),
+			"data":    results,
+		})
+	}
+}
+
+// planServer resolves a create request (port, extensions, settings, init source) without executing it
+func planServer(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req PlanServerRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		plan, err := pm.PlanServer(req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Server creation plan",
+			"data":    plan,
+		})
+	}
+}
+
+func createServer(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateServerRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if _, err := GetRuntime(req.Runtime); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := ValidateExtraArgs(req.ExtraArgs); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Workspace != nil {
+			if err := validateRequestedWorkspaceSource(*req.Workspace); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.WorkspacePath != "" {
+			if err := validateAdoptedWorkspace(req.WorkspacePath); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if req.Workspace != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_path can't be combined with a workspace source"})
+				return
+			}
+		}
+
+		server, err := pm.CreateServer(c.Request.Context(), req.Name, req.WorkspacePath, req.Extensions, req.Workspace, req.PostInitCommand, req.Port)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
 
-		// Handle file upload if present
-		if file, err := c.FormFile("zip_file"); err == nil {
-			// Save uploaded file to temporary location
-			tempFile := filepath.Join(os.TempDir(), file.Filename)
-			if err := c.SaveUploadedFile(file, tempFile); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save uploaded file"})
+		if req.Runtime != "" {
+			if err := pm.SetRuntime(server.ID, req.Runtime); err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 				return
 			}
-			zipFilePath = tempFile
-			defer os.Remove(tempFile) // Clean up after use
+			server, _ = pm.GetServer(server.ID)
+		}
+		if len(req.ExtraArgs) > 0 {
+			if err := pm.SetExtraArgs(server.ID, req.ExtraArgs); err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+			server, _ = pm.GetServer(server.ID)
+		}
+
+		c.JSON(http.StatusCreated, newCreatedServerResponse(server, req.Extensions))
+	}
+}
+
+func startServer(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		if err := pm.StartServer(id); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
 		}
 
-		server, err := pm.CreateServer(name, "", extensions, zipFilePath, githubURL)
+		server, _ := pm.GetServer(id)
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Server started",
+			"data":    newServerResponse(server),
+		})
+	}
+}
+
+func stopServer(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		if err := pm.StopServer(id); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		server, _ := pm.GetServer(id)
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Server stopped",
+			"data":    newServerResponse(server),
+		})
+	}
+}
+
+func restartServer(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		if err := pm.RestartServer(id); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		server, _ := pm.GetServer(id)
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Server restarted",
+			"data":    newServerResponse(server),
+		})
+	}
+}
+
+// resetServerConfig rebuilds a server's code-server directory, keeping its workspace
+func resetServerConfig(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		if err := pm.ResetServerConfig(c.Request.Context(), id); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		server, _ := pm.GetServer(id)
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Server config reset",
+			"data":    newServerResponse(server),
+		})
+	}
+}
+
+func getServer(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		server, err := pm.GetServer(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   newServerResponse(server),
+		})
+	}
+}
+
+func updateServer(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		var req UpdateServerRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if _, err := pm.GetServer(id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		if req.Runtime != nil {
+			if _, err := GetRuntime(*req.Runtime); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.ExtraArgs != nil {
+			if err := ValidateExtraArgs(*req.ExtraArgs); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.RestartPolicy != nil {
+			if err := req.RestartPolicy.Validate(); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.Backend != nil {
+			if err := ValidateBackend(*req.Backend); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.RunAsUser != nil {
+			if err := ValidateRunAsUser(*req.RunAsUser); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.WorkspaceFile != nil {
+			if err := ValidateWorkspaceFile(*req.WorkspaceFile); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.OpenPath != nil {
+			if err := ValidateOpenPath(*req.OpenPath); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.ResourceLimits != nil {
+			if err := req.ResourceLimits.Validate(); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if _, err := pm.SetResourceLimits(id, req.ResourceLimits); err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.Runtime != nil {
+			if err := pm.SetRuntime(id, *req.Runtime); err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.Hooks != nil {
+			if err := pm.SetHooks(id, req.Hooks); err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.ExtraArgs != nil {
+			if err := pm.SetExtraArgs(id, *req.ExtraArgs); err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.EditorFlags != nil {
+			if err := pm.SetEditorFlags(id, req.EditorFlags); err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.RestartPolicy != nil {
+			if err := pm.SetRestartPolicy(id, *req.RestartPolicy); err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.Backend != nil {
+			if err := pm.SetBackend(id, *req.Backend); err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.RunAsUser != nil {
+			if err := pm.SetRunAsUser(id, *req.RunAsUser); err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.WorkspaceFile != nil {
+			if err := pm.SetWorkspaceFile(id, *req.WorkspaceFile); err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.OpenPath != nil {
+			if err := pm.SetOpenPath(id, *req.OpenPath); err != nil {
+				c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		server, _ := pm.GetServer(id)
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Server updated",
+			"data":    newServerResponse(server),
+		})
+	}
+}
+
+func deleteServer(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		// Query parameters override the configured retention
+		opts := DefaultDeleteOptions()
+		for param, keep := range map[string]*bool{
+			"keep_logs":      &opts.KeepLogs,
+			"keep_data":      &opts.KeepData,
+			"keep_workspace": &opts.KeepWorkspace,
+		} {
+			if value := c.Query(param); value != "" {
+				parsed, err := strconv.ParseBool(value)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s: %s", param, value)})
+					return
+				}
+				*keep = parsed
+			}
+		}
+
+		if err := pm.DeleteServer(id, opts); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Server deleted",
+			"data":    opts,
+		})
+	}
+}
+
+func getServerHealth(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		health, err := pm.GetServerHealth(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   health,
+		})
+	}
+}
+
+func describeServer(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+
+		description, err := pm.DescribeServer(id)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusCreated, server)
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   description,
+		})
 	}
 }
 
-func createServer(pm *ProcessManager) gin.HandlerFunc {
+// getServerProcesses returns a top-style CPU/memory breakdown of the server's process tree
+func getServerProcesses(pm *ProcessManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var req CreateServerRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		id := c.Param("id")
+
+		if _, err := pm.GetServer(id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
 
-		server, err := pm.CreateServer(req.Name, "", req.Extensions, "", "")
+		processes, err := pm.GetServerProcesses(id)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusCreated, server)
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   processes,
+		})
 	}
 }
 
-func startServer(pm *ProcessManager) gin.HandlerFunc {
+// killServerProcess kills a single descendant process of a server without restarting it
+func killServerProcess(pm *ProcessManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 
-		if err := pm.StartServer(id); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		pid, err := strconv.ParseInt(c.Param("pid"), 10, 32)
+		if err != nil || pid <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pid: " + c.Param("pid")})
+			return
+		}
+
+		if _, err := pm.GetServer(id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := pm.KillServerProcess(id, int32(pid)); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 
-		server, _ := pm.GetServer(id)
 		c.JSON(http.StatusOK, gin.H{
 			"status":  "success",
-			"message": "Server started",
-			"data":    server,
+			"message": fmt.Sprintf("Process %d killed", pid),
 		})
 	}
 }
 
-func stopServer(pm *ProcessManager) gin.HandlerFunc {
+// getWorkspaceGitStatus reports uncommitted changes and ahead/behind counts for the workspace
+func getWorkspaceGitStatus(pm *ProcessManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 
-		if err := pm.StopServer(id); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if _, err := pm.GetServer(id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		status, err := pm.GetWorkspaceGitStatus(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 
-		server, _ := pm.GetServer(id)
 		c.JSON(http.StatusOK, gin.H{
-			"status":  "success",
-			"message": "Server stopped",
-			"data":    server,
+			"status": "success",
+			"data":   status,
 		})
 	}
 }
 
-func restartServer(pm *ProcessManager) gin.HandlerFunc {
+// commitWorkspace commits (and optionally pushes) all workspace changes, e.g. before teardown
+func commitWorkspace(pm *ProcessManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 
-		if err := pm.RestartServer(id); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		var req CommitWorkspaceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		server, _ := pm.GetServer(id)
+		if _, err := pm.GetServer(id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := pm.CommitWorkspace(c.Request.Context(), id, req.Message, req.Push)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error(), "data": result})
+			return
+		}
+
+		message := "Nothing to commit"
+		if result.Committed {
+			message = "Workspace changes committed"
+		}
+		if result.Pushed {
+			message += " and pushed"
+		}
 		c.JSON(http.StatusOK, gin.H{
 			"status":  "success",
-			"message": "Server restarted",
-			"data":    server,
+			"message": message,
+			"data":    result,
 		})
 	}
 }
 
-func deleteServer(pm *ProcessManager) gin.HandlerFunc {
+// logLinesParam reads the ?lines= query parameter, falling back to defaultLines when it is missing or
+// not a positive number, and rejecting values above the configured logging.max_log_lines
+func logLinesParam(c *gin.Context, defaultLines int) (int, error) {
+	lines, err := strconv.Atoi(c.Query("lines"))
+	if err != nil || lines <= 0 {
+		lines = defaultLines
+	}
+
+	maxLines := GetConfig().Logging.MaxLogLines
+	if lines > maxLines {
+		return 0, fmt.Errorf("lines must be at most %d", maxLines)
+	}
+	return lines, nil
+}
+
+func getServerLogs(pm *ProcessManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 
-		if err := pm.DeleteServer(id); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		lines, err := logLinesParam(c, 50)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// ?follow=true keeps the response open, streaming new lines as Server-Sent Events
+		if follow, _ := strconv.ParseBool(c.Query("follow")); follow {
+			setConnectionDeadline(c, time.Time{})
+			pm.HandleLogTailSSE(c.Writer, c.Request, id, lines)
+			return
+		}
+
+		logs, err := pm.GetServerLogs(id, lines)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"status":  "success",
-			"message": "Server deleted",
+			"status": "success",
+			"data":   gin.H{"logs": logs},
 		})
 	}
 }
 
-func getServerHealth(pm *ProcessManager) gin.HandlerFunc {
+// getCombinedServerLogs returns a server's process log and in-memory log entries as one chronological list
+func getCombinedServerLogs(pm *ProcessManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		id := c.Param("id")
+		lines, err := logLinesParam(c, 50)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-		health, err := pm.GetServerHealth(id)
+		logs, err := pm.GetCombinedLogs(c.Param("id"), lines)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
@@ -261,30 +974,26 @@ func getServerHealth(pm *ProcessManager) gin.HandlerFunc {
 
 		c.JSON(http.StatusOK, gin.H{
 			"status": "success",
-			"data":   health,
+			"data":   gin.H{"logs": logs},
 		})
 	}
 }
 
-func getServerLogs(pm *ProcessManager) gin.HandlerFunc {
+func getLogs(lm *LogManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		id := c.Param("id")
-		linesStr := c.DefaultQuery("lines", "50")
-
-		lines, err := strconv.Atoi(linesStr)
+		filter, err := NewLogFilter(c.Param("serverId"), c.Query("source"))
 		if err != nil {
-			lines = 50
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
-
-		logs, err := pm.GetServerLogs(id, lines)
-		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		if filter.SinceSeq, err = parseSinceSeq(c.Query("since_seq")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"status": "success",
-			"data":   gin.H{"logs": logs},
+			"data":   gin.H{"logs": lm.GetLogs(filter)},
 		})
 	}
 }
@@ -293,163 +1002,44 @@ func refreshServerStatus(pm *ProcessManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 
-		pm.mutex.Lock()
-		server, exists := pm.servers[id]
-		if !exists {
-			pm.mutex.Unlock()
+		result, err := pm.RefreshServerStatus(id)
+		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Server not found"})
 			return
 		}
 
-		oldStatus := server.Status
-		pidExists := server.PID != nil
-		var pidStatus string
-		var healthzStatus string
-		var isHealthy bool
-
-		// Check PID status
-		if pidExists {
-			pidStatus = fmt.Sprintf("PID %d exists", *server.PID)
-		} else {
-			pidStatus = "No PID"
-		}
-
-		// Check HTTP health endpoint
-		if pidExists {
-			isHealthy = pm.isServerHealthy(server.Port)
-			if isHealthy {
-				healthzStatus = fmt.Sprintf("Health endpoint responding on port %d", server.Port)
-			} else {
-				healthzStatus = fmt.Sprintf("Health endpoint not responding on port %d", server.Port)
-			}
-		} else {
-			healthzStatus = "No process to check"
-			isHealthy = false
-		}
-
-		// Determine new status
-		var newStatus ServerStatus
-		if pidExists {
-			if isHealthy {
-				newStatus = StatusRunning
-			} else {
-				newStatus = StatusStopped
-			}
-		} else {
-			newStatus = StatusStopped
-		}
-
-		// Update status if changed
-		updated := false
-		if server.Status != newStatus {
-			server.Status = newStatus
-			pm.saveServers()
-			pm.logger.LogProcessEvent(id, server.Name, "STATUS_REFRESHED",
-				fmt.Sprintf("Status updated from %s to %s (PID: %s, Health: %s)",
-					oldStatus, newStatus, pidStatus, healthzStatus))
-			updated = true
-		}
-
-		pm.mutex.Unlock()
-
 		c.JSON(http.StatusOK, gin.H{
 			"status":         "success",
-			"id":             id,
-			"name":           server.Name,
-			"port":           server.Port,
-			"old_status":     oldStatus,
-			"new_status":     newStatus,
-			"pid_status":     pidStatus,
-			"healthz_status": healthzStatus,
-			"updated":        updated,
+			"id":             result.ID,
+			"name":           result.Name,
+			"port":           result.Port,
+			"old_status":     result.OldStatus,
+			"new_status":     result.NewStatus,
+			"pid_status":     result.PIDStatus,
+			"healthz_status": result.HealthzStatus,
+			"updated":        result.Updated,
 		})
 	}
 }
 
 func refreshAllServersStatus(pm *ProcessManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		pm.mutex.Lock()
-		defer pm.mutex.Unlock()
+		results := pm.RefreshAllServersStatus()
 
 		updated := 0
-		total := len(pm.servers)
-		serverDetails := make([]gin.H, 0, total)
-
-		for id, server := range pm.servers {
-			oldStatus := server.Status
-			pidExists := server.PID != nil
-			var pidStatus string
-			var healthzStatus string
-			var isHealthy bool
-
-			// Check PID status
-			if pidExists {
-				pidStatus = fmt.Sprintf("PID %d exists", *server.PID)
-			} else {
-				pidStatus = "No PID"
-			}
-
-			// Check HTTP health endpoint
-			if pidExists {
-				isHealthy = pm.isServerHealthy(server.Port)
-				if isHealthy {
-					healthzStatus = fmt.Sprintf("Health endpoint responding on port %d", server.Port)
-				} else {
-					healthzStatus = fmt.Sprintf("Health endpoint not responding on port %d", server.Port)
-				}
-			} else {
-				healthzStatus = "No process to check"
-				isHealthy = false
-			}
-
-			// Determine new status
-			var newStatus ServerStatus
-			if pidExists {
-				if isHealthy {
-					newStatus = StatusRunning
-				} else {
-					newStatus = StatusStopped
-				}
-			} else {
-				newStatus = StatusStopped
-			}
-
-			// Create detailed server info
-			serverDetail := gin.H{
-				"id":             id,
-				"name":           server.Name,
-				"port":           server.Port,
-				"old_status":     oldStatus,
-				"new_status":     newStatus,
-				"pid_status":     pidStatus,
-				"healthz_status": healthzStatus,
-				"updated":        false,
-			}
-
-			// Update status if changed
-			if server.Status != newStatus {
-				server.Status = newStatus
+		for _, result := range results {
+			if result.Updated {
 				updated++
-				serverDetail["updated"] = true
-				pm.logger.LogProcessEvent(id, server.Name, "STATUS_REFRESHED",
-					fmt.Sprintf("Status updated from %s to %s (PID: %s, Health: %s)",
-						oldStatus, newStatus, pidStatus, healthzStatus))
 			}
-
-			serverDetails = append(serverDetails, serverDetail)
-		}
-
-		// Save changes if any updates
-		if updated > 0 {
-			pm.saveServers()
 		}
+		total := len(results)
 
 		c.JSON(http.StatusOK, gin.H{
 			"status":        "success",
 			"total_servers": total,
 			"updated":       updated,
 			"message":       fmt.Sprintf("Updated %d out of %d servers", updated, total),
-			"servers":       serverDetails,
+			"servers":       results,
 		})
 	}
 }
@@ -466,11 +1056,11 @@ func createServerMetadata(pm *ProcessManager) gin.HandlerFunc {
 		// Create server metadata only (no extensions, no workspace initialization)
 		server, err := pm.CreateServerMetadata(req.Name)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusCreated, server)
+		c.JSON(http.StatusCreated, newServerResponse(server))
 	}
 }
 
@@ -480,14 +1070,15 @@ func installServerExtensions(pm *ProcessManager) gin.HandlerFunc {
 
 		var req struct {
 			Extensions []string `json:"extensions"`
+			Groups     []string `json:"groups"` // Extension group keys from config, expanded into their extensions
 		}
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		if err := pm.InstallExtensionsForServer(id, req.Extensions); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if err := pm.InstallExtensionsForServer(c.Request.Context(), id, req.Extensions, req.Groups); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 
@@ -495,7 +1086,7 @@ func installServerExtensions(pm *ProcessManager) gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  "success",
 			"message": "Extensions installed",
-			"data":    server,
+			"data":    newServerResponse(server),
 		})
 	}
 }
@@ -512,14 +1103,110 @@ func installSingleExtension(pm *ProcessManager) gin.HandlerFunc {
 			return
 		}
 
-		if err := pm.InstallSingleExtension(id, req.Extension); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if err := pm.InstallSingleExtension(c.Request.Context(), id, req.Extension); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Extension installed",
+		})
+	}
+}
+
+// installVSIX installs an extension package uploaded as the vsix_file form field
+func installVSIX(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		if _, err := pm.GetServer(id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Refuse oversized uploads before they are written to disk
+		limitMB := GetConfig().UI.Workspace.MaxUploadSizeMB
+		if limitMB > 0 {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(limitMB)<<20+1<<20) // Room for the multipart framing
+		}
+		file, err := c.FormFile("vsix_file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("upload exceeds %dMB", limitMB)})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "vsix_file is required"})
+			return
+		}
+		if limitMB > 0 && file.Size > int64(limitMB)<<20 {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("upload exceeds %dMB", limitMB)})
+			return
+		}
+		filename := filepath.Base(file.Filename)
+		if !strings.EqualFold(filepath.Ext(filename), ".vsix") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "only .vsix files can be installed"})
+			return
+		}
+
+		// Save under a generated name; the client's filename is only used for display
+		tempFile, err := os.CreateTemp("", "devbox-*.vsix")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save uploaded file"})
+			return
+		}
+		tempFile.Close()
+		defer os.Remove(tempFile.Name())
+		if err := c.SaveUploadedFile(file, tempFile.Name()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save uploaded file"})
+			return
+		}
+
+		entry, err := pm.InstallVSIX(c.Request.Context(), id, tempFile.Name(), filename)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"status":  "success",
 			"message": "Extension installed",
+			"data":    gin.H{"extension": entry},
+		})
+	}
+}
+
+func getInstalledExtensions(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		if _, err := pm.GetServer(id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		drift, err := pm.GetExtensionDrift(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   drift,
+		})
+	}
+}
+
+func uninstallExtension(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := pm.UninstallExtension(c.Request.Context(), c.Param("id"), c.Param("ext")); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Extension uninstalled",
 		})
 	}
 }
@@ -553,28 +1240,20 @@ func cloneServerWorkspace(pm *ProcessManager) gin.HandlerFunc {
 		id := c.Param("id")
 
 		// Handle multipart form data
-		githubURL := c.PostForm("github_url")
-		zipFilePath := ""
-
-		// Handle file upload if present
-		if file, err := c.FormFile("zip_file"); err == nil {
-			// Save uploaded file to temporary location
-			tempFile := filepath.Join(os.TempDir(), file.Filename)
-			if err := c.SaveUploadedFile(file, tempFile); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save uploaded file"})
-				return
-			}
-			zipFilePath = tempFile
-			defer os.Remove(tempFile) // Clean up after use
+		source, cleanup, err := workspaceSourceFromForm(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
+		defer cleanup()
 
-		if githubURL == "" && zipFilePath == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Either github_url or zip_file must be provided"})
+		if source == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Either github_url, zip_file or source_type must be provided"})
 			return
 		}
 
-		if err := pm.InitializeWorkspaceForServer(id, zipFilePath, githubURL); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if err := pm.InitializeWorkspaceForServer(c.Request.Context(), id, *source); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 
@@ -582,7 +1261,74 @@ func cloneServerWorkspace(pm *ProcessManager) gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  "success",
 			"message": "Workspace initialized",
-			"data":    server,
+			"data":    newServerResponse(server),
+		})
+	}
+}
+
+func getBaseExtensions(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   gin.H{"extensions": pm.GetBaseExtensions()},
+		})
+	}
+}
+
+// searchExtensions looks up extensions by text in the configured marketplace (?q=, ?limit= default 20)
+func searchExtensions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := strings.TrimSpace(c.Query("q"))
+		if query == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+			return
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+		if limit > maxExtensionSearchResults {
+			limit = maxExtensionSearchResults
+		}
+
+		results, err := SearchExtensions(c.Request.Context(), query, limit)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   gin.H{"extensions": results},
+		})
+	}
+}
+
+func seedBaseExtensions(pm *ProcessManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Extensions []string `json:"extensions" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		installed, failed, err := pm.SeedBaseExtensions(c.Request.Context(), req.Extensions)
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": fmt.Sprintf("Seeded %d/%d base extensions", len(installed), len(req.Extensions)),
+			"data": gin.H{
+				"installed": installed,
+				"failed":    failed,
+			},
 		})
 	}
 }
@@ -599,6 +1345,26 @@ func getConfig() gin.HandlerFunc {
 	}
 }
 
+// getUIConfig returns only the UI section of the config, so the frontend can bootstrap without the rest
+func getUIConfig() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   GetConfig().UI,
+		})
+	}
+}
+
+// getServerDefaults returns the create options a client should prefill
+func getServerDefaults() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   resolveServerDefaults(GetConfig()),
+		})
+	}
+}
+
 func getTemplates() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		config := GetConfig()
@@ -649,23 +1415,30 @@ func createServerFromTemplate(pm *ProcessManager) gin.HandlerFunc {
 			return
 		}
 
-		// Build extensions list from template's extension groups
-		var allExtensions []string
-		extensionGroups := config.ExtensionGroups
+		// Build extensions list from template's extension groups and their dependencies, skipping unknown groups
+		groupKeys := []string{}
 		for _, groupKey := range template.ExtensionGroups {
-			if group, exists := extensionGroups[groupKey]; exists {
-				allExtensions = append(allExtensions, group.Extensions...)
+			if _, exists := config.ExtensionGroups[groupKey]; exists {
+				groupKeys = append(groupKeys, groupKey)
 			}
 		}
+		allExtensions, err := extensionGroupExtensions(config.ExtensionGroups, groupKeys)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
 		// Create server with template's github URL and extensions
-		githubURL := template.GithubURL
-		server, err := pm.CreateServer(req.Name, "", allExtensions, "", githubURL)
+		var source *WorkspaceSource
+		if template.GithubURL != "" {
+			source = &WorkspaceSource{Type: "github", Location: template.GithubURL}
+		}
+		server, err := pm.CreateServer(c.Request.Context(), req.Name, "", allExtensions, source, template.PostInitCommand, 0)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusCreated, server)
+		c.JSON(http.StatusCreated, newCreatedServerResponse(server, allExtensions))
 	}
 }
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter serves the API and proxy routes of a test manager
+func newTestRouter(pm *ProcessManager) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	setupRoutes(r, pm, NewLogManager())
+	setupProxyRoutes(r, pm)
+	return r
+}
+
+// serveJSON sends a request with body encoded as JSON (none when nil) and returns the recorded response
+func serveJSON(t *testing.T, handler http.Handler, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reader).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// decodeServer decodes the server in a response, either bare (create) or under "data"
+func decodeServer(t *testing.T, recorder *httptest.ResponseRecorder) *ServerInstance {
+	t.Helper()
+	var body struct {
+		*ServerInstance
+		Data *ServerInstance `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding %s: %v", recorder.Body, err)
+	}
+	if body.Data != nil {
+		return body.Data
+	}
+	return body.ServerInstance
+}
+
+func TestServerLifecycleRoutes(t *testing.T) {
+	pm := newTestProcessManager(t)
+	router := newTestRouter(pm)
+
+	created := serveJSON(t, router, http.MethodPost, "/servers", map[string]any{"name": "api-test"})
+	if created.Code != http.StatusCreated {
+		t.Fatalf("POST /servers = %d %s, want 201", created.Code, created.Body)
+	}
+	server := decodeServer(t, created)
+
+	started := serveJSON(t, router, http.MethodPost, "/servers/"+server.ID+"/start", nil)
+	if started.Code != http.StatusOK {
+		t.Fatalf("POST /servers/:id/start = %d %s, want 200", started.Code, started.Body)
+	}
+	if running := decodeServer(t, started); running.Status != StatusRunning || running.PID == nil {
+		t.Errorf("started server has status %s, PID %v; want running with a PID", running.Status, running.PID)
+	}
+	commands := testLauncher(pm).Commands()
+	if len(commands) != 1 || !strings.Contains(strings.Join(commands[0], " "), fmt.Sprintf(":%d ", server.Port)) {
+		t.Errorf("launched commands %v, want one listening on port %d", commands, server.Port)
+	}
+
+	stopped := serveJSON(t, router, http.MethodPost, "/servers/"+server.ID+"/stop", nil)
+	if stopped.Code != http.StatusOK {
+		t.Fatalf("POST /servers/:id/stop = %d %s, want 200", stopped.Code, stopped.Body)
+	}
+	if fetched := decodeServer(t, serveJSON(t, router, http.MethodGet, "/servers/"+server.ID, nil)); fetched.Status != StatusStopped {
+		t.Errorf("GET /servers/:id after stop has status %s, want stopped", fetched.Status)
+	}
+
+	if deleted := serveJSON(t, router, http.MethodDelete, "/servers/"+server.ID, nil); deleted.Code != http.StatusOK {
+		t.Fatalf("DELETE /servers/:id = %d %s, want 200", deleted.Code, deleted.Body)
+	}
+	if missing := serveJSON(t, router, http.MethodGet, "/servers/"+server.ID, nil); missing.Code != http.StatusNotFound {
+		t.Errorf("GET /servers/:id after delete = %d, want 404", missing.Code)
+	}
+}
+
+func TestErrorStatusMapsTimeoutsToGatewayTimeout(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{fmt.Errorf("git clone: %w", context.DeadlineExceeded), http.StatusGatewayTimeout},
+		{fmt.Errorf("install failed: %w", ErrServerBusy), http.StatusConflict},
+		{context.Canceled, http.StatusInternalServerError},
+		{errors.New("git clone: timed out"), http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		if got := errorStatus(test.err); got != test.want {
+			t.Errorf("errorStatus(%v) = %d, want %d", test.err, got, test.want)
+		}
+	}
+
+	// An operation cut off by the request deadline reports it, rather than its own failure
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	if _, err := runGit(ctx, nil, t.TempDir(), "status"); errorStatus(err) != http.StatusGatewayTimeout {
+		t.Errorf("git run past the request deadline failed with %v (status %d), want %d", err, errorStatus(err), http.StatusGatewayTimeout)
+	}
+}
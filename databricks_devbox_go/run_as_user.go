@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// serverUser is the OS account a server's process runs as
+type serverUser struct {
+	Name string
+	UID  int
+	GID  int
+	Home string
+}
+
+// lookupServerUser resolves an account name (or numeric uid) on this host
+func lookupServerUser(name string) (*serverUser, error) {
+	account, err := user.Lookup(name)
+	if err != nil {
+		if account, err = user.LookupId(name); err != nil {
+			return nil, fmt.Errorf("unknown user %q", name)
+		}
+	}
+	uid, err := strconv.Atoi(account.Uid)
+	if err != nil {
+		return nil, fmt.Errorf("user %q has a non-numeric uid %s", name, account.Uid)
+	}
+	gid, err := strconv.Atoi(account.Gid)
+	if err != nil {
+		return nil, fmt.Errorf("user %q has a non-numeric gid %s", name, account.Gid)
+	}
+	return &serverUser{Name: account.Username, UID: uid, GID: gid, Home: account.HomeDir}, nil
+}
+
+// ValidateRunAsUser checks that name is an account on this host; empty follows server.run_as_user
+func ValidateRunAsUser(name string) error {
+	if name == "" {
+		return nil
+	}
+	_, err := lookupServerUser(name)
+	return err
+}
+
+// runAsUser returns the account the server runs as, falling back to server.run_as_user;
+// empty means the manager's own user
+func (s *ServerInstance) runAsUser() string {
+	if s.RunAsUser != "" {
+		return s.RunAsUser
+	}
+	return GetConfig().Server.RunAsUser
+}
+
+// SetRunAsUser changes the OS user a server runs as from its next start
+func (pm *ProcessManager) SetRunAsUser(id, name string) error {
+	if err := ValidateRunAsUser(name); err != nil {
+		return err
+	}
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	server, exists := pm.servers[id]
+	if !exists {
+		return fmt.Errorf("server not found: %s", id)
+	}
+
+	server.RunAsUser = name
+	runAs := server.runAsUser()
+	if runAs == "" {
+		runAs = "the manager's user"
+	}
+	pm.logger.LogProcessEvent(id, server.Name, "RUN_AS_USER_UPDATED", fmt.Sprintf("Runs as %s (applies from the next start)", runAs))
+	pm.saveServers()
+	return nil
+}
+
+// commandUser resolves the account a server runs as (see runAsUser); nil means the manager's own user
+func commandUser(name string) (*serverUser, error) {
+	if name == "" {
+		return nil, nil
+	}
+	account, err := lookupServerUser(name)
+	if err != nil {
+		return nil, err
+	}
+	if account.UID == os.Getuid() {
+		return nil, nil
+	}
+	return account, nil
+}
+
+// serverCommandUser returns the account commands for the server with id run as. Commands for
+// anything else, such as seeding the shared base extensions, run as the manager.
+func (pm *ProcessManager) serverCommandUser(id string) (*serverUser, error) {
+	pm.mutex.RLock()
+	name := ""
+	if server, exists := pm.servers[id]; exists {
+		name = server.runAsUser()
+	}
+	pm.mutex.RUnlock()
+	return commandUser(name)
+}
+
+// runAs makes cmd run as account; nil leaves it running as the manager. Every command run in a
+// server's workspace or data directory goes through this, so nothing there is written by the
+// manager's user once the server's user owns it.
+func runAs(cmd *exec.Cmd, account *serverUser) error {
+	if account == nil {
+		return nil
+	}
+	if err := setCommandCredential(cmd, account.UID, account.GID); err != nil {
+		return err
+	}
+	// The inherited HOME is the manager's, which the user can't write to
+	if account.Home != "" {
+		env := cmd.Env
+		if env == nil {
+			env = os.Environ()
+		}
+		cmd.Env = append(env, "HOME="+account.Home)
+	}
+	return nil
+}
+
+// applyRunAsUser makes cmd run as the server's OS user. Switching users requires the manager to
+// run as root; the user must already own the server's directories (see handOverServerDirs).
+func (pm *ProcessManager) applyRunAsUser(server *ServerInstance, cmd *exec.Cmd) error {
+	account, err := commandUser(server.runAsUser())
+	if err != nil || account == nil {
+		return err
+	}
+	if err := runAs(cmd, account); err != nil {
+		return err
+	}
+	pm.log.Printf("Server %s runs as user %s (uid %d)", server.Name, account.Name, account.UID)
+	return nil
+}
+
+// handOverServerDirs gives the server's data directory, and its workspace when the manager created
+// it, to the server's user and closes them to everyone else, so servers of different users can't
+// read each other's files. Trees the user already owns aren't walked again, so this only does work
+// for a new server or one whose user changed. An adopted workspace is never changed: it must
+// already belong to the user.
+func (pm *ProcessManager) handOverServerDirs(server *ServerInstance) error {
+	account, err := commandUser(server.runAsUser())
+	if err != nil || account == nil {
+		return err
+	}
+
+	dirs := []string{filepath.Join(pm.dataDir, server.ID)}
+	if pm.managesWorkspace(server) {
+		dirs = append(dirs, server.WorkspacePath)
+	} else if err := checkOwnedBy(server.WorkspacePath, account); err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		// code-server's directory is created by the manager on start if it is missing
+		if err := handOver(dir, account, ".", "code-server"); err != nil {
+			return fmt.Errorf("failed to give %s to user %s: %v", dir, account.Name, err)
+		}
+		if err := os.Chmod(dir, 0700); err != nil {
+			return fmt.Errorf("failed to restrict %s: %v", dir, err)
+		}
+	}
+	return nil
+}
+
+// handOverServerData gives entries the manager wrote into a server's data directory (named
+// relative to it) to the server's user, so the editor running as that user can change them
+func (pm *ProcessManager) handOverServerData(id string, names ...string) error {
+	account, err := pm.serverCommandUser(id)
+	if err != nil || account == nil {
+		return err
+	}
+	return handOver(filepath.Join(pm.dataDir, id), account, names...)
+}
+
+// managesWorkspace reports whether the server's workspace is one the manager created under
+// workspace/, and so may be handed to the server's user. Adopted directories can be anywhere.
+func (pm *ProcessManager) managesWorkspace(server *ServerInstance) bool {
+	if !pm.ownsWorkspace(server) {
+		return false
+	}
+	root, err := filepath.Abs(pm.workspaceDir)
+	if err != nil {
+		return false
+	}
+	return server.WorkspacePath != root && pathWithin(root, server.WorkspacePath)
+}
+
+// checkOwnedBy refuses an adopted workspace that the user doesn't already own, since handing
+// over an arbitrary existing directory would give away (and lock others out of) its contents
+func checkOwnedBy(dir string, account *serverUser) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("adopted workspace is not accessible: %v", err)
+	}
+	if uid, ok := fileOwner(info); !ok || uid != account.UID {
+		return fmt.Errorf("adopted workspace %s must be owned by user %s to run as that user", dir, account.Name)
+	}
+	return nil
+}
+
+// handOver gives the named entries of dir ("." for dir itself) to account, directories along with
+// everything in them. Entries the account already owns are left as they are. The user may already
+// control parts of the tree, so every change goes through a descriptor opened within dir: symlinks
+// can't lead the walk out of it, and an entry swapped after it was checked is skipped.
+func handOver(dir string, account *serverUser, names ...string) error {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	for _, name := range names {
+		if err := handOverEntry(root, name, account); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handOverEntry gives one entry of root to account, see handOver. Anything but directories and
+// regular files, such as symlinks, keeps its owner.
+func handOverEntry(root *os.Root, name string, account *serverUser) error {
+	info, err := root.Lstat(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if uid, ok := fileOwner(info); ok && uid == account.UID {
+		return nil
+	}
+
+	var file *os.File
+	var children *os.Root
+	switch {
+	case info.IsDir():
+		if children, err = root.OpenRoot(name); err != nil {
+			return err
+		}
+		defer children.Close()
+		file, err = children.Open(".")
+	case info.Mode().IsRegular():
+		// Non-blocking, in case it has been swapped for a FIFO
+		file, err = root.OpenFile(name, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	opened, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(info, opened) {
+		return nil
+	}
+
+	if children != nil {
+		entries, err := file.ReadDir(-1)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := handOverEntry(children, entry.Name(), account); err != nil {
+				return err
+			}
+		}
+	}
+	return file.Chown(account.UID, account.GID)
+}
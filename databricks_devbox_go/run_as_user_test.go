@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestHandOverStaysWithinTree(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Getuid() != 0 {
+		t.Skip("handing files to another user needs root")
+	}
+	account := &serverUser{Name: "tenant", UID: 4242, GID: 4242}
+
+	outside := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(outside, []byte("not the tenant's"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "code-server", "User"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "code-server", "User", "settings.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Links the tenant could have planted, to a file and to a directory outside the tree
+	if err := os.Symlink(outside, filepath.Join(dir, "code-server", "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Dir(outside), filepath.Join(dir, "dirlink")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := handOver(dir, account, ".", "code-server"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{".", "code-server", "code-server/User", "code-server/User/settings.json"} {
+		info, err := os.Lstat(filepath.Join(dir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uid, _ := fileOwner(info); uid != account.UID {
+			t.Errorf("%s is owned by uid %d, want %d", path, uid, account.UID)
+		}
+	}
+	for _, path := range []string{outside, filepath.Dir(outside)} {
+		info, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uid, _ := fileOwner(info); uid != 0 {
+			t.Errorf("%s outside the tree was given to uid %d through a symlink", path, uid)
+		}
+	}
+}
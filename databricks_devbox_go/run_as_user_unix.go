@@ -0,0 +1,35 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setCommandCredential makes cmd run as uid:gid without the manager's supplementary groups
+func setCommandCredential(cmd *exec.Cmd, uid, gid int) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: []uint32{}}
+	return nil
+}
+
+// fileOwner returns the uid that owns a file
+func fileOwner(info os.FileInfo) (uid int, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int(stat.Uid), true
+}
+
+// commandCredential returns the uid:gid cmd was set to run as, if any
+func commandCredential(cmd *exec.Cmd) (uid, gid int, ok bool) {
+	if cmd.SysProcAttr == nil || cmd.SysProcAttr.Credential == nil {
+		return 0, 0, false
+	}
+	return int(cmd.SysProcAttr.Credential.Uid), int(cmd.SysProcAttr.Credential.Gid), true
+}
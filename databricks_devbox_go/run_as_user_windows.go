@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// setCommandCredential is not supported on Windows
+func setCommandCredential(cmd *exec.Cmd, uid, gid int) error {
+	return fmt.Errorf("running servers as another user is not supported on windows")
+}
+
+// fileOwner is not available on Windows
+func fileOwner(info os.FileInfo) (uid int, ok bool) {
+	return 0, false
+}
+
+// commandCredential never reports a credential on Windows
+func commandCredential(cmd *exec.Cmd) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// defaultRuntime is the runtime used by servers that don't specify one
+const defaultRuntime = "code-server"
+
+// codeServerRuntime is the built-in code-server runtime, used unless overridden in config.
+// --disable-telemetry/--disable-update-check/--disable-file-downloads are added per server from EditorFlags.
+func codeServerRuntime() RuntimeConfig {
+	return RuntimeConfig{
+		Name:   "code-server",
+		Binary: "code-server",
+		Args: []string{
+			"--bind-addr", "0.0.0.0:{port}",
+			"--user-data-dir", "{config_dir}", // Use absolute config dir like Python version
+			"--auth", "none",
+			"--log", "info",
+			"{workspace_target}", // The .code-workspace file of a multi-root workspace, or the directory
+		},
+		Env: []string{
+			// "VSCODE_PROXY_URI=./vscode/{port}",
+			"CODEX_HOME={workspace}/.codex", // Absolute path to workspace/.codex directory
+			"NODE_OPTIONS=--max-old-space-size=2048",
+			"VSCODE_LOGS=info",
+			"CODE_SERVER_LOG=info",
+			"UV_THREADPOOL_SIZE=128",
+			"NODE_TLS_REJECT_UNAUTHORIZED=0",
+			"VSCODE_DISABLE_CRASH_REPORTER=true",
+			"ELECTRON_NO_ATTACH_CONSOLE=1",
+			"DISABLE_TELEMETRY=true",
+		},
+	}
+}
+
+// isCodeServerRuntime reports whether a runtime key selects code-server, which EditorFlags apply to
+func isCodeServerRuntime(key string) bool {
+	return key == "" || key == defaultRuntime
+}
+
+// GetRuntime resolves a runtime by key; an empty key selects code-server
+func GetRuntime(key string) (RuntimeConfig, error) {
+	if key == "" {
+		key = defaultRuntime
+	}
+
+	if runtime, exists := GetConfig().Runtimes[key]; exists {
+		return runtime, nil
+	}
+	if key == defaultRuntime {
+		return codeServerRuntime(), nil
+	}
+	return RuntimeConfig{}, fmt.Errorf("unknown runtime: %s", key)
+}
+
+// runtimeLaunch holds the values substituted into a runtime's args and env templates
+type runtimeLaunch struct {
+	Port      int
+	Workspace string
+	Target    string // .code-workspace file to open; empty opens Workspace
+	DataDir   string // absolute data/{server_id}
+	ConfigDir string // absolute data/{server_id}/code-server
+}
+
+// BuildCommand substitutes {port}, {workspace}, {workspace_target}, {data_dir} and {config_dir} into
+// the runtime's args and env, returning the argument list and the environment entries it sets
+func (r RuntimeConfig) BuildCommand(launch runtimeLaunch) ([]string, []string) {
+	target := launch.Target
+	if target == "" {
+		target = launch.Workspace
+	}
+	replacer := strings.NewReplacer(
+		"{port}", strconv.Itoa(launch.Port),
+		"{workspace_target}", target,
+		"{workspace}", launch.Workspace,
+		"{data_dir}", launch.DataDir,
+		"{config_dir}", launch.ConfigDir,
+	)
+
+	args := make([]string, 0, len(r.Args))
+	for _, arg := range r.Args {
+		args = append(args, replacer.Replace(arg))
+	}
+
+	env := make([]string, 0, len(r.Env))
+	for _, entry := range r.Env {
+		env = append(env, replacer.Replace(entry))
+	}
+	return args, env
+}
+
+// healthProbe is what's needed to check a server's HTTP health without holding the lock
+type healthProbe struct {
+	Port       int
+	HealthPath string // empty uses code-server's /healthz check
+}
+
+// newHealthProbe builds the health probe for a server from its runtime
+func newHealthProbe(server *ServerInstance) healthProbe {
+	probe := healthProbe{Port: server.Port}
+	if runtime, err := GetRuntime(server.Runtime); err == nil {
+		probe.HealthPath = runtime.HealthPath
+	}
+	return probe
+}
+
+// probeHealth checks a server using its runtime's health endpoint and, when
+// monitoring.end_to_end_health_check is on, through the /vscode proxy as well
+func (pm *ProcessManager) probeHealth(probe healthProbe) bool {
+	if !pm.probeDirectHealth(probe) {
+		return false
+	}
+	if GetConfig().Monitoring.EndToEndHealthCheck {
+		return pm.probeProxyHealth(probe)
+	}
+	return true
+}
+
+// probeDirectHealth checks a server's health endpoint on localhost
+func (pm *ProcessManager) probeDirectHealth(probe healthProbe) bool {
+	if probe.HealthPath == "" {
+		return pm.isServerHealthy(probe.Port)
+	}
+
+	client := &http.Client{
+		Timeout: 3 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(fmt.Sprintf("http://localhost:%d%s", probe.Port, probe.HealthPath))
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	// Other editors have no code-server style heartbeat, so any non-error response counts
+	return resp.StatusCode < http.StatusBadRequest
+}
+
+// healthProbeHeader marks the manager's own end-to-end health checks, so the proxy doesn't count
+// them as activity. Its value is a per-process secret, so clients can't hide real traffic behind it.
+const healthProbeHeader = "X-Devbox-Health-Probe"
+
+var healthProbeToken = uuid.New().String()
+
+// isHealthProbe reports whether a proxied request is one of the manager's own health checks
+func isHealthProbe(req *http.Request) bool {
+	return req.Header.Get(healthProbeHeader) == healthProbeToken
+}
+
+// probeProxyHealth fetches a server's health endpoint through the manager's /vscode/{port}
+// route, so header or path handling that breaks the proxy shows up as unhealthy
+func (pm *ProcessManager) probeProxyHealth(probe healthProbe) bool {
+	baseURL, _ := pm.proxyBaseURL.Load().(string)
+	if baseURL == "" {
+		// Not serving yet (or embedded without a proxy), so there's nothing to check through
+		return true
+	}
+
+	healthPath := probe.HealthPath
+	if healthPath == "" {
+		healthPath = "/healthz"
+	}
+
+	client := &http.Client{
+		Timeout: 3 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/vscode/%d%s", baseURL, probe.Port, healthPath), nil)
+	if err != nil {
+		pm.log.Printf("End-to-end health check for port %d failed: %v", probe.Port, err)
+		return false
+	}
+	req.Header.Set(healthProbeHeader, healthProbeToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		pm.log.Printf("End-to-end health check for port %d failed: %v", probe.Port, err)
+		return false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		pm.log.Printf("End-to-end health check for port %d returned %d", probe.Port, resp.StatusCode)
+		return false
+	}
+	return true
+}
+
+// SetRuntime changes the runtime a server is launched with; it takes effect on the next start
+func (pm *ProcessManager) SetRuntime(id, runtime string) error {
+	if _, err := GetRuntime(runtime); err != nil {
+		return err
+	}
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	server, exists := pm.servers[id]
+	if !exists {
+		return fmt.Errorf("server not found: %s", id)
+	}
+
+	pm.logger.LogProcessEvent(id, server.Name, "RUNTIME_UPDATED", fmt.Sprintf("Runtime set to %s", runtime))
+
+	// Store the default as empty so servers follow any change to it
+	if runtime == defaultRuntime {
+		runtime = ""
+	}
+	server.Runtime = runtime
+	pm.saveServers()
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+// ServerDefaults are the create options a client should prefill, resolved from config
+type ServerDefaults struct {
+	ExtensionGroups       []string `json:"extension_groups"` // ui.default_extension_groups that exist in extension_groups
+	Extensions            []string `json:"extensions"`       // Extensions of those groups, including depends_on groups
+	Runtime               string   `json:"runtime"`
+	WorkspaceType         string   `json:"workspace_type"` // empty, upload or github
+	MaxUploadSizeMB       int      `json:"max_upload_size_mb"`
+	SupportedArchiveTypes []string `json:"supported_archive_types"`
+	PostInitCommand       string   `json:"post_init_command,omitempty"`
+}
+
+// resolveServerDefaults assembles the create defaults from the UI, workspace and server config
+func resolveServerDefaults(config *DevboxConfig) ServerDefaults {
+	groups := []string{}
+	for _, key := range config.UI.DefaultExtensionGroups {
+		if _, exists := config.ExtensionGroups[key]; exists {
+			groups = append(groups, key)
+		}
+	}
+
+	extensions, err := extensionGroupExtensions(config.ExtensionGroups, groups)
+	if err != nil {
+		// Dependency errors are reported at config load; fall back to no preselected extensions
+		extensions = []string{}
+	}
+
+	return ServerDefaults{
+		ExtensionGroups:       groups,
+		Extensions:            extensions,
+		Runtime:               defaultRuntime,
+		WorkspaceType:         config.UI.Workspace.DefaultType,
+		MaxUploadSizeMB:       config.UI.Workspace.MaxUploadSizeMB,
+		SupportedArchiveTypes: nonNilStrings(config.UI.Workspace.SupportedArchiveTypes),
+		PostInitCommand:       config.Server.PostInitCommand,
+	}
+}
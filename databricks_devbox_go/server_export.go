@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// serverExportVersion is bumped whenever the export format changes incompatibly
+const serverExportVersion = 1
+
+// ServerExport is the portable part of a server definition: no IDs, ports, PIDs or host paths
+type ServerExport struct {
+	Name           string          `json:"name"`
+	Runtime        string          `json:"runtime,omitempty"`
+	Extensions     []string        `json:"extensions"`
+	ResourceLimits *ResourceLimits `json:"resource_limits,omitempty"`
+	Hooks          *LifecycleHooks `json:"hooks,omitempty"`
+	ExtraArgs      []string        `json:"extra_args,omitempty"`
+	EditorFlags    *EditorFlags    `json:"editor_flags,omitempty"`
+	RestartPolicy  RestartPolicy   `json:"restart_policy,omitempty"`
+	Backend        string          `json:"backend,omitempty"`
+	RunAsUser      string          `json:"run_as_user,omitempty"`
+}
+
+// ServersExport is the document returned by GET /servers/export and accepted by POST /servers/import
+type ServersExport struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Servers    []ServerExport `json:"servers" binding:"required"`
+}
+
+// ServerImportResult reports the outcome of importing one server definition
+type ServerImportResult struct {
+	Name   string          `json:"name"`
+	Server *ServerResponse `json:"server,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// ExportServers returns the portable definitions of all servers, sorted by name
+func (pm *ProcessManager) ExportServers() ServersExport {
+	servers := pm.ListServers()
+
+	pm.mutex.RLock()
+	exports := make([]ServerExport, 0, len(servers))
+	for _, server := range servers {
+		exports = append(exports, ServerExport{
+			Name:           server.Name,
+			Runtime:        server.Runtime,
+			Extensions:     marketplaceExtensions(server.Extensions),
+			ResourceLimits: server.ResourceLimits,
+			Hooks:          server.Hooks,
+			ExtraArgs:      server.ExtraArgs,
+			EditorFlags:    server.EditorFlags,
+			RestartPolicy:  server.RestartPolicy,
+			Backend:        server.Backend,
+			RunAsUser:      server.RunAsUser,
+		})
+	}
+	pm.mutex.RUnlock()
+
+	sort.Slice(exports, func(i, j int) bool { return exports[i].Name < exports[j].Name })
+	return ServersExport{
+		Version:    serverExportVersion,
+		ExportedAt: time.Now(),
+		Servers:    exports,
+	}
+}
+
+// validateServerExport checks a definition can be imported on this host
+func validateServerExport(export ServerExport) error {
+	if export.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if _, err := GetRuntime(export.Runtime); err != nil {
+		return err
+	}
+	if export.ResourceLimits != nil {
+		if err := export.ResourceLimits.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := export.RestartPolicy.Validate(); err != nil {
+		return err
+	}
+	if err := ValidateBackend(export.Backend); err != nil {
+		return err
+	}
+	if err := ValidateRunAsUser(export.RunAsUser); err != nil {
+		return err
+	}
+	return ValidateExtraArgs(export.ExtraArgs)
+}
+
+// ImportServers recreates exported server definitions with fresh IDs and ports.
+// Each definition is imported independently; failures are reported per server.
+func (pm *ProcessManager) ImportServers(ctx context.Context, data ServersExport) ([]ServerImportResult, error) {
+	if data.Version != serverExportVersion {
+		return nil, fmt.Errorf("unsupported export version %d (expected %d)", data.Version, serverExportVersion)
+	}
+
+	results := make([]ServerImportResult, 0, len(data.Servers))
+	for _, export := range data.Servers {
+		result := ServerImportResult{Name: export.Name}
+		server, err := pm.importServer(ctx, export)
+		if err != nil {
+			result.Error = err.Error()
+			pm.log.Printf("Failed to import server %s: %v", export.Name, err)
+		} else {
+			result.Server = newServerResponse(server)
+		}
+		results = append(results, result)
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if pm.logManager != nil {
+		pm.logManager.AddSystemLog("INFO", fmt.Sprintf("Imported %d server definitions", len(results)))
+	}
+	return results, ctx.Err()
+}
+
+func (pm *ProcessManager) importServer(ctx context.Context, export ServerExport) (*ServerInstance, error) {
+	if err := validateServerExport(export); err != nil {
+		return nil, err
+	}
+
+	server, err := pm.CreateServer(ctx, export.Name, "", export.Extensions, nil, "", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if export.Runtime != "" {
+		if err := pm.SetRuntime(server.ID, export.Runtime); err != nil {
+			return nil, err
+		}
+	}
+	if export.ResourceLimits != nil {
+		if _, err := pm.SetResourceLimits(server.ID, export.ResourceLimits); err != nil {
+			return nil, err
+		}
+	}
+	if export.Hooks != nil {
+		if err := pm.SetHooks(server.ID, export.Hooks); err != nil {
+			return nil, err
+		}
+	}
+	if export.EditorFlags != nil {
+		if err := pm.SetEditorFlags(server.ID, export.EditorFlags); err != nil {
+			return nil, err
+		}
+	}
+	if len(export.ExtraArgs) > 0 {
+		if err := pm.SetExtraArgs(server.ID, export.ExtraArgs); err != nil {
+			return nil, err
+		}
+	}
+	if export.RestartPolicy != "" {
+		if err := pm.SetRestartPolicy(server.ID, export.RestartPolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	if export.Backend != "" {
+		if err := pm.SetBackend(server.ID, export.Backend); err != nil {
+			return nil, err
+		}
+	}
+
+	if export.RunAsUser != "" {
+		if err := pm.SetRunAsUser(server.ID, export.RunAsUser); err != nil {
+			return nil, err
+		}
+	}
+
+	pm.logger.LogProcessEvent(server.ID, server.Name, "IMPORTED", "Server imported from export")
+	return pm.GetServer(server.ID)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// PlanServerRequest describes a server creation to resolve without executing it
+type PlanServerRequest struct {
+	CreateServerRequest
+	ExtensionGroups []string `json:"extension_groups"` // Group keys from config, expanded into extensions
+}
+
+// ServerPlan is what CreateServer would do for a request, resolved without side effects
+type ServerPlan struct {
+	Name                string                 `json:"name"`
+	Port                int                    `json:"port"`           // Requested or next free port; not reserved, so a concurrent create may take it
+	WorkspacePath       string                 `json:"workspace_path"` // Contains {id} unless adopted, since the server ID is only generated on create
+	Runtime             string                 `json:"runtime"`
+	Command             []string               `json:"command"`
+	Source              *WorkspaceSource       `json:"source,omitempty"`
+	PostInitCommand     string                 `json:"post_init_command,omitempty"`
+	Hooks               LifecycleHooks         `json:"hooks"`
+	Extensions          []string               `json:"extensions"`            // Requested plus group extensions, deduplicated
+	BaseExtensions      []string               `json:"base_extensions"`       // Inherited from the seeded base directory
+	ExtensionsToInstall []string               `json:"extensions_to_install"` // Extensions minus the inherited ones
+	SettingsGroups      []string               `json:"settings_groups"`       // Extension groups contributing user settings
+	UserSettings        map[string]interface{} `json:"user_settings"`
+	SettingsConflicts   []SettingsConflict     `json:"settings_conflicts"` // Keys the settings groups set to different values
+}
+
+// PlanServer resolves everything CreateServer would do for req without creating
+// directories, reserving ports or starting processes
+func (pm *ProcessManager) PlanServer(req PlanServerRequest) (*ServerPlan, error) {
+	config := GetConfig()
+
+	runtime, err := GetRuntime(req.Runtime)
+	if err != nil {
+		return nil, err
+	}
+	if req.Workspace != nil {
+		if err := validateRequestedWorkspaceSource(*req.Workspace); err != nil {
+			return nil, err
+		}
+	}
+	if req.WorkspacePath != "" {
+		if err := validateAdoptedWorkspace(req.WorkspacePath); err != nil {
+			return nil, err
+		}
+		if req.Workspace != nil {
+			return nil, fmt.Errorf("workspace_path can't be combined with a workspace source")
+		}
+	}
+
+	// Expand extension groups after the explicitly requested extensions
+	extensions := []string{}
+	for _, extension := range req.Extensions {
+		if !containsExtension(extensions, extension) {
+			extensions = append(extensions, extension)
+		}
+	}
+	groupExtensions, err := extensionGroupExtensions(config.ExtensionGroups, req.ExtensionGroups)
+	if err != nil {
+		return nil, err
+	}
+	for _, extension := range groupExtensions {
+		if !containsExtension(extensions, extension) {
+			extensions = append(extensions, extension)
+		}
+	}
+	if err := checkExtensionCount(len(extensions)); err != nil {
+		return nil, err
+	}
+	if err := validateExtensionRefs(extensions); err != nil {
+		return nil, err
+	}
+
+	baseExtensions := pm.GetBaseExtensions()
+	userSettings, settingsGroups := collectUserSettings(config, extensions)
+
+	postInitCommand := req.PostInitCommand
+	if postInitCommand == "" {
+		postInitCommand = config.Server.PostInitCommand
+	}
+
+	workspacePath, workspaceFile := splitWorkspaceFile(req.WorkspacePath)
+	if workspacePath == "" {
+		workspacePath = filepath.Join(pm.workspaceDir, "{id}")
+		if abs, err := filepath.Abs(workspacePath); err == nil {
+			workspacePath = abs
+		}
+	}
+	workspaceTarget := ""
+	if workspaceFile != "" {
+		workspaceTarget = filepath.Join(workspacePath, workspaceFile)
+	}
+	dataDir := filepath.Join(pm.dataDir, "{id}")
+	if abs, err := filepath.Abs(dataDir); err == nil {
+		dataDir = abs
+	}
+	port := req.Port
+	if port != 0 {
+		pm.mutex.RLock()
+		err = pm.checkRequestedPort(port)
+		pm.mutex.RUnlock()
+	} else {
+		port, err = pm.peekNextAvailablePort()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateExtraArgs(req.ExtraArgs); err != nil {
+		return nil, err
+	}
+	args, _ := runtime.BuildCommand(runtimeLaunch{
+		Port:      port,
+		Workspace: workspacePath,
+		Target:    workspaceTarget,
+		DataDir:   dataDir,
+		ConfigDir: filepath.Join(dataDir, "code-server"),
+	})
+	if isCodeServerRuntime(req.Runtime) {
+		args = append(args, config.Editor.Args()...)
+	}
+	args = append(args, req.ExtraArgs...)
+
+	return &ServerPlan{
+		Name:                req.Name,
+		Port:                port,
+		WorkspacePath:       workspacePath,
+		Runtime:             runtime.Name,
+		Command:             append([]string{runtime.Binary}, args...),
+		Source:              req.Workspace,
+		PostInitCommand:     postInitCommand,
+		Hooks:               config.Hooks,
+		Extensions:          extensions,
+		BaseExtensions:      baseExtensions,
+		ExtensionsToInstall: excludeExtensions(extensions, baseExtensions),
+		SettingsGroups:      settingsGroups,
+		UserSettings:        userSettings,
+		SettingsConflicts:   findSettingsConflicts(config.ExtensionGroups, settingsGroups),
+	}, nil
+}
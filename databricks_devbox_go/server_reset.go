@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ResetServerConfig rebuilds a server's code-server directory (settings, extensions and editor
+// state under data/{id}/code-server) from scratch, e.g. after extensions got corrupted. The
+// server is stopped first and started again afterwards if it was running; the workspace is untouched.
+func (pm *ProcessManager) ResetServerConfig(ctx context.Context, id string) error {
+	if err := pm.beginOperation(id, "reset-config"); err != nil {
+		return err
+	}
+	defer pm.endOperation(id)
+
+	pm.mutex.RLock()
+	server, exists := pm.servers[id]
+	if !exists {
+		pm.mutex.RUnlock()
+		return fmt.Errorf("server not found: %s", id)
+	}
+	name := server.Name
+	wasRunning := server.Status == StatusRunning
+	extensions := append([]string{}, server.Extensions...)
+	pm.mutex.RUnlock()
+
+	if wasRunning {
+		if err := pm.stopServer(id); err != nil {
+			return fmt.Errorf("failed to stop server before resetting its config: %v", err)
+		}
+	}
+
+	pm.logger.LogProcessEvent(id, name, "CONFIG_RESET", "Rebuilding code-server config directory")
+	if pm.logManager != nil {
+		pm.logManager.AddServerLog(id, name, "INFO", "server", "Resetting code-server config directory")
+	}
+
+	serverDataDir := filepath.Join(pm.dataDir, id)
+	configDir := filepath.Join(serverDataDir, "code-server")
+	if err := os.RemoveAll(configDir); err != nil {
+		return fmt.Errorf("failed to remove config directory: %v", err)
+	}
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+
+	// Inherit the seeded base extensions again, then reinstall the rest
+	baseExtensions := pm.inheritBaseExtensions(id)
+	if err := pm.handOverServerData(id, "code-server"); err != nil {
+		return fmt.Errorf("failed to give config directory to the server's user: %v", err)
+	}
+	resolved, ok := pm.installExtensions(ctx, pm.extensionInstallEnv(serverDataDir), excludeExtensions(extensions, baseExtensions), id, name)
+
+	pm.mutex.Lock()
+	if server, exists := pm.servers[id]; exists {
+		server.BaseExtensions = baseExtensions
+		server.Extensions = mergeResolvedExtensions(extensions, resolved)
+		pm.saveServers()
+	}
+	pm.mutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("config reset for server %s interrupted: %w", id, err)
+	}
+	if err := pm.applyUserSettings(id, extensions); err != nil {
+		pm.log.Printf("Failed to apply user settings for server %s: %v", id, err)
+	}
+
+	message := fmt.Sprintf("Config directory rebuilt with %d extensions", len(extensions))
+	if !ok {
+		message = "Config directory rebuilt, but some extensions failed to install"
+	}
+	pm.logger.LogProcessEvent(id, name, "CONFIG_RESET_COMPLETE", message)
+	if pm.logManager != nil {
+		pm.logManager.AddServerLog(id, name, "INFO", "server", message)
+	}
+
+	if wasRunning {
+		if err := pm.startServer(id); err != nil {
+			return fmt.Errorf("config was reset but the server failed to start: %w", err)
+		}
+	}
+	if !ok {
+		return fmt.Errorf("config was reset but some extensions failed to install")
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// describeEventLimit is how many recent lifecycle events GET /servers/:id/describe includes
+const describeEventLimit = 20
+
+// ServerResponse is the API representation of a server. It embeds the persisted
+// ServerInstance and adds computed display fields that are not saved to servers.json.
+type ServerResponse struct {
+	*ServerInstance
+	UptimeHuman string               `json:"uptime_human,omitempty"` // e.g. "2h 13m", derived from StartTime
+	EditorURL   string               `json:"editor_url"`             // Proxied editor URL, opening OpenPath when set
+	LastEvent   *ProcessEvent        `json:"last_event,omitempty"`   // Only with ?include=events
+	Health      *ServerHealthSummary `json:"health,omitempty"`       // Only with ?include=health
+
+	// SettingsConflicts warns about user_settings keys set differently by the server's extension groups (create only)
+	SettingsConflicts []SettingsConflict `json:"settings_conflicts,omitempty"`
+}
+
+// ServerHealthSummary is a compact health snapshot built from the last collected metrics
+type ServerHealthSummary struct {
+	Status        ServerStatus `json:"status"`
+	CPUPercent    float64      `json:"cpu_percent"`
+	MemoryMB      float64      `json:"memory_mb"`
+	UptimeSeconds float64      `json:"uptime_seconds"`
+	LastUpdate    *time.Time   `json:"last_update,omitempty"`
+}
+
+// newServerResponse builds the API view of a server; a nil server yields a nil response
+func newServerResponse(server *ServerInstance) *ServerResponse {
+	if server == nil {
+		return nil
+	}
+
+	response := &ServerResponse{ServerInstance: server, EditorURL: editorURL(server)}
+	if server.Status == StatusRunning && server.StartTime != nil {
+		response.UptimeHuman = formatUptime(time.Since(*server.StartTime))
+	}
+	return response
+}
+
+// newCreatedServerResponse builds the response to a create, warning about user_settings keys that
+// the extension groups of the requested extensions set to different values (the last group applied wins)
+func newCreatedServerResponse(server *ServerInstance, extensions []string) *ServerResponse {
+	response := newServerResponse(server)
+	config := GetConfig()
+	_, settingsGroups := collectUserSettings(config, extensions)
+	if conflicts := findSettingsConflicts(config.ExtensionGroups, settingsGroups); len(conflicts) > 0 {
+		response.SettingsConflicts = conflicts
+	}
+	return response
+}
+
+// newServerResponses builds the API view of a list of servers
+func newServerResponses(servers []*ServerInstance) []*ServerResponse {
+	responses := make([]*ServerResponse, 0, len(servers))
+	for _, server := range servers {
+		responses = append(responses, newServerResponse(server))
+	}
+	return responses
+}
+
+// parseIncludes parses a comma-separated ?include= value into a set of section names
+func parseIncludes(param string) map[string]bool {
+	includes := make(map[string]bool)
+	for _, section := range strings.Split(param, ",") {
+		if section = strings.TrimSpace(strings.ToLower(section)); section != "" {
+			includes[section] = true
+		}
+	}
+	return includes
+}
+
+// addIncludes fills in the optional "events" and "health" sections of a server response
+func (pm *ProcessManager) addIncludes(response *ServerResponse, includes map[string]bool) {
+	if includes["events"] {
+		response.LastEvent = pm.logger.LastEvent(response.ID)
+	}
+	if includes["health"] {
+		response.Health = newServerHealthSummary(response.ServerInstance)
+	}
+}
+
+// newServerHealthSummary summarizes a server's health from the metrics collected by the refresh routine
+func newServerHealthSummary(server *ServerInstance) *ServerHealthSummary {
+	summary := &ServerHealthSummary{
+		Status:     server.Status,
+		LastUpdate: server.LastUpdate,
+	}
+	if server.Status != StatusRunning {
+		return summary
+	}
+	if server.CPUPercent != nil {
+		summary.CPUPercent = *server.CPUPercent
+	}
+	if server.MemoryMB != nil {
+		summary.MemoryMB = *server.MemoryMB
+	}
+	if server.Uptime != nil {
+		summary.UptimeSeconds = *server.Uptime
+	}
+	return summary
+}
+
+// formatUptime renders a duration using its two most significant units, e.g. "3d 4h" or "2h 13m"
+func formatUptime(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+// ServerDescription aggregates everything known about a server for support and debugging
+type ServerDescription struct {
+	Server       *ServerResponse         `json:"server"`
+	Health       map[string]interface{}  `json:"health"`
+	RecentEvents []*ProcessEvent         `json:"recent_events"`
+	Extensions   ServerExtensionsSummary `json:"extensions"`
+	Workspace    WorkspaceUsage          `json:"workspace"`
+	Command      []string                `json:"command"`
+}
+
+// ServerExtensionsSummary lists a server's extensions as recorded and as found on disk
+type ServerExtensionsSummary struct {
+	Requested []string `json:"requested"`
+	Base      []string `json:"base"`
+	OnDisk    []string `json:"on_disk"`
+}
+
+// WorkspaceUsage reports a server's workspace location and disk usage
+type WorkspaceUsage struct {
+	Path      string `json:"path"`
+	SizeBytes int64  `json:"size_bytes"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DescribeServer gathers the server record, live health, recent events, extensions,
+// workspace disk usage and launch command into a single description
+func (pm *ProcessManager) DescribeServer(id string) (*ServerDescription, error) {
+	server, err := pm.GetServer(id)
+	if err != nil {
+		return nil, err
+	}
+
+	pm.mutex.RLock()
+	snapshot := *server
+	pm.mutex.RUnlock()
+
+	health, err := pm.GetServerHealth(id)
+	if err != nil {
+		return nil, err
+	}
+
+	description := &ServerDescription{
+		Server:       newServerResponse(&snapshot),
+		Health:       health,
+		RecentEvents: pm.logger.RecentEvents(id, describeEventLimit),
+		Extensions: ServerExtensionsSummary{
+			Requested: nonNilStrings(snapshot.Extensions),
+			Base:      nonNilStrings(snapshot.BaseExtensions),
+			OnDisk:    pm.listInstalledExtensions(id),
+		},
+		Workspace: WorkspaceUsage{Path: snapshot.WorkspacePath},
+		Command:   nonNilStrings(snapshot.Command),
+	}
+
+	size, err := dirSize(snapshot.WorkspacePath)
+	description.Workspace.SizeBytes = size
+	if err != nil {
+		description.Workspace.Error = err.Error()
+	}
+
+	return description, nil
+}
+
+// listInstalledExtensions returns the extension directories (e.g. "ms-python.python-2024.1.0")
+// in a server's code-server extensions folder
+func (pm *ProcessManager) listInstalledExtensions(id string) []string {
+	entries, err := os.ReadDir(pm.extensionsDir(id))
+	if err != nil {
+		return []string{}
+	}
+
+	installed := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			installed = append(installed, entry.Name())
+		}
+	}
+	return installed
+}
+
+// dirSize returns the total size of regular files under a directory
+func dirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size, err
+}
+
+// nonNilStrings returns an empty slice for nil so it serializes as [] rather than null
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
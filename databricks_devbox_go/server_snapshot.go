@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotTimeLayout names snapshot files so they sort chronologically
+const snapshotTimeLayout = "20060102-150405.000"
+
+// SnapshotServers writes a timestamped copy of the current server state to
+// {dataDir}/snapshots and returns the path of the new file. The state is read under the lock,
+// so the snapshot matches what servers.json holds at that moment.
+func (pm *ProcessManager) SnapshotServers() (string, error) {
+	pm.mutex.RLock()
+	data, err := json.MarshalIndent(pm.servers, "", "  ")
+	pm.mutex.RUnlock()
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal servers: %w", err)
+	}
+
+	snapshotDir := filepath.Join(pm.dataDir, "snapshots")
+	if err := os.MkdirAll(snapshotDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	path := filepath.Join(snapshotDir, "servers-"+time.Now().Format(snapshotTimeLayout)+".json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	pm.log.Printf("Wrote servers snapshot: %s", path)
+	return path, nil
+}
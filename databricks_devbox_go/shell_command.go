@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// shellCommand builds a command that runs a command line through the platform shell
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// runShellCommand runs a command line in dir as account (nil for the manager's user), streaming
+// stdout/stderr into the server's logs. Returns the exit code, or -1 if the command could not be
+// run to completion.
+func (pm *ProcessManager) runShellCommand(ctx context.Context, serverID, serverName, dir, command string, env []string, account *serverUser, timeout time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := shellCommand(ctx, command)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.WaitDelay = 2 * time.Second
+	if err := runAs(cmd, account); err != nil {
+		return -1, err
+	}
+
+	// Copy output through io.Pipes rather than StdoutPipe so Wait (bounded by WaitDelay) finishes
+	// copying before returning, even if a killed command left children holding the streams open
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+
+	capture := NewEnhancedProcessOutputCapture(pm.logger, pm.logManager, serverID, serverName)
+	var wg sync.WaitGroup
+	for streamType, stream := range map[string]io.Reader{"stdout": stdoutReader, "stderr": stderrReader} {
+		wg.Add(1)
+		go func(streamType string, stream io.Reader) {
+			defer wg.Done()
+			capture.captureStream(stream, streamType)
+		}(streamType, stream)
+	}
+	closeStreams := func() {
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		wg.Wait()
+	}
+
+	if err := cmd.Start(); err != nil {
+		closeStreams()
+		return -1, fmt.Errorf("failed to start command: %v", err)
+	}
+
+	err := cmd.Wait()
+	closeStreams()
+	if ctx.Err() == context.DeadlineExceeded {
+		return -1, fmt.Errorf("command timed out after %v", timeout)
+	}
+	if ctx.Err() != nil {
+		return -1, fmt.Errorf("command interrupted: %w", ctx.Err())
+	}
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode(), fmt.Errorf("command exited with code %d", exitErr.ExitCode())
+		}
+		return -1, fmt.Errorf("command failed: %v", err)
+	}
+	return 0, nil
+}
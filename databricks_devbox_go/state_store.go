@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// StateStore persists the managed servers. The default keeps them in data/servers.json;
+// NewMemoryStateStore keeps them in memory so tests don't need a writable data directory.
+type StateStore interface {
+	// Load returns the stored servers, or an error if there are none or they can't be read
+	Load() (map[string]*ServerInstance, error)
+	// Save replaces the stored servers
+	Save(servers map[string]*ServerInstance) error
+	// Changed reports whether something other than this store changed the servers since
+	// they were last loaded or saved, so they should be reloaded
+	Changed() bool
+}
+
+// fileStateStore keeps servers in a JSON file that may also be edited by hand
+type fileStateStore struct {
+	path    string
+	modTime time.Time // mod time of the file as last written or read by us
+}
+
+func newFileStateStore(path string) *fileStateStore {
+	return &fileStateStore{path: path}
+}
+
+func (s *fileStateStore) Load() (map[string]*ServerInstance, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var servers map[string]*ServerInstance
+	if err := json.Unmarshal(data, &servers); err != nil {
+		return nil, err
+	}
+	s.modTime = info.ModTime()
+	return servers, nil
+}
+
+func (s *fileStateStore) Save(servers map[string]*ServerInstance) error {
+	data, err := json.MarshalIndent(servers, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return err
+	}
+
+	// Remember our own write so the state refresh routine doesn't reload it
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
+
+func (s *fileStateStore) Changed() bool {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return false // File doesn't exist
+	}
+	return !info.ModTime().Equal(s.modTime)
+}
+
+// memoryStateStore keeps a serialized copy of the servers, so later changes to the saved
+// instances aren't visible until the next Save
+type memoryStateStore struct {
+	mutex sync.Mutex
+	data  []byte
+}
+
+// NewMemoryStateStore returns a StateStore that never touches disk, for tests
+func NewMemoryStateStore() StateStore {
+	return &memoryStateStore{}
+}
+
+func (s *memoryStateStore) Load() (map[string]*ServerInstance, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.data == nil {
+		return nil, os.ErrNotExist
+	}
+	var servers map[string]*ServerInstance
+	if err := json.Unmarshal(s.data, &servers); err != nil {
+		return nil, err
+	}
+	return servers, nil
+}
+
+func (s *memoryStateStore) Save(servers map[string]*ServerInstance) error {
+	data, err := json.Marshal(servers)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.data = data
+	s.mutex.Unlock()
+	return nil
+}
+
+func (s *memoryStateStore) Changed() bool {
+	return false // Only the ProcessManager writes to it
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"sync"
+)
+
+// StatusListener is called after a server's status changes
+type StatusListener func(serverID string, old, new ServerStatus)
+
+// statusChange is a queued status transition waiting to be delivered to listeners
+type statusChange struct {
+	serverID string
+	old, new ServerStatus
+}
+
+// statusNotifier delivers status changes to listeners on its own goroutine, in the order they
+// happened. Changes are recorded while the ProcessManager lock is held, so listeners are never
+// called inline and may safely call back into the ProcessManager.
+type statusNotifier struct {
+	mutex     sync.Mutex
+	listeners []StatusListener
+	queue     []statusChange
+	wake      chan struct{}
+	startOnce sync.Once
+	log       Logger
+}
+
+func newStatusNotifier(logger Logger) *statusNotifier {
+	return &statusNotifier{wake: make(chan struct{}, 1), log: logger}
+}
+
+// register adds a listener, starting the delivery goroutine on first use
+func (n *statusNotifier) register(listener StatusListener) {
+	n.mutex.Lock()
+	n.listeners = append(n.listeners, listener)
+	n.mutex.Unlock()
+
+	n.startOnce.Do(func() { go n.run() })
+}
+
+// notify queues a status change; it never blocks, so it is safe to call under the ProcessManager lock
+func (n *statusNotifier) notify(serverID string, old, new ServerStatus) {
+	if n == nil {
+		return
+	}
+	n.mutex.Lock()
+	if len(n.listeners) == 0 {
+		n.mutex.Unlock()
+		return
+	}
+	n.queue = append(n.queue, statusChange{serverID: serverID, old: old, new: new})
+	n.mutex.Unlock()
+
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers queued changes to every listener in registration order
+func (n *statusNotifier) run() {
+	for range n.wake {
+		for {
+			n.mutex.Lock()
+			if len(n.queue) == 0 {
+				n.mutex.Unlock()
+				break
+			}
+			change := n.queue[0]
+			n.queue = n.queue[1:]
+			listeners := append([]StatusListener(nil), n.listeners...)
+			n.mutex.Unlock()
+
+			for _, listener := range listeners {
+				n.deliver(listener, change)
+			}
+		}
+	}
+}
+
+// deliver calls one listener, so a panicking listener doesn't stop delivery to the others
+func (n *statusNotifier) deliver(listener StatusListener, change statusChange) {
+	defer func() {
+		if r := recover(); r != nil {
+			n.log.Printf("Status listener panicked for server %s (%s -> %s): %v", change.serverID, change.old, change.new, r)
+		}
+	}()
+	listener(change.serverID, change.old, change.new)
+}
+
+// RegisterStatusListener registers a callback fired after any server's status changes
+// (start, stop, process exit, health checks, refreshes and reloads of servers.json).
+// Listeners run asynchronously on a single goroutine, in the order changes happened.
+func (pm *ProcessManager) RegisterStatusListener(listener StatusListener) {
+	pm.statusNotifier.register(listener)
+}
+
+// setStatus changes a server's status and notifies listeners if it changed; callers must hold mutex
+func (pm *ProcessManager) setStatus(server *ServerInstance, status ServerStatus) {
+	old := server.Status
+	server.Status = status
+	if old != status {
+		pm.statusNotifier.notify(server.ID, old, status)
+	}
+}
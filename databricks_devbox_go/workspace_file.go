@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// codeWorkspaceExt is the extension of VS Code multi-root workspace files
+const codeWorkspaceExt = ".code-workspace"
+
+// isCodeWorkspaceFile reports whether path names a .code-workspace file
+func isCodeWorkspaceFile(path string) bool {
+	return strings.HasSuffix(path, codeWorkspaceExt)
+}
+
+// splitWorkspaceFile splits an adopted workspace path into the directory the server runs in
+// and, when the path is a .code-workspace file, the file's name within it
+func splitWorkspaceFile(path string) (dir, file string) {
+	if isCodeWorkspaceFile(path) {
+		return filepath.Dir(path), filepath.Base(path)
+	}
+	return path, ""
+}
+
+// ValidateWorkspaceFile checks that file is a .code-workspace file inside the workspace;
+// empty opens the workspace directory
+func ValidateWorkspaceFile(file string) error {
+	if file == "" {
+		return nil
+	}
+	if !isCodeWorkspaceFile(file) {
+		return fmt.Errorf("workspace file must end in %s: %s", codeWorkspaceExt, file)
+	}
+	if !filepath.IsLocal(file) {
+		return fmt.Errorf("workspace file must be a relative path inside the workspace: %s", file)
+	}
+	return nil
+}
+
+// SetWorkspaceFile changes the .code-workspace file a server opens from its next start
+func (pm *ProcessManager) SetWorkspaceFile(id, file string) error {
+	if err := ValidateWorkspaceFile(file); err != nil {
+		return err
+	}
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	server, exists := pm.servers[id]
+	if !exists {
+		return fmt.Errorf("server not found: %s", id)
+	}
+
+	server.WorkspaceFile = file
+	opens := "the workspace directory"
+	if file != "" {
+		opens = file
+	}
+	pm.logger.LogProcessEvent(id, server.Name, "WORKSPACE_FILE_UPDATED", fmt.Sprintf("Opens %s (applies from the next start)", opens))
+	pm.saveServers()
+	return nil
+}
+
+// workspaceTarget returns what the editor opens for a server: its .code-workspace file when
+// one is set, otherwise the workspace directory. A file that has gone missing falls back to
+// the directory with a warning, rather than opening an empty editor.
+func (pm *ProcessManager) workspaceTarget(server *ServerInstance) string {
+	if server.WorkspaceFile == "" {
+		return server.WorkspacePath
+	}
+
+	path := filepath.Join(server.WorkspacePath, server.WorkspaceFile)
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		message := fmt.Sprintf("Workspace file %s not found, opening the workspace directory instead", server.WorkspaceFile)
+		pm.log.Printf("Server %s: %s", server.Name, message)
+		if pm.logManager != nil {
+			pm.logManager.AddServerLog(server.ID, server.Name, "WARN", "server", message)
+		}
+		return server.WorkspacePath
+	}
+	return path
+}
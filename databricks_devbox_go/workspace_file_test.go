@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateWorkspaceFile(t *testing.T) {
+	for file, valid := range map[string]bool{
+		"":                                 true,
+		"project.code-workspace":           true,
+		"configs/project.code-workspace":   true,
+		"project.json":                     false,
+		"../other/project.code-workspace":  false,
+		"configs/../../x.code-workspace":   false,
+		"/etc/project.code-workspace":      false,
+		"project.code-workspace/../a.json": false,
+	} {
+		if err := ValidateWorkspaceFile(file); (err == nil) != valid {
+			t.Errorf("ValidateWorkspaceFile(%q) = %v, want valid %v", file, err, valid)
+		}
+	}
+}
+
+func TestWorkspaceTargetFallsBackToDirectory(t *testing.T) {
+	pm := newTestProcessManager(t)
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "project.code-workspace"), []byte(`{"folders":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(workspace, "folder.code-workspace"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, want := range map[string]string{
+		"":                       workspace,
+		"project.code-workspace": filepath.Join(workspace, "project.code-workspace"),
+		"missing.code-workspace": workspace,
+		"folder.code-workspace":  workspace,
+	} {
+		server := &ServerInstance{Name: "files", WorkspacePath: workspace, WorkspaceFile: file}
+		if got := pm.workspaceTarget(server); got != want {
+			t.Errorf("workspaceTarget with file %q = %s, want %s", file, got, want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotGitRepository is returned for git operations on a workspace that isn't a repository
+var ErrNotGitRepository = errors.New("workspace is not a git repository")
+
+// WorkspaceGitStatus summarizes a workspace's git state so work isn't lost on delete
+type WorkspaceGitStatus struct {
+	IsRepo   bool     `json:"is_repo"`
+	Branch   string   `json:"branch,omitempty"`
+	Upstream string   `json:"upstream,omitempty"`
+	Dirty    bool     `json:"dirty"`
+	Ahead    int      `json:"ahead"`
+	Behind   int      `json:"behind"`
+	Changes  []string `json:"changes"` // git status --porcelain lines, e.g. " M main.py"
+}
+
+// branchHeaderPattern parses the "## branch...upstream [ahead N, behind M]" porcelain header
+var branchHeaderPattern = regexp.MustCompile(`^## (.+?)(?:\.\.\.(\S+))?(?: \[(.*)\])?$`)
+
+// runGit runs a git command in dir as account (nil for the manager's user) and returns its
+// stdout; errors include git's stderr. Running as the workspace's owner also keeps git from
+// refusing the repository as one owned by someone else (safe.directory).
+func runGit(parent context.Context, account *serverUser, dir string, args ...string) (string, error) {
+	timeout := GetConfig().Timeouts.GitCommand()
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// Fail fast instead of blocking on a credential prompt
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.WaitDelay = 5 * time.Second
+	if err := runAs(cmd, account); err != nil {
+		return "", fmt.Errorf("git %s: %v", args[0], err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err := parent.Err(); err != nil {
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("git %s: timed out after %s", args[0], timeout)
+	}
+	if err != nil {
+		return stdout.String(), fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// isGitRepo reports whether dir is inside a git work tree
+func isGitRepo(ctx context.Context, account *serverUser, dir string) bool {
+	output, err := runGit(ctx, account, dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil && strings.TrimSpace(output) == "true"
+}
+
+// serverWorkspace returns the workspace path of an existing server
+func (pm *ProcessManager) serverWorkspace(id string) (string, error) {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	server, exists := pm.servers[id]
+	if !exists {
+		return "", fmt.Errorf("server not found: %s", id)
+	}
+	return server.WorkspacePath, nil
+}
+
+// GetWorkspaceGitStatus reports whether the server's workspace has uncommitted changes and
+// how far it is ahead of/behind its upstream. Workspaces that aren't repos report IsRepo false.
+func (pm *ProcessManager) GetWorkspaceGitStatus(ctx context.Context, id string) (*WorkspaceGitStatus, error) {
+	workspacePath, err := pm.serverWorkspace(id)
+	if err != nil {
+		return nil, err
+	}
+	account, err := pm.serverCommandUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &WorkspaceGitStatus{Changes: []string{}}
+	if !isGitRepo(ctx, account, workspacePath) {
+		return status, nil
+	}
+	status.IsRepo = true
+
+	output, err := runGit(ctx, account, workspacePath, "status", "--porcelain", "--branch")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "## ") {
+			parseBranchHeader(line, status)
+			continue
+		}
+		status.Changes = append(status.Changes, line)
+	}
+	status.Dirty = len(status.Changes) > 0
+	return status, nil
+}
+
+// parseBranchHeader fills branch, upstream and ahead/behind counts from a porcelain header line
+func parseBranchHeader(line string, status *WorkspaceGitStatus) {
+	match := branchHeaderPattern.FindStringSubmatch(line)
+	if match == nil {
+		return
+	}
+	status.Branch = strings.TrimPrefix(match[1], "No commits yet on ")
+	status.Upstream = match[2]
+
+	for _, part := range strings.Split(match[3], ", ") {
+		fields := strings.Fields(part)
+		if len(fields) != 2 {
+			continue
+		}
+		count, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "ahead":
+			status.Ahead = count
+		case "behind":
+			status.Behind = count
+		}
+	}
+}
+
+// WorkspaceCommitResult reports what CommitWorkspace did
+type WorkspaceCommitResult struct {
+	Committed bool   `json:"committed"` // False when there was nothing to commit
+	Commit    string `json:"commit,omitempty"`
+	Branch    string `json:"branch,omitempty"`
+	Pushed    bool   `json:"pushed"`
+}
+
+// CommitWorkspace stages and commits all changes in the server's workspace and optionally
+// pushes the current branch, so work can be captured before the server is deleted.
+// Git runs as the server's user, so pushing uses whatever credentials that user has set up.
+func (pm *ProcessManager) CommitWorkspace(ctx context.Context, id, message string, push bool) (*WorkspaceCommitResult, error) {
+	server, err := pm.GetServer(id)
+	if err != nil {
+		return nil, err
+	}
+	workspacePath := server.WorkspacePath
+	account, err := pm.serverCommandUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := pm.GetWorkspaceGitStatus(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !status.IsRepo {
+		return nil, ErrNotGitRepository
+	}
+
+	result := &WorkspaceCommitResult{Branch: status.Branch}
+	if status.Dirty {
+		if _, err := runGit(ctx, account, workspacePath, "add", "--all"); err != nil {
+			return nil, err
+		}
+		if _, err := runGit(ctx, account, workspacePath, "commit", "--message", message); err != nil {
+			return nil, err
+		}
+		result.Committed = true
+	}
+
+	if output, err := runGit(ctx, account, workspacePath, "rev-parse", "HEAD"); err == nil {
+		result.Commit = strings.TrimSpace(output)
+	}
+	if result.Committed {
+		pm.logger.LogProcessEvent(id, server.Name, "WORKSPACE_COMMITTED", fmt.Sprintf("Committed %s: %s", result.Commit, message))
+		if pm.logManager != nil {
+			pm.logManager.AddServerLog(id, server.Name, "INFO", "server", fmt.Sprintf("Committed workspace changes as %s", result.Commit))
+		}
+	}
+
+	if push {
+		args := []string{"push"}
+		if status.Upstream == "" {
+			args = append(args, "--set-upstream", "origin", "HEAD")
+		}
+		if _, err := runGit(ctx, account, workspacePath, args...); err != nil {
+			return result, err
+		}
+		result.Pushed = true
+		pm.logger.LogProcessEvent(id, server.Name, "WORKSPACE_PUSHED", fmt.Sprintf("Pushed %s", result.Branch))
+		if pm.logManager != nil {
+			pm.logManager.AddServerLog(id, server.Name, "INFO", "server", fmt.Sprintf("Pushed workspace branch %s", result.Branch))
+		}
+	}
+
+	return result, nil
+}
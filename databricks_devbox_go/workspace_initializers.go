@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WorkspaceSource describes where a new workspace's initial contents come from
+type WorkspaceSource struct {
+	Type     string            `json:"type"`     // Keyword of a registered initializer, e.g. "zip" or "github"
+	Location string            `json:"location"` // Path, URL, etc., interpreted by the initializer
+	Options  map[string]string `json:"options,omitempty"`
+}
+
+// WorkspaceInitializer populates a workspace directory from a source
+type WorkspaceInitializer interface {
+	Initialize(ctx context.Context, pm *ProcessManager, source WorkspaceSource, workspacePath string) error
+}
+
+// WorkspaceInitializerFunc adapts a plain function to the WorkspaceInitializer interface
+type WorkspaceInitializerFunc func(ctx context.Context, pm *ProcessManager, source WorkspaceSource, workspacePath string) error
+
+func (f WorkspaceInitializerFunc) Initialize(ctx context.Context, pm *ProcessManager, source WorkspaceSource, workspacePath string) error {
+	return f(ctx, pm, source, workspacePath)
+}
+
+var (
+	workspaceInitializers      = make(map[string]WorkspaceInitializer)
+	workspaceInitializersMutex sync.RWMutex
+)
+
+// RegisterWorkspaceInitializer makes an initializer available under a type keyword,
+// replacing any initializer previously registered for it
+func RegisterWorkspaceInitializer(sourceType string, initializer WorkspaceInitializer) {
+	workspaceInitializersMutex.Lock()
+	defer workspaceInitializersMutex.Unlock()
+	workspaceInitializers[sourceType] = initializer
+}
+
+// getWorkspaceInitializer looks up the initializer registered for a type keyword
+func getWorkspaceInitializer(sourceType string) (WorkspaceInitializer, error) {
+	workspaceInitializersMutex.RLock()
+	defer workspaceInitializersMutex.RUnlock()
+
+	initializer, exists := workspaceInitializers[sourceType]
+	if !exists {
+		return nil, fmt.Errorf("unknown workspace source type %q (available: %v)", sourceType, workspaceSourceTypesLocked())
+	}
+	return initializer, nil
+}
+
+// validateRequestedWorkspaceSource checks a source named by an API caller. The zip initializer
+// extracts a local path, so callers may only reach it by uploading a zip_file, never by naming one.
+func validateRequestedWorkspaceSource(source WorkspaceSource) error {
+	if source.Type == "zip" {
+		return fmt.Errorf("zip workspaces must be uploaded as zip_file")
+	}
+	_, err := getWorkspaceInitializer(source.Type)
+	return err
+}
+
+// WorkspaceSourceTypes returns the registered type keywords, sorted
+func WorkspaceSourceTypes() []string {
+	workspaceInitializersMutex.RLock()
+	defer workspaceInitializersMutex.RUnlock()
+	return workspaceSourceTypesLocked()
+}
+
+func workspaceSourceTypesLocked() []string {
+	types := make([]string, 0, len(workspaceInitializers))
+	for sourceType := range workspaceInitializers {
+		types = append(types, sourceType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// initializeWorkspace dispatches to the initializer registered for the source's type
+func (pm *ProcessManager) initializeWorkspace(ctx context.Context, source WorkspaceSource, workspacePath string) error {
+	initializer, err := getWorkspaceInitializer(source.Type)
+	if err != nil {
+		return err
+	}
+
+	pm.log.Printf("Initializing workspace from %s source: %s", source.Type, source.Location)
+	if err := initializer.Initialize(ctx, pm, source, workspacePath); err != nil {
+		return fmt.Errorf("failed to initialize workspace from %s source: %w", source.Type, err)
+	}
+	pm.log.Printf("Workspace successfully initialized from %s source", source.Type)
+	return nil
+}
+
+func init() {
+	// Built-in initializers: an uploaded archive (.zip, or .tar.gz/.tgz by file extension) and a GitHub repository
+	RegisterWorkspaceInitializer("zip", WorkspaceInitializerFunc(
+		func(ctx context.Context, pm *ProcessManager, source WorkspaceSource, workspacePath string) error {
+			return pm.extractArchive(source.Location, workspacePath)
+		}))
+	RegisterWorkspaceInitializer("github", WorkspaceInitializerFunc(
+		func(ctx context.Context, pm *ProcessManager, source WorkspaceSource, workspacePath string) error {
+			opts, err := parseGitCloneOptions(source.Options)
+			if err != nil {
+				return err
+			}
+			return pm.cloneGithubRepo(ctx, source.Location, workspacePath, opts)
+		}))
+}
+
+// validateAdoptedWorkspace checks that an existing directory, or a .code-workspace file for a
+// multi-root workspace, can be opened as a workspace in place
+func validateAdoptedWorkspace(path string) error {
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("adopted workspace path must be absolute: %s", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("adopted workspace path is not accessible: %v", err)
+	}
+	if isCodeWorkspaceFile(path) {
+		if info.IsDir() {
+			return fmt.Errorf("adopted workspace file is a directory: %s", path)
+		}
+		return nil
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("adopted workspace path is not a directory or %s file: %s", codeWorkspaceExt, path)
+	}
+	return nil
+}
+
+// PostInitResult records the outcome of a server's post-init command
+type PostInitResult struct {
+	Command     string    `json:"command"`
+	ExitCode    int       `json:"exit_code"`       // -1 if the command did not run to completion
+	Error       string    `json:"error,omitempty"` // Empty on success
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// runPostInitCommand runs a setup command (e.g. "pip install -r requirements.txt") in a new
+// server's workspace. Failures are recorded on the server and logged but never fail creation.
+func (pm *ProcessManager) runPostInitCommand(ctx context.Context, server *ServerInstance, command string) {
+	pm.log.Printf("Running post-init command for server %s: %s", server.ID, command)
+	pm.logger.LogProcessEvent(server.ID, server.Name, "POST_INIT_STARTED", command)
+	if pm.logManager != nil {
+		pm.logManager.AddServerLog(server.ID, server.Name, "INFO", "server", fmt.Sprintf("Running post-init command: %s", command))
+	}
+
+	timeout := GetConfig().Timeouts.PostInit()
+	exitCode := -1
+	account, err := pm.serverCommandUser(server.ID)
+	if err == nil {
+		exitCode, err = pm.runShellCommand(ctx, server.ID, server.Name, server.WorkspacePath, command, os.Environ(), account, timeout)
+	}
+
+	result := &PostInitResult{
+		Command:     command,
+		ExitCode:    exitCode,
+		CompletedAt: time.Now(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+		pm.log.Printf("Post-init command failed for server %s: %v", server.ID, err)
+		pm.logger.LogProcessEvent(server.ID, server.Name, "POST_INIT_FAILED", err.Error())
+		if pm.logManager != nil {
+			pm.logManager.AddServerLog(server.ID, server.Name, "ERROR", "server", fmt.Sprintf("Post-init command failed: %v", err))
+		}
+	} else {
+		pm.logger.LogProcessEvent(server.ID, server.Name, "POST_INIT_COMPLETED", command)
+		if pm.logManager != nil {
+			pm.logManager.AddServerLog(server.ID, server.Name, "INFO", "server", "Post-init command completed successfully")
+		}
+	}
+
+	pm.mutex.Lock()
+	server.PostInit = result
+	if current, exists := pm.servers[server.ID]; exists {
+		current.PostInit = result
+	}
+	pm.saveServers()
+	pm.mutex.Unlock()
+}
@@ -14,6 +14,7 @@ import ErrorBoundary from '@/components/ErrorBoundary';
 import LogViewer from '@/components/LogViewer';
 import { useDeleteServer, useServers, useMultiStepServerCreation, useTemplates } from '@/hooks/useServers';
 import { useExtensionGroups } from '@/hooks/useConfig';
+import { expandExtensionGroups } from '@/lib/extensionGroups';
 import { useStatusRefresh } from '@/hooks/useStatusRefresh';
 import type { ServerResponse, TemplateItem } from '@/types/api';
 
@@ -53,7 +54,7 @@ function ServerManagementApp() {
   // Server creation hooks - moved from child components
   const multiStepCreation = useMultiStepServerCreation();
   const { data: templates, isLoading: isTemplatesLoading } = useTemplates();
-  const { extensionGroups } = useExtensionGroups();
+  const { extensionGroups, allExtensionGroups } = useExtensionGroups();
 
   const handleDeleteConfirm = (serverId: string) => {
     const server = servers.find(s => s.id === serverId);
@@ -148,11 +149,12 @@ function ServerManagementApp() {
     const allExtensions: string[] = [];
     const groupsWithUserSettings: string[] = [];
 
-    for (const groupKey of template.extensions_groups) {
-      if (extensionGroups && extensionGroups[groupKey]) {
-        allExtensions.push(...extensionGroups[groupKey].extensions);
+    // Templates may use groups hidden from the selection UI; dependencies are included
+    for (const groupKey of expandExtensionGroups(allExtensionGroups, template.extensions_groups)) {
+      if (allExtensionGroups && allExtensionGroups[groupKey]) {
+        allExtensions.push(...allExtensionGroups[groupKey].extensions.filter(extension => !allExtensions.includes(extension)));
         // Check if this group has user settings
-        if (extensionGroups[groupKey].user_settings && Object.keys(extensionGroups[groupKey].user_settings).length > 0) {
+        if (allExtensionGroups[groupKey].user_settings && Object.keys(allExtensionGroups[groupKey].user_settings).length > 0) {
           groupsWithUserSettings.push(groupKey);
         }
       }
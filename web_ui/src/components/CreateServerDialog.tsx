@@ -14,6 +14,7 @@ import { Input } from '@/components/ui/input';
 import { Label } from '@/components/ui/label';
 import { useCreateServer, useCreateServerWithWorkspace } from '@/hooks/useServers';
 import { useExtensionGroups } from '@/hooks/useConfig';
+import { expandExtensionGroups } from '@/lib/extensionGroups';
 
 interface CreateServerRequest {
   name: string;
@@ -64,7 +65,7 @@ const CreateServerDialog: React.FC<CreateServerDialogProps> = ({
   // Fallback for legacy usage when no parent props are provided
   const createServerMutation = useCreateServer();
   const createServerWithWorkspaceMutation = useCreateServerWithWorkspace();
-  const { extensionGroups: fallbackExtensionGroups, isLoading: configLoading } = useExtensionGroups();
+  const { extensionGroups: fallbackExtensionGroups, allExtensionGroups, isLoading: configLoading } = useExtensionGroups();
 
   // Use prop extensionGroups if provided, otherwise use hook result
   const effectiveExtensionGroups = extensionGroups || fallbackExtensionGroups;
@@ -83,20 +84,24 @@ const CreateServerDialog: React.FC<CreateServerDialogProps> = ({
       return;
     }
 
+    // Selected groups plus the groups they depend on (which may be hidden from the selection)
+    const groupSource = { ...allExtensionGroups, ...effectiveExtensionGroups };
+    const resolvedGroups = expandExtensionGroups(groupSource, selectedGroups);
+
     const allExtensions: string[] = [];
-    selectedGroups.forEach(groupKey => {
-      const group = effectiveExtensionGroups && effectiveExtensionGroups[groupKey];
+    resolvedGroups.forEach(groupKey => {
+      const group = groupSource[groupKey];
       if (group) {
-        allExtensions.push(...group.extensions);
+        allExtensions.push(...group.extensions.filter(extension => !allExtensions.includes(extension)));
       }
     });
 
     try {
       // Calculate groups with user settings
       const groupsWithUserSettings: string[] = [];
-      for (const groupKey of selectedGroups) {
-        if (effectiveExtensionGroups && effectiveExtensionGroups[groupKey]) {
-          if (effectiveExtensionGroups[groupKey].user_settings && Object.keys(effectiveExtensionGroups[groupKey].user_settings).length > 0) {
+      for (const groupKey of resolvedGroups) {
+        if (groupSource[groupKey]) {
+          if (groupSource[groupKey].user_settings && Object.keys(groupSource[groupKey].user_settings).length > 0) {
             groupsWithUserSettings.push(groupKey);
           }
         }
@@ -70,6 +70,16 @@ export default function LogViewer({ serverId }: LogViewerProps) {
             // Keep only last 10000 logs
             return newLogs.slice(-10000);
           });
+        } else if (data.type === 'server_deleted') {
+          // Server is gone; the backend closes the connection after this message
+          setConnected(false);
+        } else if (data.type === 'new_logs') {
+          // Batch of log entries
+          setLogs(prev => {
+            const newLogs = [...prev, ...(data.logs || [])];
+            // Keep only last 10000 logs
+            return newLogs.slice(-10000);
+          });
         }
       } catch (error) {
         console.error('Error parsing log message:', error);
@@ -160,7 +160,7 @@ const ServerActionButtons: React.FC<{
           title="Open VS Code"
         >
           <a
-            href={`/vscode/${server.port}/`}
+            href={server.editor_url ?? `/vscode/${server.port}/`}
             target="_blank"
             rel="noopener noreferrer"
           >
@@ -318,7 +318,7 @@ const ServerTable: React.FC<{
                   </div>
                 </TableCell>
               <TableCell>{server.port}</TableCell>
-              <TableCell>{formatUptime(server.uptime)}</TableCell>
+              <TableCell>{server.uptime_human ?? formatUptime(server.uptime)}</TableCell>
               <TableCell>{formatCpuPercent(server.cpu_percent)}</TableCell>
               <TableCell>{formatMemory(server.memory_mb)}</TableCell>
               <TableCell>{server.pid || 'N/A'}</TableCell>
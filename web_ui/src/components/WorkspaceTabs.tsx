@@ -68,14 +68,14 @@ export const WorkspaceTabs: React.FC<WorkspaceTabsProps> = ({
             </div>
           ) : (
             <div>
-              <p className="font-medium">Drag & drop a ZIP file here</p>
+              <p className="font-medium">Drag & drop a ZIP or .tar.gz file here</p>
               <p className="text-sm text-muted-foreground">or click to browse</p>
             </div>
           )}
           <input
             ref={fileInputRef}
             type="file"
-            accept=".zip"
+            accept=".zip,.tar.gz,.tgz"
             onChange={onFileSelect}
             className="hidden"
           />
@@ -83,9 +83,14 @@ export const useConfig = () => {
 // Hook specifically for extension groups (commonly used)
 export const useExtensionGroups = () => {
   const { data: config, isLoading, error } = useConfig();
+  const allGroups = config?.extension_groups || DEFAULT_EXTENSION_GROUPS;
 
   return {
-    extensionGroups: config?.extension_groups || DEFAULT_EXTENSION_GROUPS,
+    // Groups marked hidden in devbox.yaml aren't offered for selection
+    extensionGroups: Object.fromEntries(
+      Object.entries(allGroups).filter(([, group]) => !group.hidden)
+    ),
+    allExtensionGroups: allGroups,
     isLoading,
     error,
     // Helper to get default extension groups for UI
@@ -0,0 +1,26 @@
+import type { ExtensionGroup } from '@/types/api';
+
+// Expands selected group keys with the groups they depend on (depends_on), dependencies first.
+// Unknown groups are skipped and cycles are broken; the backend rejects those configs anyway.
+export function expandExtensionGroups(
+  groups: Record<string, ExtensionGroup>,
+  keys: string[]
+): string[] {
+  const expanded: string[] = [];
+  const visiting = new Set<string>();
+
+  const visit = (key: string) => {
+    if (expanded.includes(key) || visiting.has(key) || !groups[key]) {
+      return;
+    }
+    visiting.add(key);
+    for (const dependency of groups[key].depends_on || []) {
+      visit(dependency);
+    }
+    visiting.delete(key);
+    expanded.push(key);
+  };
+
+  keys.forEach(visit);
+  return expanded;
+}
@@ -1,4 +1,4 @@
-import type { ServerConfig, ServerResponse, HealthInfo, ApiResponse, ApiError, ConfigResponse, TemplatesResponse, CreateServerFromTemplateRequest } from '../types/api';
+import type { ServerConfig, ServerResponse, HealthInfo, ApiResponse, ApiError, ConfigResponse, UIConfigResponse, ServerDefaultsResponse, ExtensionSearchResponse, TemplatesResponse, CreateServerFromTemplateRequest, ExtensionDrift } from '../types/api';
 
 const API_BASE_URL = '';  // Relative path for same-origin requests
 
@@ -172,6 +172,44 @@ class ApiService {
     });
   }
 
+  async getInstalledExtensions(serverId: string): Promise<ApiResponse<ExtensionDrift>> {
+    return this.request<ApiResponse<ExtensionDrift>>(`/servers/${serverId}/extensions`);
+  }
+
+  async uninstallExtension(serverId: string, extension: string): Promise<ApiResponse> {
+    return this.request<ApiResponse>(`/servers/${serverId}/extensions/${encodeURIComponent(extension)}`, {
+      method: 'DELETE',
+    });
+  }
+
+  async installVSIX(serverId: string, vsixFile: File): Promise<ApiResponse<{ extension: string }>> {
+    const formData = new FormData();
+    formData.append('vsix_file', vsixFile);
+
+    const url = `${API_BASE_URL}/servers/${serverId}/install-vsix`;
+
+    try {
+      const response = await fetch(url, {
+        method: 'POST',
+        body: formData,
+      });
+
+      if (!response.ok) {
+        const errorData = await response.json().catch(() => ({
+          error: `HTTP ${response.status}: ${response.statusText}`
+        }));
+        throw new Error(errorData.error);
+      }
+
+      return await response.json();
+    } catch (error) {
+      if (error instanceof Error) {
+        throw error;
+      }
+      throw new Error('An unexpected error occurred');
+    }
+  }
+
   async applyGroupSettings(serverId: string, groupName: string): Promise<ApiResponse> {
     return this.request<ApiResponse>(`/servers/${serverId}/apply-group-settings`, {
       method: 'POST',
@@ -234,6 +272,21 @@ class ApiService {
   async getConfig(): Promise<ConfigResponse> {
     return this.request<ConfigResponse>('/config');
   }
+
+  // Marketplace extension search, for autocompleting extension IDs
+  async searchExtensions(query: string, limit = 20): Promise<ExtensionSearchResponse> {
+    const params = new URLSearchParams({ q: query, limit: String(limit) });
+    return this.request<ExtensionSearchResponse>(`/extensions/search?${params}`);
+  }
+
+  // UI section of the configuration only
+  async getUIConfig(): Promise<UIConfigResponse> {
+    return this.request<UIConfigResponse>('/config/ui');
+  }
+
+  async getServerDefaults(): Promise<ServerDefaultsResponse> {
+    return this.request<ServerDefaultsResponse>('/servers/defaults');
+  }
 }
 
 // Export singleton instance
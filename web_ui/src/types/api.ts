@@ -2,6 +2,7 @@ export interface ServerConfig {
   name: string;
   workspace_path: string;
   extensions: string[];
+  port?: number;
 }
 
 export interface ServerResponse {
@@ -9,11 +10,16 @@ export interface ServerResponse {
   name: string;
   port: number;
   workspace_path: string;
+  workspace_file?: string;
+  open_path?: string;
+  editor_url?: string;
   status: string;
   pid?: number;
   uptime?: number;
+  uptime_human?: string;
   cpu_percent?: number;
   memory_mb?: number;
+  last_access?: string;
 }
 
 export interface HealthInfo {
@@ -28,6 +34,19 @@ export interface ApiResponse<T = any> {
   data?: T;
 }
 
+export interface InstalledExtension {
+  id: string;
+  version?: string;
+}
+
+// Extensions code-server reports as installed vs. those recorded for the server
+export interface ExtensionDrift {
+  installed: InstalledExtension[];
+  recorded: string[];
+  missing: string[];
+  untracked: string[];
+}
+
 export interface ApiError {
   detail: string;
 }
@@ -40,6 +59,8 @@ export interface ExtensionGroup {
   description: string;
   extensions: string[];
   user_settings?: Record<string, any>;
+  hidden?: boolean;
+  depends_on?: string[];
 }
 
 export interface PortRange {
@@ -82,6 +103,39 @@ export interface ConfigResponse {
   data: DevboxConfig;
 }
 
+export interface ExtensionSearchResult {
+  id: string;
+  name: string;
+  description: string;
+  publisher: string;
+  version?: string;
+}
+
+export interface ExtensionSearchResponse {
+  status: string;
+  data: { extensions: ExtensionSearchResult[] };
+}
+
+export interface UIConfigResponse {
+  status: string;
+  data: UIConfig;
+}
+
+export interface ServerDefaults {
+  extension_groups: string[];
+  extensions: string[];
+  runtime: string;
+  workspace_type: string;
+  max_upload_size_mb: number;
+  supported_archive_types: string[];
+  post_init_command?: string;
+}
+
+export interface ServerDefaultsResponse {
+  status: string;
+  data: ServerDefaults;
+}
+
 // Template-related types
 export interface IconLink {
   lucide_icon: string;